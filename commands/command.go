@@ -28,4 +28,9 @@ type Command struct {
 	Usage string
 	// Whether to include in help messages.
 	Hidden bool
+	// Whether the command changes branches, in which case it is recorded in the journal so that
+	// it can be undone with "sd undo".
+	Mutating bool
+	// Whether the command supports "--output json" and "--output tsv".
+	StructuredOutput bool
 }
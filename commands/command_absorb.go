@@ -0,0 +1,329 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Matches the header of a hunk, for example "@@ -10,2 +10,3 @@", capturing the start and count
+// of the lines before the change.
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,\d+)? @@`)
+
+// A staged hunk and the commit it is absorbed into.
+type absorbHunk struct {
+	splitHunk
+	// Nil if no single commit could be found for the hunk.
+	target *templates.GitLog
+	// Why the hunk was not absorbed, if target is nil.
+	reason string
+}
+
+func createAbsorbCommand() Command {
+	flagSet := flag.NewFlagSet("absorb", flag.ContinueOnError)
+	push := flagSet.Bool("push", false, "Also update and push the PR branches of the commits that changes were absorbed into")
+	dryRun := flagSet.Bool("dry-run", false, "Only display which commit each change would be absorbed into")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Add staged changes to the commits that they belong to",
+		Description: "Finds the commit on " + util.GetMainBranchForHelp() + " that each staged hunk belongs to, by\n" +
+			"blaming the lines that the hunk changes, or if it only adds lines then\n" +
+			"the lines around it, or if the file was empty then the commit that last\n" +
+			"changed it. The hunks of each commit are then added to it as a fixup\n" +
+			"commit, and all of the fixup commits are squashed with a single rebase.\n" +
+			"\n" +
+			"Hunks are left staged if they change lines of more than one commit, or\n" +
+			"only lines that are already on origin/" + util.GetMainBranchForHelp() + ".\n" +
+			"\n" +
+			"Use \"--push\" to also update the PR branches of the commits, which is\n" +
+			"the same as running \"sd submit\" afterwards, except that only those\n" +
+			"branches are updated.",
+		Usage:    "sd " + flagSet.Name() + " [flags]",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			absorb(asyncConfig.App, *push, *dryRun)
+		}}
+}
+
+// Adds each staged hunk to the new commit that it belongs to.
+func absorb(appConfig util.AppConfig, push bool, dryRun bool) {
+	util.RequireMainBranch()
+	hunks := getAbsorbHunks()
+	if len(hunks) == 0 {
+		panic("No staged changes, use \"git add\" to stage the changes to absorb")
+	}
+	printAbsorbHunks(appConfig.Io, hunks)
+	targets := getAbsorbTargets(hunks)
+	if dryRun || len(targets) == 0 {
+		return
+	}
+	patchDir, err := os.MkdirTemp("", "sd-absorb")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(patchDir)
+	shouldPopStash := util.Stash("absorb")
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	absorbed := false
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		// The absorbed changes are in both the commits and the stash, so popping leaves only the
+		// changes that were not absorbed.
+		util.PopStash(shouldPopStash)
+		if shouldPopStash {
+			restageHunks(hunks, absorbed, patchDir)
+		}
+		if r != nil {
+			panic(r)
+		}
+	}()
+	updatedTargets := absorbInto(appConfig, hunks, targets, patchDir)
+	absorbed = true
+	rollbackManager.Clear()
+	templates.FollowRewrittenCommits()
+	if push {
+		pushUpdatedBranches(rollbackManager, updatedTargets)
+	}
+}
+
+// Stages hunks again after the stash is popped, which does not restore the index. Only the hunks
+// that were not absorbed are staged if absorbed is true.
+func restageHunks(hunks []absorbHunk, absorbed bool, patchDir string) {
+	assignments := util.MapSlice(hunks, func(hunk absorbHunk) int {
+		if absorbed && hunk.target != nil {
+			return -1
+		}
+		return 0
+	})
+	if !slices.Contains(assignments, 0) {
+		return
+	}
+	patchFile := filepath.Join(patchDir, "restage.patch")
+	if err := os.WriteFile(patchFile, []byte(getSplitPatch(getAbsorbSplitHunks(hunks), assignments, 0)), 0644); err != nil {
+		panic(err)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--quiet")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "apply", "--cached", patchFile)
+}
+
+// Returns the staged hunks along with the commit that each belongs to.
+func getAbsorbHunks() []absorbHunk {
+	diff := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "diff", "--cached",
+		"--no-color", "--no-ext-diff", "--no-renames", "--binary")
+	newCommits := templates.GetNewCommits("HEAD")
+	// Keyed by full commit hash, as output by "git blame".
+	commitsByHash := make(map[string]templates.GitLog)
+	if len(newCommits) > 0 {
+		fullHashes := strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", append([]string{"rev-parse"},
+			util.MapSlice(newCommits, func(gitLog templates.GitLog) string {
+				return gitLog.Commit
+			})...)...))
+		for i, fullHash := range fullHashes {
+			commitsByHash[fullHash] = newCommits[i]
+		}
+	}
+	var hunks []absorbHunk
+	for _, file := range parseFilePatches(diff) {
+		if len(file.hunks) == 0 || strings.Contains(file.header, "\nnew file mode ") {
+			hunks = append(hunks, absorbHunk{splitHunk: splitHunk{file: file}, reason: "new or binary file"})
+			continue
+		}
+		for _, hunk := range file.hunks {
+			absorb := absorbHunk{splitHunk: splitHunk{file: file, hunk: hunk}}
+			absorb.target, absorb.reason = getAbsorbTarget(file.filename, hunk, commitsByHash)
+			hunks = append(hunks, absorb)
+		}
+	}
+	return hunks
+}
+
+// Returns the commit of commitsByHash that the lines changed by hunk belong to, or if it only adds
+// lines then the commit of the lines around it. Returns the reason if there is no single commit.
+func getAbsorbTarget(filename string, hunk string, commitsByHash map[string]templates.GitLog) (*templates.GitLog, string) {
+	matches := hunkHeaderRegex.FindStringSubmatch(hunk)
+	if matches == nil {
+		panic("Unexpected hunk header in " + hunk)
+	}
+	oldStart, _ := strconv.Atoi(matches[1])
+	oldCount := 1
+	if matches[2] != "" {
+		oldCount, _ = strconv.Atoi(matches[2])
+	}
+	if oldCount == 0 {
+		// Lines added to an empty file, which belong to the commit that last changed the file.
+		lastCommit := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "log", "-n", "1", "--format=%H", "HEAD", "--", filename))
+		if target, ok := commitsByHash[lastCommit]; ok {
+			return &target, ""
+		}
+		return nil, "file is already on origin/" + util.GetMainBranchOrDie()
+	}
+	blameCommits := getBlameCommits(filename, oldStart, oldCount)
+	// Line numbers of the lines that the hunk removes.
+	var removedLines []int
+	lineNumber := oldStart
+	for _, line := range strings.Split(hunk, "\n")[1:] {
+		switch {
+		case strings.HasPrefix(line, "-"):
+			removedLines = append(removedLines, lineNumber)
+			lineNumber++
+		case strings.HasPrefix(line, " "):
+			lineNumber++
+		}
+	}
+	candidateLines := removedLines
+	if len(candidateLines) == 0 {
+		for i := range oldCount {
+			candidateLines = append(candidateLines, oldStart+i)
+		}
+	}
+	var targets []string
+	for _, line := range candidateLines {
+		if _, ok := commitsByHash[blameCommits[line]]; ok && !slices.Contains(targets, blameCommits[line]) {
+			targets = append(targets, blameCommits[line])
+		}
+	}
+	switch len(targets) {
+	case 0:
+		return nil, "lines are already on origin/" + util.GetMainBranchOrDie()
+	case 1:
+		target := commitsByHash[targets[0]]
+		return &target, ""
+	default:
+		return nil, fmt.Sprint("lines are from ", len(targets), " commits")
+	}
+}
+
+// Returns the full commit hash of each line of filename in HEAD, keyed by line number.
+func getBlameCommits(filename string, start int, count int) map[int]string {
+	blame := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "blame", "-s", "-l",
+		"-L", fmt.Sprint(start, ",+", count), "HEAD", "--", filename)
+	commits := make(map[int]string)
+	for _, line := range strings.Split(strings.TrimSpace(blame), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		lineNumber, err := strconv.Atoi(strings.TrimSuffix(fields[1], ")"))
+		if err != nil {
+			panic("Unexpected blame output " + line)
+		}
+		// Boundary commits are prefixed with "^".
+		commits[lineNumber] = strings.TrimPrefix(fields[0], "^")
+	}
+	return commits
+}
+
+// Returns the commits that hunks are absorbed into, in the order of "sd log".
+func getAbsorbTargets(hunks []absorbHunk) []templates.GitLog {
+	var targets []templates.GitLog
+	for _, gitLog := range templates.GetNewCommits("HEAD") {
+		if slices.ContainsFunc(hunks, func(hunk absorbHunk) bool {
+			return hunk.target != nil && hunk.target.Commit == gitLog.Commit
+		}) {
+			targets = append(targets, gitLog)
+		}
+	}
+	return targets
+}
+
+// Returns the patch of the hunks that are absorbed into target.
+func getAbsorbPatch(hunks []absorbHunk, target templates.GitLog) string {
+	assignments := util.MapSlice(hunks, func(hunk absorbHunk) int {
+		if hunk.target != nil && hunk.target.Commit == target.Commit {
+			return 0
+		}
+		return -1
+	})
+	return getSplitPatch(getAbsorbSplitHunks(hunks), assignments, 0)
+}
+
+// Returns the split hunk of each of hunks.
+func getAbsorbSplitHunks(hunks []absorbHunk) []splitHunk {
+	return util.MapSlice(hunks, func(hunk absorbHunk) splitHunk {
+		return hunk.splitHunk
+	})
+}
+
+// Commits the hunks of each of targets as a fixup of it and squashes them all with a single
+// rebase, so that each patch applies to the commits that its hunks were found in. Returns the
+// updated targets.
+func absorbInto(appConfig util.AppConfig, hunks []absorbHunk, targets []templates.GitLog, patchDir string) []templates.GitLog {
+	// targets are in the order of "sd log", so the last one is the bottom of the stack.
+	baseCommit := targets[len(targets)-1].Commit + "^"
+	commitsBefore := getCommitsAfter(baseCommit)
+	editorArgs := make([]string, 0, 3*len(targets))
+	for i, target := range targets {
+		slog.Info(fmt.Sprint("Absorbing changes into ", target.Commit, " ", target.Subject))
+		patchFile := filepath.Join(patchDir, fmt.Sprint(i, ".patch"))
+		if err := os.WriteFile(patchFile, []byte(getAbsorbPatch(hunks, target)), 0644); err != nil {
+			panic(err)
+		}
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "apply", "--index", patchFile)
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "--no-verify", "-m", "fixup! "+target.Subject)
+		fixupCommit := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "log", "-n", "1", "--pretty=format:%h"))
+		if i > 0 {
+			editorArgs = append(editorArgs, fixupGroupSeparator)
+		}
+		editorArgs = append(editorArgs, target.Commit, fixupCommit)
+	}
+	// Only the fixup commits that were just created are squashed, not any others in the stack.
+	environmentVariables := []string{
+		"GIT_SEQUENCE_EDITOR=" + appConfig.AppExecutable + " sequence-editor-mark-as-fixup " + strings.Join(editorArgs, " "),
+	}
+	slog.Debug(fmt.Sprint("Using sequence editor ", environmentVariables))
+	options := util.ExecuteOptions{EnvironmentVariables: environmentVariables, Io: appConfig.Io}
+	util.ExecuteOrDie(options, "git", "rebase", "-i", "--no-autosquash", baseCommit)
+	commitsAfter := getCommitsAfter(baseCommit)
+	if len(commitsAfter) != len(commitsBefore) {
+		panic(fmt.Sprint("Expected ", len(commitsBefore), " commits after absorbing, found ", len(commitsAfter)))
+	}
+	updatedTargets := make([]templates.GitLog, 0, len(targets))
+	for _, target := range targets {
+		fullCommit := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", target.Commit))
+		updatedCommit := commitsAfter[slices.Index(commitsBefore, fullCommit)]
+		if util.GetLocalHasBranchOrDie(target.Branch) {
+			util.RecordStackEntry(updatedCommit, target.Branch, 0)
+		}
+		target.Commit = strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", "--short", updatedCommit))
+		updatedTargets = append(updatedTargets, target)
+	}
+	return updatedTargets
+}
+
+// Returns the full hashes of the commits after baseCommit on HEAD, oldest first.
+func getCommitsAfter(baseCommit string) []string {
+	return strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-list", "--reverse", baseCommit+"..HEAD"))
+}
+
+func printAbsorbHunks(stdIo util.StdIo, hunks []absorbHunk) {
+	writer := tabwriter.NewWriter(stdIo.Out, 0, 0, 3, ' ', 0)
+	util.Fprintln(writer, "File\tLines\tCommit")
+	for _, hunk := range hunks {
+		row := getSplitHunkRow(hunk.splitHunk)
+		commit := "(not absorbed: " + hunk.reason + ")"
+		if hunk.target != nil {
+			commit = hunk.target.Commit + " " + hunk.target.Subject
+		}
+		util.Fprintln(writer, row.Filename+"\t"+row.Lines+"\t"+commit)
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
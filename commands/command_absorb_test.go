@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Writes contents to filename and stages it.
+func stageFileChange(filename string, contents string) {
+	if err := os.WriteFile(filename, []byte(contents), os.ModePerm); err != nil {
+		panic(err)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "add", filename)
+}
+
+func TestSdAbsorb_AddsEachHunkToItsCommit(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "a\nb\nc\n")
+	testutil.CommitFileChange("second", "second-file", "d\ne\nf\n")
+	stageFileChange("first-file", "a\nb changed\nc\n")
+	stageFileChange("second-file", "d\ne\nf changed\n")
+
+	testParseArguments("absorb")
+
+	allCommits := templates.GetNewCommits("HEAD")
+	assert.Equal([]string{"second", "first"}, util.MapSlice(allCommits, func(gitLog templates.GitLog) string {
+		return gitLog.Subject
+	}))
+	assert.Equal("a\nb changed\nc\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", allCommits[1].Commit+":first-file"))
+	assert.Equal("d\ne\nf changed\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", allCommits[0].Commit+":second-file"))
+	assert.Equal("", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "status", "--porcelain"))
+}
+
+func TestSdAbsorb_WhenLinesAlreadyOnOrigin_LeavesHunk(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "a\nb\nc\n")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.CommitFileChange("second", "second-file", "d\ne\nf\n")
+	commitsBefore := templates.GetAllCommits()
+	stageFileChange("first-file", "a\nb changed\nc\n")
+
+	out := testParseArguments("absorb")
+
+	assert.Contains(out, "not absorbed: lines are already on origin/"+util.GetMainBranchOrDie())
+	assert.Equal(commitsBefore, templates.GetAllCommits())
+	assert.Equal("M  first-file", strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "status", "--porcelain")))
+}
+
+func TestSdAbsorb_WithDryRun_DoesNotChangeCommits(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "a\nb\nc\n")
+	commitsBefore := templates.GetAllCommits()
+	stageFileChange("first-file", "a\nb changed\nc\n")
+
+	out := testParseArguments("absorb", "--dry-run")
+
+	assert.Contains(out, commitsBefore[0].Commit+" first")
+	assert.Equal(commitsBefore, templates.GetAllCommits())
+}
+
+func TestSdAbsorb_WithPush_UpdatesPrBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "a\nb\nc\n")
+	testParseArguments("new", "1")
+	branch := templates.GetNewCommits("HEAD")[0].Branch
+	stageFileChange("first-file", "a\nb changed\nc\n")
+
+	testParseArguments("absorb", "--push")
+
+	assert.Equal("a\nb changed\nc\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "origin/"+branch+":first-file"))
+	assert.Equal(templates.GetNewCommits("HEAD")[0].Branch, branch)
+}
+
+func TestSdAbsorb_WhenSomeHunksNotAbsorbed_KeepsThemStaged(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "a\nb\nc\n")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.CommitFileChange("second", "second-file", "d\ne\nf\n")
+	stageFileChange("first-file", "a\nb changed\nc\n")
+	stageFileChange("second-file", "d\ne\nf changed\n")
+	if err := os.WriteFile("first-file", []byte("a\nb changed\nc\nunstaged\n"), os.ModePerm); err != nil {
+		panic(err)
+	}
+
+	testParseArguments("absorb")
+
+	assert.Equal("d\ne\nf changed\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "HEAD:second-file"))
+	assert.Equal("a\nb changed\nc\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", ":first-file"))
+	assert.Equal("MM first-file", strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "status", "--porcelain")))
+}
+
+func TestSdAbsorb_WhenLinesAddedToEmptyFile_AddsThemToCommitOfFile(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "")
+	testutil.CommitFileChange("second", "second-file", "d\ne\nf\n")
+	stageFileChange("first-file", "a\n")
+
+	testParseArguments("absorb")
+
+	allCommits := templates.GetNewCommits("HEAD")
+	assert.Equal("a\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", allCommits[1].Commit+":first-file"))
+	assert.Equal("", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "status", "--porcelain"))
+}
+
+func TestSdAbsorb_WhenStackHasOtherFixupCommits_OnlySquashesItsOwn(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "first-file", "a\nb\nc\n")
+	testutil.CommitFileChange("squash! first", "other-file", "d\n")
+	testutil.CommitFileChange("second", "second-file", "e\nf\ng\n")
+	stageFileChange("first-file", "a\nb changed\nc\n")
+	stageFileChange("second-file", "e\nf\ng changed\n")
+
+	testParseArguments("absorb")
+
+	allCommits := templates.GetNewCommits("HEAD")
+	assert.Equal([]string{"second", "squash! first", "first"}, util.MapSlice(allCommits, func(gitLog templates.GitLog) string {
+		return gitLog.Subject
+	}))
+	assert.Equal("a\nb changed\nc\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", allCommits[2].Commit+":first-file"))
+	assert.Equal("e\nf\ng changed\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", allCommits[0].Commit+":second-file"))
+}
@@ -21,17 +21,33 @@ func createAddReviewersCommand() Command {
 	indicatorTypeString := addIndicatorFlag(flagSet)
 
 	whenChecksPass := flagSet.Bool("when-checks-pass", true, "Poll until all checks pass before adding reviewers")
-	defaultPollFrequency := 30 * time.Second
-	pollFrequency := flagSet.Duration("poll-frequency", defaultPollFrequency,
+	pollFrequency := flagSet.Duration("poll-frequency", 0,
 		"Frequency which to poll checks. For valid formats see https://pkg.go.dev/time#ParseDuration")
 	reviewers, silent, minChecks := addReviewersFlags(flagSet)
+	pickCount, pickStrategy := addPickReviewersFlags(flagSet)
+	checkRetries := flagSet.Int("check-retries", 0,
+		"Number of times to re-run failed checks, in case they are flaky, before giving up")
+	fromCodeOwners := flagSet.Bool("from-codeowners", false,
+		"Add the code owners of the files changed by each PR as reviewers,\n"+
+			"in addition to any \"--reviewers\"")
 
 	return Command{
 		FlagSet: flagSet,
 		Summary: "Add reviewers to Pull Request on Github once its checks have passed",
 		Description: "Add reviewers to Pull Request on Github once its checks have passed.\n" +
 			"\n" +
-			"If PR is marked as a Draft, it is first marked as \"Ready for Review\".",
+			"If PR is marked as a Draft, it is first marked as \"Ready for Review\".\n" +
+			"\n" +
+			"If checks fail they are re-run up to \"--check-retries\" times. Use\n" +
+			"\"sd checks\" to see which checks failed and why.\n" +
+			"\n" +
+			"Reviewers can be Github usernames, teams such as \"org/team\", or groups\n" +
+			"from the \"reviewer-groups\" config such as \"@web\". When prompted for\n" +
+			"reviewers, the code owners of the PRs are suggested first.\n" +
+			"\n" +
+			"Use \"--pick-reviewers\" to spread reviews across a pool of reviewers,\n" +
+			"for example \"--reviewers=@web --pick-reviewers=2\". Reviewers in the\n" +
+			"\"out-of-office\" config are never added.",
 		Usage: "sd " + flagSet.Name() + " [flags] [commitIndicator [commitIndicator]...]",
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			selectPrsOptions := interactive.CommitSelectionOptions{
@@ -39,9 +55,10 @@ func createAddReviewersCommand() Command {
 				CommitType:  interactive.CommitTypePr,
 				MultiSelect: true,
 			}
+			pick := checkPickReviewersFlags(asyncConfig.App, command, pickCount, pickStrategy)
 			targetCommits := getTargetCommits(asyncConfig.App, command, flagSet.Args(), indicatorTypeString, selectPrsOptions)
-			if *reviewers == "" {
-				*reviewers = interactive.UserSelection(asyncConfig)
+			if *reviewers == "" && !*fromCodeOwners {
+				*reviewers = interactive.UserSelection(asyncConfig, getSuggestedReviewers(targetCommits))
 				if *reviewers == "" {
 					commandError(
 						asyncConfig.App,
@@ -50,43 +67,76 @@ func createAddReviewersCommand() Command {
 						command.Usage)
 				}
 				slog.Info("Using reviewers " + *reviewers)
-			} else {
+			} else if *reviewers != "" {
 				util.SetHistory(asyncConfig.App, interactive.REVIEWERS_HISTORY_FILE,
 					util.AddToHistory(
 						util.ReadHistory(asyncConfig.App, interactive.REVIEWERS_HISTORY_FILE), *reviewers))
 			}
-			addReviewersToPr(asyncConfig, targetCommits, *whenChecksPass, *silent, *minChecks, *reviewers, *pollFrequency)
+			addReviewersToPr(asyncConfig, targetCommits, *whenChecksPass, *silent, *minChecks, *checkRetries, *reviewers, pick, *fromCodeOwners, *pollFrequency)
 		}}
 }
 
-// Adds reviewers to a PR once checks have passed via Github CLI.
-func addReviewersToPr(asyncConfig util.AsyncAppConfig, targetCommits []templates.GitLog, whenChecksPass bool, silent bool, minChecks int, reviewers string, pollFrequency time.Duration) {
-	if reviewers == "" {
+// Adds reviewers to a PR once checks have passed via Github CLI. If fromCodeOwners is set then the
+// code owners of each PR are added as well. If pick.Count is set then only that many of the
+// reviewers are added to each PR.
+func addReviewersToPr(asyncConfig util.AsyncAppConfig, targetCommits []templates.GitLog, whenChecksPass bool, silent bool, minChecks int, checkRetries int, reviewers string, pick util.PickReviewersOptions, fromCodeOwners bool, pollFrequency time.Duration) {
+	if reviewers == "" && !fromCodeOwners {
 		panic("Reviewers cannot be empty")
 	}
+	// Read CODEOWNERS once up front rather than from each goroutine.
+	var codeOwners *util.CodeOwners
+	if fromCodeOwners {
+		repoCodeOwners := readRepoCodeOwners()
+		codeOwners = &repoCodeOwners
+	}
 	var wg sync.WaitGroup
 	for _, targetCommit := range targetCommits {
 		wg.Add(1)
-		go checkBranch(asyncConfig, &wg, targetCommit, whenChecksPass, silent, minChecks, reviewers, pollFrequency)
+		go checkBranch(asyncConfig, &wg, targetCommit, whenChecksPass, silent, minChecks, checkRetries, reviewers, pick, codeOwners, pollFrequency)
 	}
 	wg.Wait()
 }
 
-func checkBranch(asyncConfig util.AsyncAppConfig, wg *sync.WaitGroup, targetCommit templates.GitLog, whenChecksPass bool, silent bool, minChecks int, reviewers string, pollFrequency time.Duration) {
+func checkBranch(asyncConfig util.AsyncAppConfig, wg *sync.WaitGroup, targetCommit templates.GitLog, whenChecksPass bool, silent bool, minChecks int, checkRetries int, reviewers string, pick util.PickReviewersOptions, codeOwners *util.CodeOwners, pollFrequency time.Duration) {
 	defer asyncConfig.GracefulRecover()
 	if whenChecksPass {
+		// Number of times that each check was re-run, by name as re-running a GitLab job creates a
+		// job with a new id.
+		retries := map[string]int{}
+		// Failing checks that were re-run, until they are no longer reported as failed.
+		var rerunChecks []util.CheckResult
 		for {
 			summary := util.GetChecksStatus(targetCommit.Branch, minChecks)
+			rerunChecks = util.FilterSlice(rerunChecks, func(check util.CheckResult) bool {
+				return slices.Contains(summary.FailingChecks(), check)
+			})
+			if len(rerunChecks) > 0 {
+				// The re-run has not started yet, so the status is still that of the failure that
+				// was re-run.
+				slog.Info(fmt.Sprint("Waiting for re-run checks of ", targetCommit.Branch, " to start"))
+				util.Sleep(pollFrequency)
+				continue
+			}
+			if summary.Failing > 0 && checkRetries > 0 {
+				if rerun, ok := retryFailedChecks(targetCommit, summary, retries, checkRetries); ok {
+					rerunChecks = rerun
+					util.Sleep(pollFrequency)
+					continue
+				}
+			}
 			if summary.Failing > 0 {
 				if !silent {
-					util.ExecuteOrDie(util.ExecuteOptions{}, "say", "Checks failed")
+					util.GetNotifier(asyncConfig.App).Notify(util.Notification{
+						Title:   "Checks failed",
+						Message: targetCommit.Subject + ": " + getCheckNames(summary.FailingChecks()),
+					})
 				}
 				slog.Error(fmt.Sprint("Checks failed for ", targetCommit, ". "+
 					"Total: ", summary.Total(),
 					" | Passed: ", summary.Passing,
 					" | Pending: ", summary.Pending,
-					" | Failed: ", summary.Failing,
-					"\n"))
+					" | Failed: ", summary.Failing, " (", getCheckNames(summary.FailingChecks()), ")",
+					"\nUse \"sd checks ", targetCommit.Commit, "\" to see their logs.\n"))
 				asyncConfig.App.Exit(1)
 			}
 
@@ -103,8 +153,27 @@ func checkBranch(asyncConfig util.AsyncAppConfig, wg *sync.WaitGroup, targetComm
 			util.Sleep(pollFrequency)
 		}
 	}
+	if codeOwners != nil {
+		codeOwnerReviewers := getCodeOwnerReviewers(*codeOwners, targetCommit)
+		slog.Info(fmt.Sprint("Code owners of ", targetCommit.Branch, ": ", strings.Join(codeOwnerReviewers, ",")))
+		reviewers = strings.Join(slices.DeleteFunc(append(strings.Split(reviewers, ","), codeOwnerReviewers...), func(reviewer string) bool {
+			return reviewer == ""
+		}), ",")
+	}
+	if reviewers == "" {
+		slog.Warn("No reviewers to add to " + targetCommit.Branch + ", it has no code owners")
+	} else {
+		requestReviews(asyncConfig.App, targetCommit, reviewers, pick, silent)
+	}
+	wg.Done()
+}
+
+// Marks the PR of targetCommit as ready for review and adds the reviewers that have not already
+// approved it and are not out of office, picking pick.Count of them if set.
+func requestReviews(appConfig util.AppConfig, targetCommit templates.GitLog, reviewers string, pick util.PickReviewersOptions, silent bool) {
+	reviewers = strings.Join(util.RemoveOutOfOfficeReviewers(util.ExpandReviewers(reviewers)), ",")
 	slog.Info("Marking PR as ready for review")
-	util.ExecuteOrDie(util.ExecuteOptions{}, "gh", "pr", "ready", targetCommit.Branch)
+	util.GetForge().MarkPullRequestReady(targetCommit.Branch)
 	slog.Info("Waiting 10 seconds for any automatically assigned reviewers to be added...")
 	util.Sleep(10 * time.Second)
 	slog.Info("Checking if user has already approved latest commit")
@@ -112,15 +181,21 @@ func checkBranch(asyncConfig util.AsyncAppConfig, wg *sync.WaitGroup, targetComm
 	if nonApprovingUsers != reviewers {
 		slog.Warn(fmt.Sprint("Skipping reviewers that have already approved: " + approvingUsers))
 	}
+	if len(nonApprovingUsers) > 0 && pick.Count > 0 {
+		nonApprovingUsers = strings.Join(util.PickReviewers(appConfig, strings.Split(nonApprovingUsers, ","), pick), ",")
+		slog.Info(fmt.Sprint("Picked reviewers ", nonApprovingUsers, " using ", pick.Strategy))
+	}
 	if len(nonApprovingUsers) > 0 {
-		prUrl := strings.TrimSpace(
-			util.ExecuteOrDie(util.ExecuteOptions{},
-				"gh", "pr", "edit", targetCommit.Branch, "--add-reviewer", nonApprovingUsers,
-			),
-		)
+		prUrl := util.GetForge().AddReviewers(targetCommit.Branch, strings.Split(nonApprovingUsers, ","))
 		slog.Info(fmt.Sprint("Added reviewers ", nonApprovingUsers, " to ", prUrl))
+		if !silent {
+			util.GetNotifier(appConfig).Notify(util.Notification{
+				Title:   "Reviewers added",
+				Message: "Added " + nonApprovingUsers + " to " + targetCommit.Subject,
+				Url:     prUrl,
+			})
+		}
 	}
-	wg.Done()
 }
 
 func getNonApprovingUsers(commit templates.GitLog, reviewers string) (string, string) {
@@ -136,3 +211,40 @@ func getNonApprovingUsers(commit templates.GitLog, reviewers string) (string, st
 	}
 	return strings.Join(approvingUsers, ","), strings.Join(nonApprovingUsers, ",")
 }
+
+// Re-runs the failed checks of summary for targetCommit whose run has completed, counting the
+// re-runs of each check in retries. Returns the checks that were re-run, and false if the checks
+// cannot be re-run or a check was already re-run maxRetries times.
+func retryFailedChecks(targetCommit templates.GitLog, summary util.PullRequestChecksStatus, retries map[string]int, maxRetries int) ([]util.CheckResult, bool) {
+	failingChecks := summary.FailingChecks()
+	if _, allHaveRunIds := getFailedRunIds(failingChecks); !allHaveRunIds {
+		slog.Warn(fmt.Sprint("Cannot re-run failed checks of ", targetCommit.Branch, " that were not run by Github Actions or GitLab CI"))
+		return nil, false
+	}
+	if slices.ContainsFunc(failingChecks, func(check util.CheckResult) bool {
+		return retries[check.Name] >= maxRetries
+	}) {
+		return nil, false
+	}
+	// A run cannot be re-run until all of its checks have completed.
+	pendingRunIds := util.MapSlice(util.FilterSlice(summary.Checks, util.CheckResult.IsPending), func(check util.CheckResult) string {
+		return check.RunId
+	})
+	completedChecks := util.FilterSlice(failingChecks, func(check util.CheckResult) bool {
+		return !slices.Contains(pendingRunIds, check.RunId)
+	})
+	if len(completedChecks) == 0 {
+		slog.Info(fmt.Sprint("Waiting for the runs of the failed checks of ", targetCommit.Branch, " to complete before re-running them"))
+		return nil, true
+	}
+	retry := 0
+	for _, check := range completedChecks {
+		retries[check.Name]++
+		retry = max(retry, retries[check.Name])
+	}
+	slog.Warn(fmt.Sprint("Checks failed for ", targetCommit.Branch, ": ", getCheckNames(completedChecks),
+		". Re-running them, retry ", retry, " of ", maxRetries))
+	runIds, _ := getFailedRunIds(completedChecks)
+	rerunFailedChecks(runIds)
+	return completedChecks, true
+}
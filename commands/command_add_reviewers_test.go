@@ -2,8 +2,10 @@ package commands
 
 import (
 	"log/slog"
+	"os"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -25,13 +27,7 @@ func TestSdAddReviewers_AddReviewers(t *testing.T) {
 	testParseArguments("new", "1")
 
 	allCommits := templates.GetAllCommits()
-	testExecutor.SetResponse(
-		// There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n",
-		nil, "gh", "pr", "view", util.MatchAnyRemainingArgs)
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
 
 	testParseArguments("add-reviewers", "--min-checks", "4", "--reviewers=mybestie", allCommits[0].Commit)
 
@@ -42,6 +38,8 @@ func TestSdAddReviewers_AddReviewers(t *testing.T) {
 	assert.True(contains, util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
 		return next.ProgramName == "gh"
 	}))
+	assert.Equal([]util.Notification{{Title: "Reviewers added", Message: "Added mybestie to first", Url: "Ok"}},
+		testutil.GetFakeNotifier().Notifications())
 }
 
 func TestSdAddReviewers_WhenUsingListIndicator_AddReviewers(t *testing.T) {
@@ -54,13 +52,7 @@ func TestSdAddReviewers_WhenUsingListIndicator_AddReviewers(t *testing.T) {
 	testParseArguments("new", "1")
 
 	allCommits := templates.GetAllCommits()
-	testExecutor.SetResponse(
-		// There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n",
-		nil, "gh", "pr", "view", util.MatchAnyRemainingArgs)
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
 
 	testParseArguments("add-reviewers", "--min-checks", "4", "--indicator=list", "--reviewers=mybestie", "1")
 
@@ -83,13 +75,7 @@ func TestSdAddReviewers_WhenOmittingCommitIndicator_AsksForSelection(t *testing.
 	testParseArguments("new", "1")
 
 	allCommits := templates.GetAllCommits()
-	testExecutor.SetResponse(
-		// There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n",
-		nil, "gh", "pr", "view", util.MatchAnyRemainingArgs)
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
 
 	interactive.SendToProgram(0, interactive.NewMessageKey(tea.KeyEnter))
 	testParseArguments("add-reviewers", "--min-checks", "4", "--indicator=list", "--reviewers=mybestie")
@@ -113,25 +99,7 @@ func TestSdAddReviewers_WhenUserAlreadyApproved_DoesNotRequestReview(t *testing.
 	testParseArguments("new", "1")
 
 	allCommits := templates.GetAllCommits()
-	checksSuccess := // There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n"
-	testExecutor.SetResponseFunc(checksSuccess, nil, func(programName string, args ...string) bool {
-		return programName == "gh" &&
-			args[0] == "pr" &&
-			args[1] == "view" &&
-			slices.Contains(args, "statusCheckRollup")
-	})
-
-	approvedUsers := "alreadyapproved1\nalreadyapproved2"
-	testExecutor.SetResponseFunc(approvedUsers, nil, func(programName string, args ...string) bool {
-		return programName == "gh" &&
-			args[0] == "pr" &&
-			args[1] == "view" &&
-			slices.Contains(args, "reviews")
-	})
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4, "alreadyapproved1", "alreadyapproved2")
 
 	out := testParseArguments("--log-level=info", "add-reviewers", "--min-checks", "4", "--reviewers=alreadyapproved2,mybestie,alreadyapproved1", "1")
 
@@ -156,17 +124,7 @@ func TestSdAddReviewers_UserChoosesHistory_ChoosesSameReviewers(t *testing.T) {
 	testParseArguments("new", "1")
 
 	allCommits := templates.GetAllCommits()
-	checksSuccess := // There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n"
-	testExecutor.SetResponseFunc(checksSuccess, nil, func(programName string, args ...string) bool {
-		return programName == "gh" &&
-			args[0] == "pr" &&
-			args[1] == "view" &&
-			slices.Contains(args, "statusCheckRollup")
-	})
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
 
 	testParseArguments("add-reviewers", "--min-checks", "4", "--reviewers=mybestie", "1")
 
@@ -199,17 +157,7 @@ func TestSdAddReviewers_UserChoosesHistoryFromTyped_ChoosesSameReviewers(t *test
 	testParseArguments("new", "1")
 
 	allCommits := templates.GetAllCommits()
-	checksSuccess := // There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n" +
-			"SUCCESS\nSUCCESS\nSUCCESS\n"
-	testExecutor.SetResponseFunc(checksSuccess, nil, func(programName string, args ...string) bool {
-		return programName == "gh" &&
-			args[0] == "pr" &&
-			args[1] == "view" &&
-			slices.Contains(args, "statusCheckRollup")
-	})
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
 
 	// What reviewers?
 	interactive.SendToProgram(0,
@@ -237,3 +185,294 @@ func TestSdAddReviewers_UserChoosesHistoryFromTyped_ChoosesSameReviewers(t *test
 		return next.ProgramName == "gh"
 	}))
 }
+
+func TestSdAddReviewers_WithCheckRetries_RerunsFailedChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	testParseArguments("new", "1")
+
+	allCommits := templates.GetAllCommits()
+	setFailingChecksPullRequest(allCommits[0].Branch)
+	testExecutor.SetResponseFunc("Ok", nil, func(programName string, args ...string) bool {
+		if programName == "gh" && slices.Equal(args, []string{"run", "rerun", "42", "--failed"}) {
+			// The flaky check passes when it is re-run.
+			testutil.SetOpenPullRequest(allCommits[0].Branch, 2)
+			return true
+		}
+		return false
+	})
+
+	testParseArguments("add-reviewers", "--min-checks", "2", "--check-retries", "1", "--reviewers=mybestie", allCommits[0].Commit)
+
+	contains := slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie"}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	})
+	assert.True(contains)
+}
+
+func TestSdAddReviewers_WithCheckRetriesAndRerunNotStarted_WaitsForRerun(t *testing.T) {
+	assert := assert.New(t)
+
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	testParseArguments("new", "1")
+
+	allCommits := templates.GetAllCommits()
+	setFailingChecksPullRequest(allCommits[0].Branch)
+	rerun := false
+	sleepsAfterRerun := 0
+	util.SetDefaultSleep(func(d time.Duration) {
+		if !rerun {
+			return
+		}
+		sleepsAfterRerun++
+		// The failure is still reported until the re-run starts on a later poll.
+		if sleepsAfterRerun == 2 {
+			testutil.SetOpenPullRequest(allCommits[0].Branch, 2)
+		}
+	})
+	testExecutor.SetResponseFunc("Ok", nil, func(programName string, args ...string) bool {
+		if programName == "gh" && slices.Equal(args, []string{"run", "rerun", "42", "--failed"}) {
+			rerun = true
+			return true
+		}
+		return false
+	})
+
+	testParseArguments("add-reviewers", "--min-checks", "2", "--check-retries", "1", "--reviewers=mybestie", allCommits[0].Commit)
+
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie"}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	}))
+	assert.Len(util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && len(next.Args) > 1 && next.Args[0] == "run" && next.Args[1] == "rerun"
+	}), 1)
+}
+
+func TestSdAddReviewers_WithCheckRetriesAndRerunFailsAgain_RerunsAgain(t *testing.T) {
+	assert := assert.New(t)
+
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	testParseArguments("new", "1")
+
+	allCommits := templates.GetAllCommits()
+	setFailingChecksPullRequest(allCommits[0].Branch)
+	reruns := 0
+	testExecutor.SetResponseFunc("Ok", nil, func(programName string, args ...string) bool {
+		if programName == "gh" && slices.Equal(args, []string{"run", "rerun", "42", "--failed"}) {
+			reruns++
+			if reruns == 1 {
+				// The new attempt of the same run fails again.
+				setChecksPullRequest(allCommits[0].Branch,
+					util.PullRequestCheck{Name: "lint", Status: "COMPLETED", Conclusion: "SUCCESS", Url: "https://example.com/lint", RunId: "41"},
+					util.PullRequestCheck{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE", Url: "https://example.com/test/attempt-2", RunId: "42"},
+				)
+			} else {
+				testutil.SetOpenPullRequest(allCommits[0].Branch, 2)
+			}
+			return true
+		}
+		return false
+	})
+
+	testParseArguments("add-reviewers", "--min-checks", "2", "--check-retries", "2", "--reviewers=mybestie", allCommits[0].Commit)
+
+	assert.Equal(2, reruns)
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie"}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	}))
+}
+
+func TestSdAddReviewers_WithCheckRetriesAndRunInProgress_WaitsForRunBeforeRerun(t *testing.T) {
+	assert := assert.New(t)
+
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	testParseArguments("new", "1")
+
+	allCommits := templates.GetAllCommits()
+	setChecksPullRequest(allCommits[0].Branch,
+		util.PullRequestCheck{Name: "build", Status: "IN_PROGRESS", Url: "https://example.com/build", RunId: "42"},
+		util.PullRequestCheck{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE", Url: "https://example.com/test", RunId: "42"},
+	)
+	runCompleted := false
+	util.SetDefaultSleep(func(d time.Duration) {
+		if !runCompleted {
+			runCompleted = true
+			setChecksPullRequest(allCommits[0].Branch,
+				util.PullRequestCheck{Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS", Url: "https://example.com/build", RunId: "42"},
+				util.PullRequestCheck{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE", Url: "https://example.com/test", RunId: "42"},
+			)
+		}
+	})
+	testExecutor.SetResponseFunc("Ok", nil, func(programName string, args ...string) bool {
+		if programName == "gh" && slices.Equal(args, []string{"run", "rerun", "42", "--failed"}) {
+			assert.True(runCompleted, "re-ran checks of a run that was still in progress")
+			testutil.SetOpenPullRequest(allCommits[0].Branch, 2)
+			return true
+		}
+		return false
+	})
+
+	testParseArguments("add-reviewers", "--min-checks", "2", "--check-retries", "1", "--reviewers=mybestie", allCommits[0].Commit)
+
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie"}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	}))
+}
+
+// Fakes that the PR of branchName has checks.
+func setChecksPullRequest(branchName string, checks ...util.PullRequestCheck) {
+	testutil.GetFakeGithubClient().SetPullRequest(util.PullRequest{
+		HeadBranch: branchName,
+		BaseBranch: util.GetMainBranchOrDie(),
+		State:      util.PullRequestStateOpen,
+		Checks:     checks,
+	})
+}
+
+func TestSdAddReviewers_WithFromCodeOwners_AddsOwnersOfBranch(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-changed")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.AddCommit("second", "second-changed")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "mkdir", "-p", ".github")
+	codeOwners := "first-changed @firstOwner\n" +
+		"second-changed @secondOwner @my-org/ios someone@example.com\n"
+	if writeErr := os.WriteFile(".github/CODEOWNERS", []byte(codeOwners), os.ModePerm); writeErr != nil {
+		panic(writeErr)
+	}
+
+	testParseArguments("add-reviewers", "--min-checks", "4", "--from-codeowners", "--reviewers=mybestie", allCommits[0].Commit)
+
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie,my-org/ios,secondOwner"}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	}), util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh"
+	}))
+}
+
+func TestSdAddReviewers_WithFromCodeOwnersAndManyCommits_AddsOwnersOfEachBranch(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-changed")
+	testutil.AddCommit("second", "second-changed")
+	testutil.AddCommit("third", "third-changed")
+	allCommits := templates.GetAllCommits()
+	for _, gitLog := range allCommits[0:3] {
+		testParseArguments("new", "--indicator", "commit", gitLog.Commit)
+		testutil.SetOpenPullRequest(gitLog.Branch, 4)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "mkdir", "-p", ".github")
+	codeOwners := "first-changed @firstOwner\n" +
+		"second-changed @secondOwner\n" +
+		"third-changed @thirdOwner\n"
+	if writeErr := os.WriteFile(".github/CODEOWNERS", []byte(codeOwners), os.ModePerm); writeErr != nil {
+		panic(writeErr)
+	}
+
+	testParseArguments("add-reviewers", "--min-checks", "4", "--from-codeowners",
+		allCommits[0].Commit, allCommits[1].Commit, allCommits[2].Commit)
+
+	for i, owner := range []string{"thirdOwner", "secondOwner", "firstOwner"} {
+		assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+			ghExpectedArgs := []string{"pr", "edit", allCommits[i].Branch, "--add-reviewer", owner}
+			return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+		}), owner)
+	}
+}
+
+func TestSdAddReviewers_WithReviewerGroup_AddsMembers(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+	t.Setenv("SD_REVIEWER_GROUPS", "web=alice,bob")
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
+
+	testParseArguments("add-reviewers", "--min-checks", "4", "--reviewers=@web,my-org/ios", allCommits[0].Commit)
+
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "alice,bob,my-org/ios"}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	}))
+}
+
+func TestSdAddReviewers_WithPickReviewers_RotatesReviewers(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+	t.Setenv("SD_OUT_OF_OFFICE", "carol")
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
+
+	for range 3 {
+		testParseArguments("add-reviewers", "--min-checks", "4", "--reviewers=alice,bob,carol,unit-test", "--pick-reviewers=1", allCommits[0].Commit)
+	}
+
+	assert.Equal([]string{"alice", "bob", "alice"}, getAddedReviewers(testExecutor))
+}
+
+func TestSdAddReviewers_WithFewestRequestsStrategy_PicksLeastBusyReviewers(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.GetFakeGithubClient().SetReviewRequestCount("alice", 5)
+	testutil.GetFakeGithubClient().SetReviewRequestCount("carol", 2)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
+
+	testParseArguments("add-reviewers", "--min-checks", "4", "--reviewers=alice,bob,carol,my-org/ios",
+		"--pick-reviewers=2", "--pick-strategy=fewest-requests", allCommits[0].Commit)
+
+	assert.Equal([]string{"my-org/ios,bob,carol"}, getAddedReviewers(testExecutor))
+}
+
+func TestSdAddReviewers_WithInvalidPickStrategy_Panics(t *testing.T) {
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+
+	assert.Panics(t, func() {
+		testParseArguments("add-reviewers", "--reviewers=alice", "--pick-strategy=busiest", "1")
+	})
+}
+
+// Returns the reviewers of each "gh pr edit --add-reviewer" that was executed.
+func getAddedReviewers(testExecutor *util.TestExecutor) []string {
+	addReviewers := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && len(next.Args) == 5 && next.Args[3] == "--add-reviewer"
+	})
+	return util.MapSlice(addReviewers, func(next util.ExecutedResponse) string {
+		return next.Args[4]
+	})
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Number of unchanged lines to display around each changed line of a diff.
+const diffContextLines = 2
+
+func createAmendPrCommand() Command {
+	flagSet := flag.NewFlagSet("amend-pr", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	dryRun := flagSet.Bool("dry-run", false, "Only display the changes, do not edit the PR")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Update the title and description of a PR from its commit",
+		Description: "Renders the PR title and description again from the current commit\n" +
+			"message and templates, displays how they differ from the PR on Github,\n" +
+			"and then edits the PR. Useful after rewording a commit or changing the\n" +
+			"pr-title.template or pr-description.template.\n" +
+			"\n" +
+			"Sections of the description that are between\n" +
+			"\"<!-- sd-preserve:name -->\" and \"<!-- /sd-preserve:name -->\" keep the\n" +
+			"text from the PR, so that any edits made to them on Github are not lost.",
+		Usage: "sd " + flagSet.Name() + " [flags] [commitIndicator]",
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() > 1 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			selectCommitOptions := interactive.CommitSelectionOptions{
+				Prompt:      "What PR do you want to amend?",
+				CommitType:  interactive.CommitTypePr,
+				MultiSelect: false,
+			}
+			targetCommit := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectCommitOptions)
+			amendPr(asyncConfig.App, targetCommit[0], *dryRun)
+		}}
+}
+
+// Edits the title and description of the PR of gitLog to match the ones rendered from its commit.
+func amendPr(appConfig util.AppConfig, gitLog templates.GitLog, dryRun bool) {
+	pullRequest, ok := util.GetForge().GetPullRequests([]string{gitLog.Branch})[gitLog.Branch]
+	if !ok {
+		panic("No PR found for branch " + gitLog.Branch)
+	}
+	prText := templates.GetPullRequestText(gitLog.Commit, "", templates.GetStack())
+	prText.Description = templates.PreserveSections(prText.Description, pullRequest.Body)
+	// Github stores descriptions edited in the browser with Windows line endings.
+	existingBody := strings.ReplaceAll(pullRequest.Body, "\r\n", "\n")
+	if strings.TrimSpace(prText.Title) == strings.TrimSpace(pullRequest.Title) &&
+		strings.TrimSpace(prText.Description) == strings.TrimSpace(existingBody) {
+		slog.Info(fmt.Sprint("PR ", pullRequest.Number, " is already up to date"))
+		return
+	}
+	util.Fprintln(appConfig.Io.Out, "Title:")
+	printLineDiff(appConfig.Io, pullRequest.Title, prText.Title)
+	util.Fprintln(appConfig.Io.Out, "Description:")
+	printLineDiff(appConfig.Io, existingBody, prText.Description)
+	if dryRun {
+		return
+	}
+	slog.Info(fmt.Sprint("Editing PR ", pullRequest.Number))
+	util.GetForge().EditPullRequest(fmt.Sprint(pullRequest.Number),
+		util.EditPullRequestOptions{Title: prText.Title, Body: prText.Description})
+}
+
+// Prints the lines that differ between before and after, prefixed with "-" if removed or "+" if
+// added, along with a few unchanged lines around them.
+func printLineDiff(stdIo util.StdIo, before string, after string) {
+	lines := diffLines(strings.Split(strings.TrimSpace(before), "\n"), strings.Split(strings.TrimSpace(after), "\n"))
+	lastPrinted := -1
+	for i, line := range lines {
+		if !isLineNearChange(lines, i) {
+			continue
+		}
+		if lastPrinted != -1 && lastPrinted != i-1 {
+			util.Fprintln(stdIo.Out, "  ...")
+		}
+		util.Fprintln(stdIo.Out, line)
+		lastPrinted = i
+	}
+	if lastPrinted == -1 {
+		util.Fprintln(stdIo.Out, "  (unchanged)")
+	}
+}
+
+// Returns whether lines[index] is a change, or is within diffContextLines of one.
+func isLineNearChange(lines []string, index int) bool {
+	for i := max(0, index-diffContextLines); i <= min(len(lines)-1, index+diffContextLines); i++ {
+		if !strings.HasPrefix(lines[i], " ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the lines of before and after, prefixed with " " if unchanged, "-" if removed, or "+" if
+// added, based on their longest common subsequence.
+func diffLines(before []string, after []string) []string {
+	// common[i][j] is the length of the longest common subsequence of before[i:] and after[j:].
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+	lines := make([]string, 0, len(before)+len(after))
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		if before[i] == after[j] {
+			lines = append(lines, "  "+before[i])
+			i++
+			j++
+		} else if common[i+1][j] >= common[i][j+1] {
+			lines = append(lines, "- "+before[i])
+			i++
+		} else {
+			lines = append(lines, "+ "+after[j])
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		lines = append(lines, "- "+before[i])
+	}
+	for ; j < len(after); j++ {
+		lines = append(lines, "+ "+after[j])
+	}
+	return lines
+}
+
+// Edits the stack section of the description of each open PR in the local stack that is out of
+// date, for example because a PR was added to the stack, see [templates.ReplaceStackSection].
+func updateStackSections(stack templates.Stack) {
+	for _, stackSection := range templates.GetStackSections(stack) {
+		body, ok := templates.ReplaceStackSection(stackSection.PullRequest.Body, stackSection.Section)
+		if !ok || strings.TrimSpace(body) == strings.TrimSpace(stackSection.PullRequest.Body) {
+			continue
+		}
+		slog.Info(fmt.Sprint("Updating stack section of PR ", stackSection.PullRequest.Number))
+		util.GetForge().EditPullRequest(fmt.Sprint(stackSection.PullRequest.Number), util.EditPullRequestOptions{Body: body})
+	}
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Creates a PR for a commit and then rewords the commit to newSubject. Returns the reworded commit.
+func createPrAndReword(newSubject string, existingBody string) templates.GitLog {
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	branchName := templates.GetNewCommits("HEAD")[0].Branch
+	testutil.GetFakeGithubClient().SetPullRequest(util.PullRequest{
+		Number:     123,
+		HeadBranch: branchName,
+		BaseBranch: util.GetMainBranchOrDie(),
+		State:      util.PullRequestStateOpen,
+		Title:      "first",
+		Body:       existingBody,
+	})
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "--amend", "-m", newSubject)
+	return templates.GetNewCommits("HEAD")[0]
+}
+
+func getPrEditArgs(testExecutor *util.TestExecutor) []string {
+	edits := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:2], []string{"pr", "edit"})
+	})
+	if len(edits) == 0 {
+		return nil
+	}
+	return edits[len(edits)-1].Args
+}
+
+func TestSdAmendPr_WhenCommitReworded_EditsTitle(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	createPrAndReword("reworded", "")
+
+	out := testParseArguments("amend-pr", "1")
+
+	assert.Contains(out, "- first")
+	assert.Contains(out, "+ reworded")
+	args := getPrEditArgs(testExecutor)
+	assert.Equal([]string{"pr", "edit", "123", "--title", "reworded"}, args[0:5])
+}
+
+func TestSdAmendPr_WithPreservedSection_KeepsTextFromPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	createPrAndReword("reworded", "old\r\n<!-- sd-preserve:testing -->\r\nTested on my phone\r\n<!-- /sd-preserve:testing -->\r\n")
+
+	testParseArguments("amend-pr", "1")
+
+	args := getPrEditArgs(testExecutor)
+	body := args[len(args)-1]
+	assert.Contains(body, "<!-- sd-preserve:testing -->\nTested on my phone\n<!-- /sd-preserve:testing -->")
+	assert.NotContains(body, "#### Testing")
+	assert.Contains(body, "#### Feature flag(s)")
+}
+
+func TestSdAmendPr_WithDryRun_DoesNotEditPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	createPrAndReword("reworded", "")
+
+	out := testParseArguments("amend-pr", "--dry-run", "1")
+
+	assert.Contains(out, "+ reworded")
+	assert.Nil(getPrEditArgs(testExecutor))
+}
+
+func TestSdAmendPr_WhenUpToDate_DoesNotEditPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	gitLog := createPrAndReword("first", "")
+	prText := templates.GetPullRequestText(gitLog.Commit, "", templates.GetStack())
+	pullRequest := testutil.GetFakeGithubClient().GetPullRequests([]string{gitLog.Branch})[gitLog.Branch]
+	pullRequest.Body = strings.ReplaceAll(prText.Description, "\n", "\r\n")
+	testutil.GetFakeGithubClient().SetPullRequest(pullRequest)
+
+	testParseArguments("amend-pr", "1")
+
+	assert.Nil(getPrEditArgs(testExecutor))
+}
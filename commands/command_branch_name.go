@@ -17,7 +17,8 @@ func createBranchNameCommand() Command {
 		Summary:         "Outputs branch name of commit",
 		Description: "Outputs the branch name for a given commit indicator.\n" +
 			"Useful for your own custom scripting.",
-		Usage: "sd " + flagSet.Name() + " [flags] <commitIndicator>",
+		Usage:            "sd " + flagSet.Name() + " [flags] <commitIndicator>",
+		StructuredOutput: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() > 1 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
@@ -28,6 +29,14 @@ func createBranchNameCommand() Command {
 				MultiSelect: false,
 			}
 			targetCommit := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectCommitOptions)
+			if isStructuredOutput(asyncConfig.App) {
+				printStructuredOutput(asyncConfig.App, []branchNameOutput{{
+					Commit:  targetCommit[0].Commit,
+					Subject: targetCommit[0].Subject,
+					Branch:  targetCommit[0].Branch,
+				}}, branchNameOutputHeaders, branchNameOutput.tsvRow)
+				return
+			}
 			util.Fprint(asyncConfig.App.Io.Out, targetCommit[0].Branch)
 		}}
 }
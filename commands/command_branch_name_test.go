@@ -21,3 +21,22 @@ func TestSdBranchName_OutputsBranchName(t *testing.T) {
 
 	assert.Equal(allCommits[0].Branch, out)
 }
+
+func TestSdBranchName_WithJsonOutput_OutputsRecord(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	allCommits := templates.GetAllCommits()
+
+	out := testParseArguments("--output", "json", "branch-name", allCommits[0].Commit)
+
+	assert.Equal("[\n"+
+		"  {\n"+
+		"    \"commit\": \""+allCommits[0].Commit+"\",\n"+
+		"    \"subject\": \"first\",\n"+
+		"    \"branch\": \""+allCommits[0].Branch+"\"\n"+
+		"  }\n"+
+		"]\n", out)
+}
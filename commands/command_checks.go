@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"flag"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createChecksCommand() Command {
+	flagSet := flag.NewFlagSet("checks", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	logLines := flagSet.Int("log-lines", 30, "Number of lines to display from the end of the log of each failed run, 0 for none")
+	rerun := flagSet.Bool("rerun", false, "Re-run the failed checks without asking")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Lists the checks of a PR, with the logs of the failed ones",
+		Description: "Lists the name, state, and URL of each check of the PR of the commit.\n" +
+			"\n" +
+			"If any checks failed then the end of the log of their failed jobs is\n" +
+			"displayed, and you are asked whether to re-run them. Logs and re-runs\n" +
+			"are only available for checks run by Github Actions or GitLab CI, for\n" +
+			"other checks follow their URL.",
+		Usage:            "sd " + flagSet.Name() + " [flags] [commitIndicator]",
+		StructuredOutput: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() > 1 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			selectCommitOptions := interactive.CommitSelectionOptions{
+				Prompt:      "What PR do you want to see the checks of?",
+				CommitType:  interactive.CommitTypePr,
+				MultiSelect: false,
+			}
+			targetCommits := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectCommitOptions)
+			checks(asyncConfig.App, targetCommits[0], *logLines, *rerun)
+		}}
+}
+
+// Displays the checks of the PR of gitLog, and the logs of the failed ones, and re-runs them if
+// rerun is set or the user confirms.
+func checks(appConfig util.AppConfig, gitLog templates.GitLog, logLines int, rerun bool) {
+	status := util.GetPullRequestStatus(gitLog.Branch, 0)
+	runIds, _ := getFailedRunIds(status.Checks.FailingChecks())
+	if isStructuredOutput(appConfig) {
+		records := util.MapSlice(status.Checks.Checks, func(check util.CheckResult) checkOutput {
+			return checkOutput{Name: check.Name, State: check.Conclusion, Url: check.Url, RunId: check.RunId}
+		})
+		printStructuredOutput(appConfig, records, checkOutputHeaders, checkOutput.tsvRow)
+	} else {
+		printChecks(appConfig.Io, status.Checks.Checks)
+		if logLines > 0 {
+			for _, runId := range runIds {
+				util.Fprintln(appConfig.Io.Out, "")
+				util.Fprintln(appConfig.Io.Out, "Log of failed jobs of run "+runId+":")
+				util.Fprintln(appConfig.Io.Out, tailLines(util.GetForge().GetFailedCheckLog(runId), logLines))
+			}
+		}
+		if len(runIds) > 0 && !rerun {
+			util.Fprintln(appConfig.Io.Out, "")
+			rerun = interactive.Confirm(appConfig, "Re-run the failed checks?")
+		}
+	}
+	if rerun {
+		if len(runIds) == 0 {
+			slog.Info("No failed checks to re-run")
+		}
+		rerunFailedChecks(runIds)
+	}
+}
+
+func printChecks(stdIo util.StdIo, checks []util.CheckResult) {
+	if len(checks) == 0 {
+		util.Fprintln(stdIo.Out, "No checks")
+		return
+	}
+	writer := tabwriter.NewWriter(stdIo.Out, 0, 8, 2, ' ', 0)
+	util.Fprintln(writer, "Check\tState\tUrl")
+	for _, check := range checks {
+		util.Fprintln(writer, check.Name+"\t"+check.Conclusion+"\t"+check.Url)
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// Returns the sorted unique run ids of failingChecks, and whether all of them have a run id and
+// so can be re-run.
+func getFailedRunIds(failingChecks []util.CheckResult) ([]string, bool) {
+	runIds := make([]string, 0, len(failingChecks))
+	allHaveRunIds := true
+	for _, check := range failingChecks {
+		if check.RunId == "" {
+			allHaveRunIds = false
+		} else {
+			runIds = append(runIds, check.RunId)
+		}
+	}
+	slices.Sort(runIds)
+	return slices.Compact(runIds), allHaveRunIds
+}
+
+func rerunFailedChecks(runIds []string) {
+	for _, runId := range runIds {
+		slog.Info("Re-running failed jobs of run " + runId)
+		util.GetForge().RerunFailedChecks(runId)
+	}
+}
+
+// Returns the last count lines of text.
+func tailLines(text string, count int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns the names of checks, separated by commas.
+func getCheckNames(checks []util.CheckResult) string {
+	return strings.Join(util.MapSlice(checks, func(check util.CheckResult) string {
+		return check.Name
+	}), ", ")
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Fakes that the PR of branchName has a passing check and a failing check run by Github Actions
+// run 42.
+func setFailingChecksPullRequest(branchName string) {
+	testutil.GetFakeGithubClient().SetPullRequest(util.PullRequest{
+		HeadBranch: branchName,
+		BaseBranch: util.GetMainBranchOrDie(),
+		State:      util.PullRequestStateOpen,
+		Checks: []util.PullRequestCheck{
+			{Name: "lint", Status: "COMPLETED", Conclusion: "SUCCESS", Url: "https://example.com/lint", RunId: "41"},
+			{Name: "test", Status: "COMPLETED", Conclusion: "FAILURE", Url: "https://example.com/test", RunId: "42"},
+		},
+	})
+}
+
+func TestSdChecks_ListsChecksAndFailedLogs(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	setFailingChecksPullRequest(allCommits[0].Branch)
+	testExecutor.SetResponse("first line\ntest\tstep\tassertion failed", nil, "gh", "run", "view", "42", "--log-failed")
+	interactive.SendToProgram(0, interactive.NewMessageRune('n'))
+
+	out := testParseArguments("checks", "--log-lines=1", allCommits[0].Commit)
+
+	assert.Regexp("lint +SUCCESS +https://example.com/lint", out)
+	assert.Regexp("test +FAILURE +https://example.com/test", out)
+	assert.Contains(out, "Log of failed jobs of run 42:\ntest\tstep\tassertion failed\n")
+	assert.NotContains(out, "first line")
+	assert.False(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Contains(next.Args, "rerun")
+	}))
+}
+
+func TestSdChecks_WithRerun_RerunsFailedRuns(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	setFailingChecksPullRequest(allCommits[0].Branch)
+
+	testParseArguments("checks", "--rerun", "--log-lines=0", allCommits[0].Commit)
+
+	reruns := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Contains(next.Args, "rerun")
+	})
+	assert.Equal(1, len(reruns))
+	assert.Equal([]string{"run", "rerun", "42", "--failed"}, reruns[0].Args)
+}
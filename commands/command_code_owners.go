@@ -2,105 +2,278 @@ package commands
 
 import (
 	"flag"
-	"fmt"
 	"log/slog"
-	"os"
 	"slices"
 	"strings"
 
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
 
-	"github.com/hairyhenderson/go-codeowners"
+// Values of [commitCodeOwnersOutput.Approval].
+const (
+	codeOwnersApproved = "approved"
+	codeOwnersMissing  = "missing"
+	codeOwnersOptional = "optional"
 )
 
 func createCodeOwnersCommand() Command {
 	flagSet := flag.NewFlagSet("code-owners", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
 
 	return Command{
 		FlagSet: flagSet,
-		Summary: "Outputs code owners for all of the changes in branch",
+		Summary: "Outputs code owners of the changes in branch, or of each commit",
 		Description: "Outputs code owners for each file that has been modified\n" +
-			"in the current local branch when compared to the remote main branch",
-		Usage:           "sd " + flagSet.Name(),
-		DefaultLogLevel: slog.LevelError,
+			"in the current local branch when compared to the remote main branch.\n" +
+			"\n" +
+			"If commitIndicators are given then the code owners of each commit are\n" +
+			"output instead, using the files changed by its PR branch if it has\n" +
+			"one. For PRs, each owner shows whether they have approved the latest\n" +
+			"commit or their approval is still missing. Approvals from members of\n" +
+			"a team are not known, so owners that are only teams show as missing.\n" +
+			"\n" +
+			"The CODEOWNERS file is read from the first of .github/, the root,\n" +
+			"docs/, and .gitlab/ of the repository. GitLab sections, such as\n" +
+			"\"[Docs] @docs-team\", \"^[Optional]\", and \"[Security][2]\", are supported.",
+		Usage:            "sd " + flagSet.Name() + " [flags] [commitIndicator [commitIndicator]...]",
+		DefaultLogLevel:  slog.LevelError,
+		StructuredOutput: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
-			if flagSet.NArg() != 0 {
-				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			if flagSet.NArg() == 0 {
+				if isStructuredOutput(asyncConfig.App) {
+					printStructuredOutput(asyncConfig.App, getCodeOwnersOutput(), codeOwnersOutputHeaders, codeOwnersOutput.tsvRow)
+					return
+				}
+				util.Fprint(asyncConfig.App.Io.Out, changedFilesOwnersString())
+				return
 			}
-			util.Fprint(asyncConfig.App.Io.Out, changedFilesOwnersString())
+			targetCommits := getTargetCommits(asyncConfig.App, command, flagSet.Args(), indicatorTypeString, interactive.CommitSelectionOptions{})
+			records := getCommitCodeOwnersOutput(targetCommits)
+			if isStructuredOutput(asyncConfig.App) {
+				printStructuredOutput(asyncConfig.App, records, commitCodeOwnersOutputHeaders, commitCodeOwnersOutput.tsvRow)
+				return
+			}
+			util.Fprint(asyncConfig.App.Io.Out, commitCodeOwnersString(records))
 		}}
 }
 
+// Files that are owned by the same group of owners.
+type ownedFiles struct {
+	// Owners of the files, with no Owners if the files are unowned.
+	group util.CodeOwnerGroup
+	files []string
+}
+
+// Returns a description of the owners of the files, such as "@alice,@bob", or "unowned".
+func (owned ownedFiles) ownerDescription() string {
+	if len(owned.group.Owners) == 0 {
+		return "unowned"
+	}
+	return owned.group.String()
+}
+
 // Returns changed files and their owners.
 func changedFilesOwnersString() string {
 	var ownerString strings.Builder
-	ownedFiles := changedFilesOwners(getChangedFiles())
-	keys := make([]string, 0, len(ownedFiles))
-	for k := range ownedFiles {
-		keys = append(keys, k)
-	}
-	slices.Sort(keys)
-
-	for i, key := range keys {
+	for i, owned := range changedFilesOwners(readRepoCodeOwners(), getChangedFiles(util.GetCurrentBranchName())) {
 		if i > 0 {
 			ownerString.WriteString("\n")
 		}
-		ownerString.WriteString("Owner: " + key + "\n")
-		for _, filename := range ownedFiles[key] {
+		ownerString.WriteString("Owner: " + owned.ownerDescription() + "\n")
+		for _, filename := range owned.files {
 			ownerString.WriteString(filename + "\n")
 		}
 	}
 	return ownerString.String()
 }
 
-func changedFilesOwners(changedFiles []string) map[string][]string {
-	ownedFiles := make(map[string][]string)
-	githubCodeowners = nil
+// Returns a record of each owner and the changed files that they own, sorted by owner.
+func getCodeOwnersOutput() []codeOwnersOutput {
+	return util.MapSlice(changedFilesOwners(readRepoCodeOwners(), getChangedFiles(util.GetCurrentBranchName())), func(owned ownedFiles) codeOwnersOutput {
+		return codeOwnersOutput{Owner: owned.ownerDescription(), Files: owned.files}
+	})
+}
+
+// Returns a record of each owner of the files changed by each of targetCommits, along with whether
+// they have approved the latest commit of its PR.
+func getCommitCodeOwnersOutput(targetCommits []templates.GitLog) []commitCodeOwnersOutput {
+	branches := util.FilterSlice(util.MapSlice(targetCommits, func(gitLog templates.GitLog) string {
+		return gitLog.Branch
+	}), util.GetLocalHasBranchOrDie)
+	statuses := util.GetPullRequestStatuses(branches, 0)
+	codeOwners := readRepoCodeOwners()
+	records := make([]commitCodeOwnersOutput, 0)
+	for _, gitLog := range targetCommits {
+		branch := ""
+		var changedFiles []string
+		if slices.Contains(branches, gitLog.Branch) {
+			branch = gitLog.Branch
+			changedFiles = getChangedFiles(gitLog.Branch)
+		} else {
+			changedFiles = getCommitFiles(gitLog.Commit)
+		}
+		status, hasPr := statuses[gitLog.Branch]
+		for _, owned := range changedFilesOwners(codeOwners, changedFiles) {
+			record := commitCodeOwnersOutput{
+				Commit:     gitLog.Commit,
+				Subject:    gitLog.Subject,
+				Branch:     branch,
+				Owner:      owned.ownerDescription(),
+				Files:      owned.files,
+				ApprovedBy: []string{},
+			}
+			if hasPr && len(owned.group.Owners) > 0 {
+				record.ApprovedBy = owned.group.GetApprovers(status.Approvers)
+				record.Approval = getCodeOwnersApproval(owned.group, status.Approvers)
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Returns one of the codeOwners approval constants for group.
+func getCodeOwnersApproval(group util.CodeOwnerGroup, approvers []string) string {
+	if group.IsApproved(approvers) {
+		return codeOwnersApproved
+	}
+	if group.Optional {
+		return codeOwnersOptional
+	}
+	return codeOwnersMissing
+}
+
+// Returns the owners of each commit of records and the changed files that they own.
+func commitCodeOwnersString(records []commitCodeOwnersOutput) string {
+	var ownerString strings.Builder
+	for i, record := range records {
+		if i == 0 || records[i-1].Commit != record.Commit {
+			if i > 0 {
+				ownerString.WriteString("\n")
+			}
+			ownerString.WriteString(record.Commit + " " + record.Subject + "\n")
+		}
+		ownerString.WriteString("\nOwner: " + record.Owner)
+		switch record.Approval {
+		case codeOwnersApproved:
+			ownerString.WriteString(" (approved by " + strings.Join(record.ApprovedBy, ",") + ")")
+		case codeOwnersMissing:
+			if len(record.ApprovedBy) > 0 {
+				ownerString.WriteString(" (missing approvals, approved by " + strings.Join(record.ApprovedBy, ",") + ")")
+			} else {
+				ownerString.WriteString(" (missing approval)")
+			}
+		case codeOwnersOptional:
+			ownerString.WriteString(" (approval optional)")
+		}
+		ownerString.WriteString("\n")
+		for _, filename := range record.Files {
+			ownerString.WriteString(filename + "\n")
+		}
+	}
+	return ownerString.String()
+}
+
+// Returns the changed files grouped by their owners, sorted by owner. A file with owners in more
+// than one GitLab section is in more than one group.
+func changedFilesOwners(codeOwners util.CodeOwners, changedFiles []string) []ownedFiles {
+	owned := make([]ownedFiles, 0)
 	for _, filename := range changedFiles {
 		if filename == "" || filename == "\"\"" {
 			continue
 		}
-		owners := getGithubCodeOwners(filename)
-		var ownersForFile string
-		if len(owners) != 0 {
-			for i, o := range owners {
-				if i > 0 {
-					ownersForFile += ","
-				}
-				ownersForFile += o
-			}
-		} else {
-			ownersForFile = "unowned"
+		groups := codeOwners.GetOwners(filename)
+		if len(groups) == 0 {
+			groups = []util.CodeOwnerGroup{{}}
 		}
-		existing := ownedFiles[ownersForFile]
-		if existing == nil {
-			existing = make([]string, 0)
+		for _, group := range groups {
+			index := slices.IndexFunc(owned, func(next ownedFiles) bool {
+				return next.group.String() == group.String()
+			})
+			if index == -1 {
+				owned = append(owned, ownedFiles{group: group, files: []string{}})
+				index = len(owned) - 1
+			}
+			if !slices.Contains(owned[index].files, filename) {
+				owned[index].files = append(owned[index].files, filename)
+			}
 		}
-		existing = append(existing, filename)
-		ownedFiles[ownersForFile] = existing
 	}
-	return ownedFiles
+	slices.SortFunc(owned, func(a ownedFiles, b ownedFiles) int {
+		return strings.Compare(a.ownerDescription(), b.ownerDescription())
+	})
+	return owned
 }
 
 /*
-Returns changed files against main.
+Returns files changed by branchName against main.
 */
-func getChangedFiles() []string {
-	firstOriginCommit := util.FirstOriginMainCommit(util.GetCurrentBranchName())
+func getChangedFiles(branchName string) []string {
+	firstOriginCommit := util.FirstOriginMainCommit(branchName)
 	filenamesRaw := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager",
-		"log", "--pretty=format:\"\"", "--name-only", firstOriginCommit+"..HEAD")
+		"log", "--pretty=format:\"\"", "--name-only", firstOriginCommit+".."+branchName)
 	return strings.Split(strings.TrimSpace(filenamesRaw), "\n")
 }
 
-var githubCodeowners *codeowners.Codeowners
+// Returns the files changed by commit.
+func getCommitFiles(commit string) []string {
+	filenamesRaw := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--name-only", "--format=", commit)
+	return strings.Fields(filenamesRaw)
+}
 
-func getGithubCodeOwners(filename string) []string {
-	if githubCodeowners == nil {
-		var err error
-		if githubCodeowners, err = codeowners.FromFileWithFS(os.DirFS("."), ""); err != nil {
-			slog.Info(fmt.Sprint("Could not calculate code owners: ", err))
-			return []string{}
+// Returns the code owners of the files changed by the PR branch of gitLog, or by gitLog itself if
+// it does not have a branch yet, as reviewers. The logged in user is not included.
+func getCodeOwnerReviewers(codeOwners util.CodeOwners, gitLog templates.GitLog) []string {
+	var owners []string
+	if util.GetLocalHasBranchOrDie(gitLog.Branch) {
+		owners = make([]string, 0)
+		for _, filename := range getChangedFiles(gitLog.Branch) {
+			if filename != "" && filename != "\"\"" {
+				owners = append(owners, getFileOwners(codeOwners, filename)...)
+			}
+		}
+	} else {
+		owners = getCommitOwners(codeOwners, gitLog.Commit)
+	}
+	reviewers := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		if reviewer, ok := util.GetCodeOwnerReviewer(owner); ok && reviewer != util.GetLoggedInUsername() &&
+			!slices.Contains(reviewers, reviewer) {
+			reviewers = append(reviewers, reviewer)
 		}
 	}
-	return githubCodeowners.Owners(filename)
+	slices.Sort(reviewers)
+	return reviewers
+}
+
+// Returns the code owners of gitLogs as reviewers, to suggest first when selecting reviewers.
+func getSuggestedReviewers(gitLogs []templates.GitLog) []string {
+	codeOwners := readRepoCodeOwners()
+	suggested := make([]string, 0)
+	for _, gitLog := range gitLogs {
+		suggested = append(suggested, getCodeOwnerReviewers(codeOwners, gitLog)...)
+	}
+	slices.Sort(suggested)
+	return slices.Compact(suggested)
+}
+
+// Returns the CODEOWNERS of the repository, or no owners if it does not have a CODEOWNERS file.
+// The result is read only, so it can be shared by goroutines.
+func readRepoCodeOwners() util.CodeOwners {
+	codeOwners, ok := util.ReadCodeOwners()
+	if !ok {
+		slog.Info("Could not calculate code owners: no CODEOWNERS file found")
+	}
+	return codeOwners
+}
+
+// Returns the owners of filename from every section of codeOwners.
+func getFileOwners(codeOwners util.CodeOwners, filename string) []string {
+	owners := make([]string, 0)
+	for _, group := range codeOwners.GetOwners(filename) {
+		owners = append(owners, group.Owners...)
+	}
+	return owners
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
 	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
 )
@@ -39,3 +40,55 @@ func TestSdCodeOwners_OutputsOwnersOfChangedFiles(t *testing.T) {
 		"Owner: thirdOwners\n"+
 		"third-changed\n", out)
 }
+
+func TestSdCodeOwners_WithTsvOutput_OutputsOwnerAndFiles(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-changed")
+	testutil.AddCommit("second", "second-changed")
+
+	util.ExecuteOrDie(util.ExecuteOptions{}, "mkdir", "-p", ".github")
+	if writeErr := os.WriteFile(".github/CODEOWNERS", []byte("*-changed myOwners\n"), os.ModePerm); writeErr != nil {
+		panic(writeErr)
+	}
+	out := testParseArguments("--output", "tsv", "code-owners")
+
+	assert.Equal("owner\tfiles\n"+
+		"myOwners\tsecond-changed,first-changed\n", out)
+}
+
+func TestSdCodeOwners_WithCommitIndicators_OutputsOwnersAndApprovalsOfEachCommit(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-changed")
+	testutil.AddCommit("second", "second-changed")
+
+	util.ExecuteOrDie(util.ExecuteOptions{}, "mkdir", "-p", "docs")
+	codeOwners := "first-changed @firstOwner\n" +
+		"second-changed @secondOwner\n" +
+		"[Docs][2] @docs-team\n" +
+		"second-*\n"
+	if writeErr := os.WriteFile("docs/CODEOWNERS", []byte(codeOwners), os.ModePerm); writeErr != nil {
+		panic(writeErr)
+	}
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 0, "secondOwner")
+
+	out := testParseArguments("code-owners", allCommits[0].Commit, allCommits[1].Commit)
+
+	assert.Equal(allCommits[0].Commit+" second\n"+
+		"\n"+
+		"Owner: @secondOwner (approved by secondOwner)\n"+
+		"second-changed\n"+
+		"\n"+
+		"Owner: [Docs] @docs-team (missing approval)\n"+
+		"second-changed\n"+
+		"\n"+
+		allCommits[1].Commit+" first\n"+
+		"\n"+
+		"Owner: @firstOwner\n"+
+		"first-changed\n", out)
+}
@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createConfigCommand() Command {
+	flagSet := flag.NewFlagSet("config", flag.ContinueOnError)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Get or set config, such as default values for flags",
+		Description: "Get or set config values. Each value comes from the first of these that\n" +
+			"has it set:\n" +
+			"\n" +
+			"   1. Command line flags\n" +
+			"   2. Environment variables, for example SD_MIN_CHECKS\n" +
+			"   3. User config, ~/.gh-stacked-diff/" + util.UserConfigFilename + "\n" +
+			"   4. Repository config, " + util.RepoConfigFilename + " in the root of the repository\n" +
+			"   5. Built-in defaults\n" +
+			"\n" +
+			"The repository config is for values shared by everyone working on the\n" +
+			"repository, so commit it.\n" +
+			"\n" +
+			"Possible keys are:\n" +
+			"\n" +
+			getConfigKeysHelp(),
+		Usage: "sd " + flagSet.Name() + " list\n" +
+			"       sd " + flagSet.Name() + " get <key>\n" +
+			"       sd " + flagSet.Name() + " set [--repo] <key> <value>\n" +
+			"\n" +
+			"   list   displays all values and where they came from\n" +
+			"   get    displays a value and where it came from\n" +
+			"   set    sets a value in the user config, or the repository config if\n" +
+			"          \"--repo\" is used",
+		DefaultLogLevel:  slog.LevelError,
+		StructuredOutput: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			switch flagSet.Arg(0) {
+			case "list":
+				if flagSet.NArg() != 1 {
+					commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+				}
+				printConfigValues(asyncConfig.App, util.GetConfigValues())
+			case "get":
+				if flagSet.NArg() != 2 {
+					commandError(asyncConfig.App, flagSet, "get requires a key", command.Usage)
+				}
+				printConfigValues(asyncConfig.App, []util.ConfigValue{util.GetConfigValue(flagSet.Arg(1))})
+			case "set":
+				setConfig(asyncConfig.App, flagSet, command)
+			case "":
+				commandError(asyncConfig.App, flagSet, "missing list, get, or set", command.Usage)
+			default:
+				commandError(asyncConfig.App, flagSet, "unknown config command "+flagSet.Arg(0), command.Usage)
+			}
+		}}
+}
+
+// Returns the name and description of each config key, one per line.
+func getConfigKeysHelp() string {
+	var help strings.Builder
+	for i, key := range util.GetConfigKeys() {
+		if i > 0 {
+			help.WriteString("\n")
+		}
+		help.WriteString("   " + key.Name + strings.Repeat(" ", max(1, 17-len(key.Name))) + key.Description)
+	}
+	return help.String()
+}
+
+func setConfig(appConfig util.AppConfig, flagSet *flag.FlagSet, command Command) {
+	setFlagSet := flag.NewFlagSet("set", flag.ContinueOnError)
+	setFlagSet.SetOutput(io.Discard)
+	repo := setFlagSet.Bool("repo", false, "Set the value in the repository config instead of the user config")
+	if err := setFlagSet.Parse(flagSet.Args()[1:]); err != nil {
+		commandError(appConfig, flagSet, err.Error(), command.Usage)
+	}
+	if setFlagSet.NArg() != 2 {
+		commandError(appConfig, flagSet, "set requires a key and a value", command.Usage)
+	}
+	source := util.ConfigSourceUser
+	if *repo {
+		source = util.ConfigSourceRepo
+	}
+	configFile := util.SetConfigValue(setFlagSet.Arg(0), setFlagSet.Arg(1), source)
+	util.Fprintln(appConfig.Io.Out, "Set "+setFlagSet.Arg(0)+" to \""+setFlagSet.Arg(1)+"\" in "+configFile)
+	if value := util.GetConfigValue(setFlagSet.Arg(0)); value.Location != configFile {
+		util.Fprintln(appConfig.Io.Out, "Note: it is overridden by "+value.SourceDescription())
+	}
+}
+
+func printConfigValues(appConfig util.AppConfig, values []util.ConfigValue) {
+	if isStructuredOutput(appConfig) {
+		records := util.MapSlice(values, func(value util.ConfigValue) configOutput {
+			return configOutput{Key: value.Key.Name, Value: value.Value, Source: string(value.Source), Location: value.Location}
+		})
+		printStructuredOutput(appConfig, records, configOutputHeaders, configOutput.tsvRow)
+		return
+	}
+	writer := tabwriter.NewWriter(appConfig.Io.Out, 0, 0, 3, ' ', 0)
+	util.Fprintln(writer, "Key\tValue\tSource")
+	for _, value := range values {
+		util.Fprintln(writer, value.Key.Name+"\t"+value.Value+"\t"+value.SourceDescription())
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
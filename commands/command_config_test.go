@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdConfig_List_ShowsDefaults(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	out := testParseArguments("config", "list")
+
+	assert.Regexp(`draft\s+true\s+default`, out)
+	assert.Regexp(`min-checks\s+-1\s+default`, out)
+}
+
+func TestSdConfig_SetRepo_OverridesDefault(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testParseArguments("config", "set", "--repo", "min-checks", "2")
+
+	out := testParseArguments("config", "get", "min-checks")
+	assert.Regexp(`min-checks\s+2\s+repo \(.*`+util.RepoConfigFilename+`\)`, out)
+}
+
+func TestSdConfig_SetUser_OverridesRepo(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testParseArguments("config", "set", "--repo", "reviewers", "from-repo")
+	testParseArguments("config", "set", "reviewers", "from-user")
+
+	out := testParseArguments("config", "get", "reviewers")
+	assert.Regexp(`reviewers\s+from-user\s+user \(`, out)
+	assert.Equal("from-user", util.GetConfigString("reviewers"))
+}
+
+func TestSdConfig_WithEnvironmentVariable_OverridesUser(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	testParseArguments("config", "set", "draft", "false")
+	t.Setenv("SD_DRAFT", "true")
+
+	out := testParseArguments("config", "get", "draft")
+
+	assert.Regexp(`draft\s+true\s+env \(SD_DRAFT\)`, out)
+}
+
+func TestSdConfig_SetWithInvalidValue_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "config", "set", "min-checks", "many")
+	})
+
+	assert.Contains(out.String(), "Invalid value for min-checks")
+}
+
+func TestSdConfig_SetRepo_KeepsComments(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	if err := os.WriteFile(util.RepoConfigFilename, []byte("# Shared by the team\nreviewers: mybestie\n"), 0644); err != nil {
+		panic(err)
+	}
+
+	testParseArguments("config", "set", "--repo", "draft", "false")
+
+	data, err := os.ReadFile(filepath.Join(".", util.RepoConfigFilename))
+	if err != nil {
+		panic(err)
+	}
+	assert.Equal("# Shared by the team\nreviewers: mybestie\ndraft: false\n", string(data))
+}
+
+func TestSdNew_WithDraftConfig_CreatesReadyPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testParseArguments("config", "set", "draft", "false")
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+
+	prCreates := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:2], []string{"pr", "create"})
+	})
+	assert.Equal(1, len(prCreates))
+	assert.NotContains(prCreates[0].Args, "--draft")
+}
+
+func TestSdConfig_WithJiraUrl_LinksTicket(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	testParseArguments("config", "set", "--repo", "jira-url", "https://example.atlassian.net/browse/")
+
+	testutil.AddCommit("CONV-123 Add feature", "feature")
+
+	prText := templates.GetPullRequestText("HEAD", "", templates.GetStack())
+	assert.Contains(prText.Description, "[CONV-123](https://example.atlassian.net/browse/CONV-123)")
+}
+
+func TestSdNew_WithDraftFlag_OverridesConfig(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testParseArguments("config", "set", "draft", "false")
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "--draft", "1")
+
+	prCreates := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:2], []string{"pr", "create"})
+	})
+	assert.Equal(1, len(prCreates))
+	assert.Contains(prCreates[0].Args, "--draft")
+}
+
+func TestSdConfig_WithInvalidRepoConfig_OnlyFailsCommandsThatUseIt(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	if err := os.WriteFile(util.RepoConfigFilename, []byte("min-checks: many\n"), 0644); err != nil {
+		panic(err)
+	}
+
+	assert.NotPanics(func() {
+		testParseArguments("log")
+	})
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "status")
+	})
+	assert.Contains(out.String(), "Invalid value for min-checks")
+}
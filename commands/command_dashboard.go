@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createDashboardCommand() Command {
+	flagSet := flag.NewFlagSet("dashboard", flag.ContinueOnError)
+	pollFrequency := flagSet.Duration("poll-frequency", 0,
+		"Frequency which to refresh the status of PRs. For valid formats see https://pkg.go.dev/time#ParseDuration")
+	minChecks := flagSet.Int("min-checks", 0,
+		"Minimum number of checks to wait for before considering that checks\n"+
+			"have passed. Defaults to the \"min-checks\" config, where -1 means to\n"+
+			"use 4 or the average number of checks of merged PRs, whatever is less.")
+
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Interactive dashboard of your commits and their PRs",
+		Description: "Displays the new commits on " + util.GetMainBranchForHelp() + " along with the status of\n" +
+			"their PRs: the progress of the PR checks and who has approved it.\n" +
+			"The status is refreshed every \"--poll-frequency\".\n" +
+			"\n" +
+			"Commands can be run on the highlighted commit with a single keystroke.\n" +
+			"Once the command completes the dashboard is shown again.",
+		Usage: "sd " + flagSet.Name() + " [flags]\n" +
+			"\n" +
+			"   [n]        new\n" +
+			"   [u]        update\n" +
+			"   [a]        add-reviewers\n" +
+			"   [c]        checkout (exits dashboard)\n" +
+			"   [r]        replace-commit\n" +
+			"   [up,k]     moves cursor up\n" +
+			"   [down,j]   moves cursor down\n" +
+			"   [q,esc]    quits\n",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			showDashboard(asyncConfig, *minChecks, *pollFrequency)
+		}}
+}
+
+// Shows the dashboard until the user quits, executing any actions selected along the way.
+func showDashboard(asyncConfig util.AsyncAppConfig, minChecks int, pollFrequency time.Duration) {
+	for {
+		selection := interactive.ShowDashboard(asyncConfig, minChecks, pollFrequency)
+		if selection.Action == interactive.DashboardActionNone {
+			return
+		}
+		slog.Info(fmt.Sprint("Executing ", selection.Action, " for ", selection.Commit.Commit, " ", selection.Commit.Subject))
+		executeSubcommand(asyncConfig, string(selection.Action), "--indicator="+string(templates.IndicatorTypeCommit), selection.Commit.Commit)
+		if selection.Action == interactive.DashboardActionCheckout {
+			// No longer on main branch so there is nothing left to show.
+			return
+		}
+	}
+}
+
+// Executes another sd command from within a command.
+func executeSubcommand(asyncConfig util.AsyncAppConfig, commandName string, args ...string) {
+	commands := newCommands()
+	selectedIndex := slices.IndexFunc(commands, func(command Command) bool {
+		return command.FlagSet.Name() == commandName
+	})
+	if selectedIndex == -1 {
+		panic("Unknown command " + commandName)
+	}
+	subcommand := commands[selectedIndex]
+	subcommand.FlagSet.Usage = func() {}
+	subcommand.FlagSet.SetOutput(io.Discard)
+	if parseErr := subcommand.FlagSet.Parse(args); parseErr != nil {
+		panic(fmt.Sprint("Could not parse arguments for ", commandName, " ", args, ": ", parseErr))
+	}
+	applyConfigFlags(subcommand.FlagSet)
+	subcommand.OnSelected(asyncConfig, subcommand)
+}
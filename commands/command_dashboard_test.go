@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdDashboard_WhenCheckoutSelected_ChecksOutBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	testParseArguments("new", "1")
+
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 1)
+
+	interactive.SendToProgram(0, interactive.NewMessageRune('c'))
+	testParseArguments("dashboard", "--min-checks", "1")
+
+	assert.Equal(allCommits[0].Branch, util.GetCurrentBranchName())
+}
+
+func TestSdDashboard_WhenNewSelected_CreatesPrAndShowsDashboardAgain(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+
+	allCommits := templates.GetAllCommits()
+
+	interactive.SendToProgram(0, interactive.NewMessageRune('n'))
+	interactive.SendToProgram(1, interactive.NewMessageRune('q'))
+	testParseArguments("dashboard", "--min-checks", "1")
+
+	assert.True(util.RemoteHasBranch(allCommits[0].Branch))
+}
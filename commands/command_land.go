@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// How a PR is merged by "sd land".
+type mergeMethod string
+
+const (
+	mergeMethodSquash mergeMethod = "squash"
+	mergeMethodRebase mergeMethod = "rebase"
+	mergeMethodMerge  mergeMethod = "merge"
+)
+
+func (method mergeMethod) isValid() bool {
+	switch method {
+	case mergeMethodSquash, mergeMethodRebase, mergeMethodMerge:
+		return true
+	default:
+		return false
+	}
+}
+
+type landOptions struct {
+	method       mergeMethod
+	auto         bool
+	minChecks    int
+	minApprovals int
+	pollInterval time.Duration
+	// How long to wait for a PR to be merged, or 0 to wait indefinitely.
+	timeout time.Duration
+}
+
+func createLandCommand() Command {
+	flagSet := flag.NewFlagSet("land", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	method := flagSet.String("method", "", "How to merge the PR: squash, rebase, or merge")
+	auto := flagSet.Bool("auto", false,
+		"Enable auto-merge instead of merging immediately. If the base branch\n"+
+			"has a merge queue then the PR is added to the queue. Checks may\n"+
+			"still be pending.")
+	bottom := flagSet.Int("bottom", 0,
+		"Land the bottom N PRs of the stack, one after the other, instead of\n"+
+			"a single PR.")
+	minChecks := flagSet.Int("min-checks", 0,
+		"Minimum number of checks that must have passed. Defaults to the\n"+
+			"\"min-checks\" config, where -1 means to use 4 or the average number\n"+
+			"of checks of merged PRs, whatever is less.")
+	minApprovals := flagSet.Int("min-approvals", 0, "Minimum number of approvals of the latest commit")
+	timeout := flagSet.Duration("timeout", time.Hour,
+		"How long to wait for each PR to be merged, such as while it is in a\n"+
+			"merge queue, before giving up. Use 0 to wait indefinitely.")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Merge a PR once it is ready and then rebase " + util.GetMainBranchForHelp(),
+		Description: "Verifies that the checks of a PR have passed and that it is approved,\n" +
+			"merges it, waits for the merge to complete, and then runs\n" +
+			"\"sd rebase-main\" to drop the merged commit from " + util.GetMainBranchForHelp() + ".\n" +
+			"\n" +
+			"Use \"--bottom\" to land several PRs, starting from the bottom of the\n" +
+			"stack. All of the PRs are verified before any are merged, and each\n" +
+			"PR waits for its checks to pass again after the one below it lands.",
+		Usage:    "sd " + flagSet.Name() + " [flags] [commitIndicator]",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() > 1 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			if *bottom != 0 && flagSet.NArg() > 0 {
+				commandError(asyncConfig.App, flagSet, "cannot use both --bottom and commitIndicator", command.Usage)
+			}
+			if !mergeMethod(*method).isValid() {
+				commandError(asyncConfig.App, flagSet, "invalid merge method "+*method, command.Usage)
+			}
+			var targetCommits []templates.GitLog
+			if *bottom > 0 {
+				targetCommits = getBottomCommits(*bottom)
+			} else {
+				selectCommitOptions := interactive.CommitSelectionOptions{
+					Prompt:      "What PR do you want to land?",
+					CommitType:  interactive.CommitTypePr,
+					MultiSelect: false,
+				}
+				targetCommits = getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectCommitOptions)
+			}
+			land(asyncConfig.App, targetCommits, landOptions{
+				method:       mergeMethod(*method),
+				auto:         *auto,
+				minChecks:    *minChecks,
+				minApprovals: *minApprovals,
+				pollInterval: util.GetConfigDuration("poll-frequency"),
+				timeout:      *timeout,
+			})
+		}}
+}
+
+// Returns the bottom count commits on main, oldest first.
+func getBottomCommits(count int) []templates.GitLog {
+	newCommits := templates.GetNewCommits("HEAD")
+	if count > len(newCommits) {
+		panic(fmt.Sprint("Cannot land ", count, " PRs, there are only ", len(newCommits), " commits"))
+	}
+	bottomCommits := slices.Clone(newCommits[len(newCommits)-count:])
+	slices.Reverse(bottomCommits)
+	return bottomCommits
+}
+
+// Merges the PRs of targetCommits in order, rebasing main after each one.
+func land(appConfig util.AppConfig, targetCommits []templates.GitLog, options landOptions) {
+	util.RequireMainBranch()
+	if options.minChecks == -1 {
+		options.minChecks = util.GetMinChecks()
+	}
+	// Verify all PRs before merging any, so that a stack is not left partially landed.
+	for _, targetCommit := range targetCommits {
+		verifyCanLand(targetCommit, options)
+	}
+	for i, targetCommit := range targetCommits {
+		if i > 0 && !options.auto {
+			// Checks run again once the PR below has landed and the branches are restacked.
+			waitForLandChecks(targetCommit, options)
+		}
+		mergePr(targetCommit, options)
+		waitForLanded(targetCommit, options)
+		if !rebaseMain(appConfig, false) {
+			if i < len(targetCommits)-1 {
+				slog.Warn(fmt.Sprint("Not landing the remaining ", len(targetCommits)-i-1, " PRs"))
+			}
+			return
+		}
+	}
+}
+
+// Panics if the PR of targetCommit is not approved or if its checks have not passed.
+func verifyCanLand(targetCommit templates.GitLog, options landOptions) {
+	slog.Info(fmt.Sprint("Verifying PR for ", targetCommit.Commit, " ", targetCommit.Subject))
+	status := util.GetPullRequestStatus(targetCommit.Branch, options.minChecks)
+	if status.State != util.PullRequestStateOpen {
+		panic(fmt.Sprint("PR for ", targetCommit.Branch, " is not open"))
+	}
+	if len(status.Approvers) < options.minApprovals {
+		panic(fmt.Sprint("PR for ", targetCommit.Branch, " has ", len(status.Approvers),
+			" approvals of its latest commit, ", options.minApprovals, " required"))
+	}
+	if status.Checks.IsFailing() {
+		panic(fmt.Sprint("Checks failed for ", targetCommit.Branch, ": ", status.Checks.Failing, " of ", status.Checks.Total()))
+	}
+	if !options.auto && !status.Checks.IsSuccess() {
+		panic(fmt.Sprint("Checks have not passed for ", targetCommit.Branch, ". "+
+			"Passed: ", status.Checks.Passing,
+			" | Pending: ", status.Checks.Pending,
+			" | Required: ", status.Checks.MinChecks,
+			"\nUse \"--auto\" to merge once they pass."))
+	}
+}
+
+// Waits for the checks of the PR of targetCommit to pass. Panics if any fail.
+func waitForLandChecks(targetCommit templates.GitLog, options landOptions) {
+	for {
+		checks := util.GetChecksStatus(targetCommit.Branch, options.minChecks)
+		if checks.IsFailing() {
+			panic(fmt.Sprint("Checks failed for ", targetCommit.Branch, ": ", checks.Failing, " of ", checks.Total()))
+		}
+		if checks.IsSuccess() {
+			return
+		}
+		slog.Info(fmt.Sprint("Checks pending for ", targetCommit.Branch, ". Completed: ", int(checks.PercentageComplete()*100), "%"))
+		util.Sleep(options.pollInterval)
+	}
+}
+
+func mergePr(targetCommit templates.GitLog, options landOptions) {
+	if options.auto {
+		slog.Info("Enabling auto-merge for " + targetCommit.Branch)
+	} else {
+		slog.Info("Merging " + targetCommit.Branch)
+	}
+	util.GetForge().MergePullRequest(targetCommit.Branch, string(options.method), options.auto)
+}
+
+// Waits for the PR of targetCommit to be merged. Panics if it is closed instead, or if it is not
+// merged within options.timeout.
+func waitForLanded(targetCommit templates.GitLog, options landOptions) {
+	for waited := time.Duration(0); ; waited += options.pollInterval {
+		pullRequest, ok := util.GetForge().GetPullRequests([]string{targetCommit.Branch})[targetCommit.Branch]
+		if !ok {
+			panic("No PR found for branch " + targetCommit.Branch)
+		}
+		switch pullRequest.State {
+		case util.PullRequestStateMerged:
+			slog.Info("Merged " + targetCommit.Branch)
+			return
+		case util.PullRequestStateClosed:
+			panic("PR for " + targetCommit.Branch + " was closed without being merged")
+		}
+		if options.timeout > 0 && waited >= options.timeout {
+			panic(fmt.Sprint("PR for ", targetCommit.Branch, " was not merged within ", options.timeout,
+				". Use \"sd rebase-main\" once it is merged."))
+		}
+		slog.Info("Not merged yet...")
+		util.Sleep(options.pollInterval)
+	}
+}
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Fakes that "gh pr merge" merges the PR.
+func setMergeResponse(testExecutor *util.TestExecutor) {
+	testExecutor.SetResponseFunc("", nil, func(programName string, args ...string) bool {
+		if programName != "gh" || len(args) < 3 || !slices.Equal(args[0:2], []string{"pr", "merge"}) {
+			return false
+		}
+		testutil.SetMergedPullRequest(args[2], "fakeMergeCommit")
+		return true
+	})
+}
+
+func getPrMergeArgs(testExecutor *util.TestExecutor) [][]string {
+	merges := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:2], []string{"pr", "merge"})
+	})
+	return util.MapSlice(merges, func(next util.ExecutedResponse) []string {
+		return next.Args
+	})
+}
+
+func TestSdLand_WhenApproved_MergesAndDropsCommit(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testParseArguments("new", "2")
+	branchName := templates.GetNewCommits("HEAD")[1].Branch
+	testutil.SetOpenPullRequest(branchName, 1, "mybestie")
+	setMergeResponse(testExecutor)
+
+	testParseArguments("land", "--min-checks=1", "2")
+
+	assert.Equal([][]string{{"pr", "merge", branchName, "--squash"}}, getPrMergeArgs(testExecutor))
+	newCommits := templates.GetNewCommits("HEAD")
+	assert.Equal(1, len(newCommits))
+	assert.Equal("second", newCommits[0].Subject)
+	assert.False(util.GetLocalHasBranchOrDie(branchName))
+}
+
+func TestSdLand_WhenNotApproved_DoesNotMerge(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	testutil.SetOpenPullRequest(templates.GetNewCommits("HEAD")[0].Branch, 1)
+	setMergeResponse(testExecutor)
+
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "land", "--min-checks=1", "1")
+	})
+
+	assert.Contains(out.String(), "has 0 approvals of its latest commit, 1 required")
+	assert.Equal(0, len(getPrMergeArgs(testExecutor)))
+}
+
+func TestSdLand_WithBottom_LandsPrsInOrder(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+	testParseArguments("new", "3")
+	testParseArguments("new", "2")
+	allCommits := templates.GetNewCommits("HEAD")
+	testutil.SetOpenPullRequest(allCommits[2].Branch, 1, "mybestie")
+	testutil.SetOpenPullRequest(allCommits[1].Branch, 1, "mybestie")
+	setMergeResponse(testExecutor)
+
+	testParseArguments("land", "--min-checks=1", "--method=rebase", "--bottom=2")
+
+	assert.Equal([][]string{
+		{"pr", "merge", allCommits[2].Branch, "--rebase"},
+		{"pr", "merge", allCommits[1].Branch, "--rebase"},
+	}, getPrMergeArgs(testExecutor))
+	newCommits := templates.GetNewCommits("HEAD")
+	assert.Equal(1, len(newCommits))
+	assert.Equal("third", newCommits[0].Subject)
+}
+
+func TestSdLand_WithAutoAndPendingChecks_EnablesAutoMerge(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	branchName := templates.GetNewCommits("HEAD")[0].Branch
+	testutil.SetOpenPullRequest(branchName, 0, "mybestie")
+	pullRequest := testutil.GetFakeGithubClient().GetPullRequests([]string{branchName})[branchName]
+	pullRequest.Checks = []util.PullRequestCheck{{Status: "IN_PROGRESS"}}
+	testutil.GetFakeGithubClient().SetPullRequest(pullRequest)
+	setMergeResponse(testExecutor)
+
+	testParseArguments("land", "--min-checks=1", "--auto", "1")
+
+	assert.Equal([][]string{{"pr", "merge", branchName, "--squash", "--auto"}}, getPrMergeArgs(testExecutor))
+	assert.Equal(0, len(templates.GetNewCommits("HEAD")))
+}
+
+func TestSdLand_WhenClosedWithoutMerging_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	branchName := templates.GetNewCommits("HEAD")[0].Branch
+	testutil.SetOpenPullRequest(branchName, 1, "mybestie")
+	testExecutor.SetResponseFunc("", nil, func(programName string, args ...string) bool {
+		if programName != "gh" || len(args) < 2 || !slices.Equal(args[0:2], []string{"pr", "merge"}) {
+			return false
+		}
+		pullRequest := testutil.GetFakeGithubClient().GetPullRequests([]string{branchName})[branchName]
+		pullRequest.State = util.PullRequestStateClosed
+		testutil.GetFakeGithubClient().SetPullRequest(pullRequest)
+		return true
+	})
+
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "land", "--min-checks=1", "1")
+	})
+
+	assert.Contains(out.String(), "was closed without being merged")
+	assert.Equal(1, len(templates.GetNewCommits("HEAD")))
+}
+
+func TestSdLand_WhenNotMergedWithinTimeout_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	testutil.SetOpenPullRequest(templates.GetNewCommits("HEAD")[0].Branch, 1, "mybestie")
+	// The PR stays open, such as while it waits in a merge queue.
+	testExecutor.SetResponse("", nil, "gh", "pr", "merge", util.MatchAnyRemainingArgs)
+
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "land", "--min-checks=1", "--timeout=10m", "1")
+	})
+
+	assert.Contains(out.String(), "was not merged within 10m0s")
+	assert.Equal(1, len(templates.GetNewCommits("HEAD")))
+}
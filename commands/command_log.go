@@ -31,12 +31,17 @@ func createLogCommand() Command {
 			"using this workflow). If there is more than one commit on the\n" +
 			"associated branch, those commits are also listed (indented under the\n" +
 			"their associated commit summary).",
-		Usage:           "sd " + flagSet.Name(),
-		DefaultLogLevel: slog.LevelError,
+		Usage:            "sd " + flagSet.Name(),
+		DefaultLogLevel:  slog.LevelError,
+		StructuredOutput: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() != 0 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
 			}
+			if isStructuredOutput(asyncConfig.App) {
+				printStructuredOutput(asyncConfig.App, getLogOutput(), commitOutputHeaders, commitOutput.tsvRow)
+				return
+			}
 			printGitLog(asyncConfig.App.Io)
 		},
 	}
@@ -47,19 +52,14 @@ func printGitLog(stdIo util.StdIo) {
 	if util.GetCurrentBranchName() != util.GetMainBranchOrDie() {
 		gitArgs := []string{"--no-pager", "log", "--pretty=oneline", "--abbrev-commit"}
 		if util.RemoteHasBranch(util.GetMainBranchOrDie()) {
-			gitArgs = append(gitArgs, "origin/"+util.GetMainBranchOrDie()+"..HEAD")
+			gitArgs = append(gitArgs, util.GetRemoteMainBranchOrDie()+"..HEAD")
 		}
 		gitArgs = append(gitArgs, "--color=always")
 		util.ExecuteOrDie(util.ExecuteOptions{Io: stdIo}, "git", gitArgs...)
 		return
 	}
 	logs := templates.GetNewCommits("HEAD")
-	gitBranchArgs := make([]string, 0, len(logs)+2)
-	gitBranchArgs = append(gitBranchArgs, "branch", "-l")
-	for _, log := range logs {
-		gitBranchArgs = append(gitBranchArgs, log.Branch)
-	}
-	checkedBranches := strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", gitBranchArgs...))
+	checkedBranches := getExistingBranches(logs)
 	for i, log := range logs {
 		numberPrefix := getNumberPrefix(i, len(logs))
 		if slices.Contains(checkedBranches, log.Branch) {
@@ -82,6 +82,39 @@ func printGitLog(stdIo util.StdIo) {
 	}
 }
 
+// Returns the branches of logs that exist locally.
+func getExistingBranches(logs []templates.GitLog) []string {
+	gitBranchArgs := make([]string, 0, len(logs)+2)
+	gitBranchArgs = append(gitBranchArgs, "branch", "-l")
+	for _, log := range logs {
+		gitBranchArgs = append(gitBranchArgs, log.Branch)
+	}
+	return strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", gitBranchArgs...))
+}
+
+// Returns a record of each new commit using only local data, so without PR state or checks.
+func getLogOutput() []commitOutput {
+	logs := templates.GetNewCommits("HEAD")
+	existingBranches := getExistingBranches(logs)
+	records := make([]commitOutput, 0, len(logs))
+	for i, log := range logs {
+		record := commitOutput{
+			Index:     i + 1,
+			Commit:    log.Commit,
+			Subject:   log.Subject,
+			Branch:    log.Branch,
+			HasBranch: slices.Contains(existingBranches, log.Branch),
+			Approvers: []string{},
+			Owners:    []string{},
+		}
+		if entry, ok := util.GetStackEntryForBranch(log.Branch); ok {
+			record.PrNumber = entry.PullRequest
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
 func getNumberPrefix(i int, numLogs int) string {
 	maxIndex := fmt.Sprint(numLogs)
 	currentIndex := fmt.Sprint(i + 1)
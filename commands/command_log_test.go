@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"encoding/json"
 	"log/slog"
 	"testing"
 
@@ -127,3 +128,25 @@ func TestSdLog_WhenMultiplePrs_MatchesAllPrs(t *testing.T) {
 	assert.Regexp("✅.*first", out)
 	assert.Regexp("✅.*second", out)
 }
+
+func TestSdLog_WithJsonOutput_OutputsRecords(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testExecutor.SetResponse("https://github.com/owner/repo/pull/42", nil, "gh", "pr", "create", util.MatchAnyRemainingArgs)
+	testParseArguments("new", "2")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	out := testParseArguments("--output", "json", "log")
+
+	var records []commitOutput
+	assert.Nil(json.Unmarshal([]byte(out), &records))
+	assert.Equal([]commitOutput{
+		{Index: 1, Commit: allCommits[0].Commit, Subject: "second", Branch: allCommits[0].Branch,
+			Approvers: []string{}, Owners: []string{}},
+		{Index: 2, Commit: allCommits[1].Commit, Subject: "first", Branch: allCommits[1].Branch,
+			HasBranch: true, PrNumber: 42, Approvers: []string{}, Owners: []string{}},
+	}, records)
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"flag"
+	"log/slog"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createMigrateChangeIdsCommand() Command {
+	flagSet := flag.NewFlagSet("migrate-change-ids", flag.ContinueOnError)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Add change ids to the commits that already have PRs",
+		Description: "Adds a " + templates.ChangeIdTrailer + " trailer to each commit on " + util.GetMainBranchForHelp() + " that has\n" +
+			"a branch but no change id yet, for use after setting the \"change-id\"\n" +
+			"config, see \"sd new --help\".\n" +
+			"\n" +
+			"The commits keep their existing branches and PRs, so that the commits\n" +
+			"can be reworded without losing them.",
+		Usage:    "sd " + flagSet.Name(),
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			migrated := migrateChangeIds(asyncConfig.App)
+			if len(migrated) > 0 {
+				writer := tabwriter.NewWriter(asyncConfig.App.Io.Out, 0, 8, 2, ' ', 0)
+				util.Fprintln(writer, "Commit\tBranch\tChange Id")
+				for _, gitLog := range migrated {
+					util.Fprintln(writer, gitLog.Commit+"\t"+gitLog.Branch+"\t"+gitLog.ChangeId)
+				}
+				if err := writer.Flush(); err != nil {
+					panic(err)
+				}
+			}
+		}}
+}
+
+// Adds change ids to the new commits that have a branch but no change id, and records that they
+// keep their branches. Returns the migrated commits.
+func migrateChangeIds(appConfig util.AppConfig) []templates.GitLog {
+	util.RequireMainBranch()
+	withoutChangeIds := util.FilterSlice(templates.GetNewCommits("HEAD"), func(gitLog templates.GitLog) bool {
+		return gitLog.ChangeId == "" && util.GetLocalHasBranchOrDie(gitLog.Branch)
+	})
+	if len(withoutChangeIds) == 0 {
+		slog.Info("All commits with a branch already have a change id")
+		return withoutChangeIds
+	}
+	shouldPopStash := util.Stash("migrate-change-ids")
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		util.PopStash(shouldPopStash)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	migrated := addChangeIds(appConfig, withoutChangeIds)
+	for i := range migrated {
+		// Keep the existing branch, as the branch of a PR cannot be renamed.
+		migrated[i].Branch = withoutChangeIds[i].Branch
+		util.RecordStackEntry(migrated[i].Commit, migrated[i].Branch, 0)
+	}
+	rollbackManager.Clear()
+	return migrated
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdMigrateChangeIds_AddsChangeIdsToCommitsWithBranches(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+	testParseArguments("new", "2")
+	before := templates.GetNewCommits("HEAD")
+
+	out := testParseArguments("migrate-change-ids")
+
+	after := templates.GetNewCommits("HEAD")
+	assert.Equal("", after[0].ChangeId)
+	assert.NotEqual("", after[1].ChangeId)
+	assert.NotEqual(before[1].Commit, after[1].Commit)
+	// Keeps the existing branch of the PR.
+	assert.Equal(before[1].Branch, after[1].Branch)
+	assert.Contains(out, before[1].Branch)
+	assert.Contains(out, after[1].ChangeId)
+}
+
+func TestSdMigrateChangeIds_WhenNoCommitsNeedChangeIds_DoesNothing(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.AddCommit("second", "")
+	before := templates.GetNewCommits("HEAD")
+
+	testParseArguments("migrate-change-ids")
+
+	assert.Equal(before, templates.GetNewCommits("HEAD"))
+}
@@ -0,0 +1,195 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createMoveCommand() Command {
+	flagSet := flag.NewFlagSet("move", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Move a commit up or down the stack",
+		Description: "Moves a commit on " + util.GetMainBranchForHelp() + " to the position of another commit, as\n" +
+			"displayed by \"sd log\". The commit is placed above the other commit if\n" +
+			"it is moved up, or below it if it is moved down.\n" +
+			"\n" +
+			"If no commits are specified then the commits are reordered interactively.\n" +
+			"\n" +
+			"A PR that was stacked on a PR that is now above it is stacked on the\n" +
+			"nearest PR below it instead, or on " + util.GetMainBranchForHelp() + " if there is none. Its\n" +
+			"branch is rebased and force pushed, along with any branches stacked on it.",
+		Usage:    "sd " + flagSet.Name() + " [flags] [commitIndicator [destinationCommitIndicator]]",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() > 2 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			if flagSet.NArg() == 0 {
+				reorderInteractively(asyncConfig.App, command)
+				return
+			}
+			indicatorType := checkIndicatorFlag(asyncConfig.App, command, indicatorTypeString)
+			targetCommit := templates.GetBranchInfo(flagSet.Arg(0), indicatorType)
+			selectDestinationOptions := interactive.CommitSelectionOptions{
+				Prompt:          "Move \"" + targetCommit.Subject + "\" to the position of which commit?",
+				CommitType:      interactive.CommitTypeBoth,
+				MultiSelect:     false,
+				DisabledCommits: []string{targetCommit.Commit},
+			}
+			destinationCommit := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(1)}, indicatorTypeString, selectDestinationOptions)[0]
+			move(asyncConfig.App, targetCommit, destinationCommit)
+		}}
+}
+
+// Lets the user reorder the new commits on main and then moves them into that order.
+func reorderInteractively(appConfig util.AppConfig, command Command) {
+	if !interactive.InteractiveEnabled(appConfig) {
+		commandError(appConfig, command.FlagSet, "Commits not specified and cannot ask interactively because not a terminal", command.Usage)
+	}
+	newCommits := templates.GetNewCommits("HEAD")
+	if len(newCommits) < 2 {
+		commandError(appConfig, command.FlagSet, "Commits not specified and there are not enough new commits to reorder", command.Usage)
+	}
+	rows := make([][]string, len(newCommits))
+	for i, gitLog := range newCommits {
+		rows[i] = []string{fmt.Sprint(i + 1), gitLog.Commit, gitLog.Subject}
+	}
+	selectedOrder, ok := interactive.GetReorderSelection("What order should the commits be in?", []string{"Index", "Commit", "Summary"}, rows, appConfig.Io)
+	if !ok {
+		appConfig.Exit(0)
+	}
+	// The commits are displayed newest first.
+	order := util.MapSlice(selectedOrder, func(index int) string {
+		return newCommits[index].Commit
+	})
+	slices.Reverse(order)
+	reorderCommits(appConfig, order, "reorder")
+}
+
+// Moves gitLog to the position of destination on main.
+func move(appConfig util.AppConfig, gitLog templates.GitLog, destination templates.GitLog) {
+	util.RequireMainBranch()
+	templates.RequireCommitOnMain(gitLog.Commit)
+	templates.RequireCommitOnMain(destination.Commit)
+	if gitLog.Commit == destination.Commit {
+		panic("Cannot move a commit to its own position")
+	}
+	order := getCommitOrder()
+	from := slices.Index(order, gitLog.Commit)
+	to := slices.Index(order, destination.Commit)
+	order = slices.Insert(slices.Delete(order, from, from+1), to, gitLog.Commit)
+	slog.Info(fmt.Sprint("Moving ", gitLog.Commit, " ", gitLog.Subject, " to the position of ", destination.Commit))
+	reorderCommits(appConfig, order, "move "+gitLog.Commit+" "+gitLog.Subject)
+}
+
+// Rebases the new commits on main into newOrder, oldest first, and then restacks the PRs whose
+// base branch is no longer below them. stashMessage describes the change for [util.Stash].
+func reorderCommits(appConfig util.AppConfig, newOrder []string, stashMessage string) {
+	util.RequireMainBranch()
+	order := getCommitOrder()
+	oldest := -1
+	for i := range order {
+		if order[i] != newOrder[i] {
+			oldest = i
+			break
+		}
+	}
+	if oldest == -1 {
+		slog.Info("The order of the commits did not change")
+		return
+	}
+	shouldPopStash := util.Stash(stashMessage)
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		util.PopStash(shouldPopStash)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	rebaseInOrder(appConfig, order[oldest]+"^", newOrder[oldest:])
+	rollbackManager.Clear()
+	templates.FollowRewrittenCommits()
+	if restackReorderedBranches(appConfig) {
+		slog.Info("Restacking branches stacked on the moved PRs")
+		restack(appConfig, []string{})
+	}
+}
+
+// Stacks each PR whose base branch is now above it on the nearest PR below it, or on main if
+// there is none. Returns whether any PRs were changed.
+func restackReorderedBranches(appConfig util.AppConfig) bool {
+	newCommits := templates.GetNewCommits("HEAD")
+	changed := false
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+			panic(r)
+		}
+	}()
+	for _, stackedCommit := range getStackedCommits(newCommits) {
+		baseBranch, baseCommit := util.GetStackedBase(stackedCommit.Branch)
+		index := slices.IndexFunc(newCommits, func(gitLog templates.GitLog) bool {
+			return gitLog.Commit == stackedCommit.Commit
+		})
+		baseIndex := slices.IndexFunc(newCommits, func(gitLog templates.GitLog) bool {
+			return gitLog.Branch == baseBranch
+		})
+		if baseIndex == -1 || baseIndex > index {
+			// The base branch is still below, or was merged which restack takes care of.
+			continue
+		}
+		changed = true
+		newBaseIndex := slices.IndexFunc(newCommits[index+1:], func(gitLog templates.GitLog) bool {
+			return util.GetLocalHasBranchOrDie(gitLog.Branch)
+		})
+		if newBaseIndex != -1 {
+			newBase := newCommits[index+1+newBaseIndex].Branch
+			slog.Info(fmt.Sprint("Changing base of PR of ", stackedCommit.Branch, " to ", newBase))
+			util.GetForge().EditPullRequest(stackedCommit.Branch, util.EditPullRequestOptions{BaseBranch: newBase})
+			// Keep the old base commit so that restack rebases the branch onto its new base.
+			util.SetStackedBase(stackedCommit.Branch, newBase, baseCommit)
+			continue
+		}
+		if baseCommit == "" {
+			baseCommit = getForkPoint(baseBranch, stackedCommit.Branch)
+		}
+		slog.Info(fmt.Sprint("Rebasing ", stackedCommit.Branch, " onto ", util.GetRemoteMainBranchOrDie()))
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", stackedCommit.Branch)
+		rollbackManager.SaveState()
+		util.ExecuteOrDie(util.ExecuteOptions{Io: appConfig.Io}, "git", "rebase", "--onto", util.GetRemoteMainBranchOrDie(), baseCommit, stackedCommit.Branch)
+		slog.Info("Force pushing " + stackedCommit.Branch)
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "-f", util.GetPushRemote(), stackedCommit.Branch)
+		slog.Info(fmt.Sprint("Changing base of PR of ", stackedCommit.Branch, " to ", util.GetMainBranchOrDie()))
+		util.GetForge().EditPullRequest(stackedCommit.Branch, util.EditPullRequestOptions{BaseBranch: util.GetMainBranchOrDie()})
+		util.ClearStackedBase(stackedCommit.Branch)
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+	}
+	rollbackManager.Clear()
+	return changed
+}
+
+// Returns the abbreviated hashes of the new commits on main, oldest first, as used by
+// [rebaseInOrder].
+func getCommitOrder() []string {
+	order := util.MapSlice(templates.GetNewCommits("HEAD"), func(gitLog templates.GitLog) string {
+		return gitLog.Commit
+	})
+	slices.Reverse(order)
+	return order
+}
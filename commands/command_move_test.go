@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func getSubjects(gitLogs []templates.GitLog) []string {
+	return util.MapSlice(gitLogs, func(gitLog templates.GitLog) string {
+		return gitLog.Subject
+	})
+}
+
+func TestSdMove_WhenMovedUp_PlacesCommitAboveDestination(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+
+	testParseArguments("move", "3", "1")
+
+	assert.Equal([]string{"first", "third", "second"}, getSubjects(templates.GetNewCommits("HEAD")))
+}
+
+func TestSdMove_WhenDestinationSelected_PlacesCommitBelowDestination(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+
+	// The cursor starts on "second" as "third" is the commit being moved.
+	interactive.SendToProgram(0, interactive.NewMessageKey(tea.KeyEnter))
+	testParseArguments("move", "1")
+
+	assert.Equal([]string{"second", "third", "first"}, getSubjects(templates.GetNewCommits("HEAD")))
+}
+
+func TestSdMove_WhenCommitHasPr_KeepsBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testParseArguments("new", "2")
+	branch := templates.GetNewCommits("HEAD")[1].Branch
+
+	testParseArguments("move", "2", "1")
+
+	newCommits := templates.GetNewCommits("HEAD")
+	assert.Equal([]string{"first", "second"}, getSubjects(newCommits))
+	assert.Equal(branch, newCommits[0].Branch)
+}
+
+func TestSdMove_WhenNoCommitsSpecified_ReordersInteractively(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+
+	// Pick up "third" and move it to the bottom.
+	interactive.SendToProgram(0,
+		interactive.NewMessageRune(' '),
+		interactive.NewMessageKey(tea.KeyDown),
+		interactive.NewMessageKey(tea.KeyDown),
+		interactive.NewMessageRune(' '),
+		interactive.NewMessageKey(tea.KeyEnter),
+	)
+	testParseArguments("move")
+
+	assert.Equal([]string{"second", "first", "third"}, getSubjects(templates.GetNewCommits("HEAD")))
+}
+
+func TestSdMove_WhenStackedPrMovedBelowBase_StacksOnMain(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "2")
+	testParseArguments("new", "--stack", "1")
+
+	testParseArguments("move", "1", "2")
+
+	assert.Equal([]string{"first", "second"}, getSubjects(templates.GetNewCommits("HEAD")))
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args, []string{"pr", "edit", allCommits[0].Branch, "--base", util.GetMainBranchOrDie()})
+	}))
+	baseBranch, _ := util.GetStackedBase(allCommits[0].Branch)
+	assert.Equal("", baseBranch)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[0].Branch)
+	assert.Equal([]string{"second"}, getSubjects(templates.GetNewCommits("HEAD")))
+	assert.Equal(util.GetBranchLatestCommit(allCommits[0].Branch), util.GetBranchLatestCommit("origin/"+allCommits[0].Branch))
+}
+
+func TestSdMove_WhenStackedPrMovedBelowBase_StacksOnPrBelow(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "3")
+	testParseArguments("new", "2")
+	testParseArguments("new", "--stack", "1")
+
+	testParseArguments("move", "2", "1")
+
+	assert.Equal([]string{"second", "third", "first"}, getSubjects(templates.GetNewCommits("HEAD")))
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args, []string{"pr", "edit", allCommits[0].Branch, "--base", allCommits[2].Branch})
+	}))
+	baseBranch, _ := util.GetStackedBase(allCommits[0].Branch)
+	assert.Equal(allCommits[2].Branch, baseBranch)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[0].Branch)
+	assert.Equal([]string{"third", "first"}, getSubjects(templates.GetNewCommits("HEAD")))
+	assert.Equal(util.GetBranchLatestCommit(allCommits[0].Branch), util.GetBranchLatestCommit("origin/"+allCommits[0].Branch))
+}
@@ -6,6 +6,8 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
@@ -19,11 +21,16 @@ import (
 func createNewCommand() Command {
 	flagSet := flag.NewFlagSet("new", flag.ContinueOnError)
 
-	draft := flagSet.Bool("draft", true, "Whether to create the PR as draft")
+	draft := flagSet.Bool("draft", false, "Whether to create the PR as draft")
 	featureFlag := flagSet.String("feature-flag", "", "Value for FEATURE_FLAG in PR description")
 	baseBranch := flagSet.String("base", "", "Base branch for Pull Request. Default is "+util.GetMainBranchForHelp())
+	stack := flagSet.Bool("stack", false,
+		"Whether to stack the PR on top of the PR of the nearest commit below it.\n"+
+			"The PR is then based on that commit's branch instead of "+util.GetMainBranchForHelp()+".\n"+
+			"Use \"sd restack\" to keep stacked branches up to date.")
 
 	reviewers, silent, minChecks := addReviewersFlags(flagSet)
+	pickCount, pickStrategy := addPickReviewersFlags(flagSet)
 
 	indicatorTypeString := addIndicatorFlag(flagSet)
 
@@ -35,7 +42,17 @@ func createNewCommand() Command {
 			"This command first creates an associated branch, (with a name based\n" +
 			"on the commit summary), and then uses Github CLI to create a PR.\n" +
 			"\n" +
-			"Can also add reviewers once PR checks have passed, see \"--reviewers\" flag.",
+			"Can also add reviewers once PR checks have passed, see \"--reviewers\" flag.\n" +
+			"\n" +
+			"With the \"--stack\" flag the PR is based on the branch of the nearest\n" +
+			"commit below it that has a PR, so that reviewers only see the changes\n" +
+			"of the commit.\n" +
+			"\n" +
+			"If the \"change-id\" config is set then a " + templates.ChangeIdTrailer + " trailer is\n" +
+			"first added to the commit, and the branch is named after it instead of\n" +
+			"the commit summary, so that the commit can be reworded without losing\n" +
+			"its branch. Use \"sd migrate-change-ids\" for commits that already have\n" +
+			"PRs.",
 		Usage: "sd new [flags] [commitIndicator]\n" +
 			"\n" +
 			"If commitIndicator is missing then you will be prompted to select commit:\n" +
@@ -65,26 +82,54 @@ func createNewCommand() Command {
 			"   branch-name.template:      templates/config/branch-name.template\n" +
 			"   pr-description.template:   templates/config/pr-description.template\n" +
 			"   pr-title.template:         templates/config/pr-title.template\n" +
+			"   stack.template:            templates/config/stack.template\n" +
 			"\n" +
 			"To change a template, copy the default from templates/config/ into\n" +
-			"~/.gh-stacked-diff/ and modify contents.\n" +
+			"~/.gh-stacked-diff/, or into " + templates.RepoTemplatesDir + "/ of the repository to share\n" +
+			"it, and modify contents. See \"sd templates --help\" for the functions\n" +
+			"and partials that templates can use, and to preview them.\n" +
+			"\n" +
+			"Use \"sd amend-pr\" to update a PR after changing its commit message or\n" +
+			"templates. Sections of pr-description.template between\n" +
+			"\"<!-- sd-preserve:name -->\" and \"<!-- /sd-preserve:name -->\" keep\n" +
+			"any edits made on Github when the PR is amended.\n" +
+			"\n" +
+			"The stack section of pr-description.template, from stack.template, links\n" +
+			"the PRs below and above in the stack. It is between \"<!-- sd-stack -->\"\n" +
+			"and \"<!-- /sd-stack -->\", and is kept current in the PRs of the stack\n" +
+			"by \"sd new\", \"sd update\", and \"sd submit\".\n" +
 			"\n" +
 			"The possible values for the templates are:\n" +
 			"\n" +
+			"   ChangedFiles                 Files changed by the commit\n" +
+			"   CodeOwners                   Code owners of ChangedFiles, see \"sd code-owners\"\n" +
 			"   CommitBody                   Body of the commit message\n" +
 			"   CommitSummary                Summary line of the commit message\n" +
+			"   ChangeId                     Stacked-Diff-Id trailer of the commit, if any\n" +
 			"   CommitSummaryCleaned         Summary line of the commit message without\n" +
 			"                                spaces or special characters\n" +
 			"   CommitSummaryWithoutTicket   Summary line of the commit message without\n" +
 			"                                the prefix of the ticket number\n" +
+			"   DiffStat                     Changes of the commit as output by git show --stat\n" +
 			"   FeatureFlag                  Value passed to feature-flag flag\n" +
+			"   JiraUrl                      Value of the jira-url config, see \"sd config\"\n" +
+			"   NextPullRequest              PR above the commit in Stack, if any, see Stack\n" +
+			"   PreviousPullRequest          PR below the commit in Stack, if any, see Stack\n" +
+			"   Stack                        Commits on main with an open PR, and the commit\n" +
+			"                                itself, from bottom to top. Each has Commit,\n" +
+			"                                Subject, Branch, Number, Url, Current, and Link,\n" +
+			"                                such as [#101](url), or \"this PR\" if no Number\n" +
+			"   StackPosition                Position of the commit in the stack, 1 is bottom\n" +
+			"   StackSize                    Number of commits on main that are not merged\n" +
 			"   TicketNumber                 Jira ticket as parsed from the commit summary\n" +
 			"   Username                     Name as parsed from git config email.\n" +
 			"   UsernameCleaned              Username with dots (.) converted to dashes (-).\n",
+		Mutating: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() > 1 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
 			}
+			pick := checkPickReviewersFlags(asyncConfig.App, command, pickCount, pickStrategy)
 			selectCommitOptions := interactive.CommitSelectionOptions{
 				Prompt:      "What commit do you want to create a PR from?",
 				CommitType:  interactive.CommitTypeNoPr,
@@ -93,24 +138,31 @@ func createNewCommand() Command {
 			targetCommits := getTargetCommits(asyncConfig.App, command, flagSet.Args(), indicatorTypeString, selectCommitOptions)
 			// Note: set the default here rather than via flags to avoid GetMainBranchOrDie being called before OnSelected.
 			if *baseBranch == "" {
-				*baseBranch = util.GetMainBranchOrDie()
+				if *stack {
+					*baseBranch = getStackBaseBranch(targetCommits[0])
+				} else {
+					*baseBranch = util.GetMainBranchOrDie()
+				}
 			}
 			if *reviewers == "" && flagSet.NArg() == 0 {
-				*reviewers = interactive.UserSelection(asyncConfig)
+				*reviewers = interactive.UserSelection(asyncConfig, getSuggestedReviewers(targetCommits))
 				if *reviewers != "" {
 					slog.Info("Using reviewers " + *reviewers)
 				}
 			}
-			createNewPr(*draft, *featureFlag, *baseBranch, targetCommits[0])
+			createNewPr(asyncConfig.App, *draft, *featureFlag, *baseBranch, targetCommits[0])
 			if *reviewers != "" {
-				addReviewersToPr(asyncConfig, targetCommits, true, *silent, *minChecks, *reviewers, 30*time.Second)
+				addReviewersToPr(asyncConfig, targetCommits, true, *silent, *minChecks, util.GetConfigInt("check-retries"), *reviewers, pick, false, 30*time.Second)
 			}
 		}}
 }
 
 // Creates a new pull request via Github CLI.
-func createNewPr(draft bool, featureFlag string, baseBranch string, gitLog templates.GitLog) {
+func createNewPr(appConfig util.AppConfig, draft bool, featureFlag string, baseBranch string, gitLog templates.GitLog) {
 	util.RequireMainBranch()
+	if baseBranch != util.GetMainBranchOrDie() {
+		requireStackingSupported()
+	}
 	templates.RequireCommitOnMain(gitLog.Commit)
 	shouldPopStash := util.Stash("sd new " + gitLog.Commit + " " + gitLog.Subject)
 	rollbackManager := &util.GitRollbackManager{}
@@ -125,6 +177,11 @@ func createNewPr(draft bool, featureFlag string, baseBranch string, gitLog templ
 			panic(r)
 		}
 	}()
+	if util.GetConfigBool("change-id") && gitLog.ChangeId == "" {
+		gitLog = addChangeIds(appConfig, []templates.GitLog{gitLog})[0]
+	}
+	// Fetch the stack while still on main, as the PR is rendered from its branch.
+	stack := templates.GetStack()
 	var commitToBranchFrom string
 	if baseBranch == util.GetMainBranchOrDie() {
 		commitToBranchFrom = util.FirstOriginMainCommit(util.GetMainBranchOrDie())
@@ -135,19 +192,24 @@ func createNewPr(draft bool, featureFlag string, baseBranch string, gitLog templ
 	}
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch", "--no-track", gitLog.Branch, commitToBranchFrom)
 	rollbackManager.CreatedBranch(gitLog.Branch)
+	if baseBranch != util.GetMainBranchOrDie() {
+		util.SetStackedBase(gitLog.Branch, baseBranch, util.GetBranchLatestCommit(baseBranch))
+	}
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", gitLog.Branch)
 	slog.Info(fmt.Sprint("Cherry picking ", gitLog.Commit))
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "cherry-pick", gitLog.Commit)
 	slog.Info("Pushing to remote")
 	// -u is required because in newer versions of Github CLI the upstream must be set.
-	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "-c", "push.default=current", "push", "-f", "-u")
-	prText := templates.GetPullRequestText(gitLog.Commit, featureFlag)
-	slog.Info("Creating PR via gh")
-	createPrOutput := createPr(prText, baseBranch, draft)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "-f", "-u", util.GetPushRemote(), gitLog.Branch)
+	prText := templates.GetPullRequestText(gitLog.Commit, featureFlag, stack)
+	slog.Info("Creating PR")
+	createPrOutput := createPr(prText, baseBranch, gitLog.Branch, draft)
 	slog.Info(fmt.Sprint("Created PR ", createPrOutput))
 	rollbackManager.Clear()
+	util.RecordStackEntry(gitLog.Commit, gitLog.Branch, getPullRequestNumber(createPrOutput))
+	addCreatedPullRequest(&stack, gitLog.Branch, createPrOutput, prText)
 
-	util.ExecuteOrDie(util.ExecuteOptions{}, "gh", "pr", "view", "--web")
+	util.GetForge().OpenPullRequest(gitLog.Branch)
 	slog.Info(fmt.Sprint("Switching back to " + util.GetMainBranchOrDie()))
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
 
@@ -157,23 +219,69 @@ func createNewPr(draft bool, featureFlag string, baseBranch string, gitLog templ
 	   > hint: Disable this message with "git config advice.skippedCherryPicks false",
 	*/
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "config", "advice.skippedCherryPicks", "false")
+	updateStackSections(stack)
 }
 
-func createPr(prText templates.PullRequestText, baseBranch string, draft bool) string {
-	createPrArgsNoDraft := []string{"pr", "create", "--title", prText.Title, "--body", prText.Description, "--fill", "--base", baseBranch}
-	createPrArgs := createPrArgsNoDraft
-	if draft {
-		createPrArgs = append(createPrArgs, "--draft")
+// Returns the branch of the nearest commit below gitLog that has a PR, or main if there is none.
+func getStackBaseBranch(gitLog templates.GitLog) string {
+	newCommits := templates.GetNewCommits("HEAD")
+	commitIndex := slices.IndexFunc(newCommits, func(next templates.GitLog) bool {
+		return next.Commit == gitLog.Commit
+	})
+	if commitIndex == -1 {
+		panic("Commit " + gitLog.Commit + " does not exist on " + util.GetMainBranchOrDie() + ". Check `sd log` for available commits.")
 	}
-	createPrOutput, createPrErr := util.Execute(util.ExecuteOptions{}, "gh", createPrArgs...)
-	if createPrErr != nil {
-		if draft && strings.Contains(createPrOutput, "Draft pull requests are not supported") {
-			slog.Warn("Draft PRs not supported, trying again without draft.\nUse \"--draft=false\" to avoid this warning.")
-			return util.ExecuteOrDie(util.ExecuteOptions{}, "gh", createPrArgsNoDraft...)
-		} else {
-			panic("Could not create PR: " + createPrOutput + ", " + createPrErr.Error())
+	// New commits are ordered from newest to oldest, so the commits below are the ones after it.
+	for _, belowCommit := range newCommits[commitIndex+1:] {
+		if util.GetLocalHasBranchOrDie(belowCommit.Branch) {
+			slog.Info(fmt.Sprint("Stacking on top of ", belowCommit.Commit, " ", belowCommit.Subject, ", branch ", belowCommit.Branch))
+			return belowCommit.Branch
 		}
-	} else {
-		return createPrOutput
 	}
+	slog.Info("No commits below " + gitLog.Commit + " have a PR, using " + util.GetMainBranchOrDie() + " as the base branch")
+	return util.GetMainBranchOrDie()
+}
+
+// Returns the PR number from the URL output by "gh pr create", or 0 if it cannot be parsed.
+func getPullRequestNumber(createPrOutput string) int {
+	url := strings.TrimSpace(createPrOutput)
+	number, err := strconv.Atoi(url[strings.LastIndex(url, "/")+1:])
+	if err != nil {
+		return 0
+	}
+	return number
+}
+
+// Adds the PR that was created for branch to stack, so that the stack sections of the other PRs
+// link to it without fetching the stack again.
+func addCreatedPullRequest(stack *templates.Stack, branch string, createPrOutput string, prText templates.PullRequestText) {
+	number := getPullRequestNumber(createPrOutput)
+	if number == 0 {
+		return
+	}
+	stack.AddPullRequest(util.PullRequest{
+		Number:     number,
+		Url:        strings.TrimSpace(createPrOutput),
+		HeadBranch: branch,
+		Body:       prText.Description,
+		State:      util.PullRequestStateOpen,
+	})
+}
+
+// Panics if PRs cannot be stacked because branches are pushed to a fork, as the base branch of a
+// PR must be on the base remote.
+func requireStackingSupported() {
+	if util.IsForkWorkflow() {
+		panic("Cannot stack PRs when pushing to a fork, as the base branch of a PR must be on " + util.GetBaseRemote())
+	}
+}
+
+func createPr(prText templates.PullRequestText, baseBranch string, headBranch string, draft bool) string {
+	return util.GetForge().CreatePullRequest(util.CreatePullRequestOptions{
+		Title:      prText.Title,
+		Body:       prText.Description,
+		BaseBranch: baseBranch,
+		HeadBranch: util.GetPullRequestHead(headBranch),
+		Draft:      draft,
+	})
 }
@@ -3,6 +3,7 @@ package commands
 import (
 	"log/slog"
 	"slices"
+	"strings"
 
 	"testing"
 
@@ -76,18 +77,11 @@ func TestSdNew_WithReviewers_AddReviewers(t *testing.T) {
 
 	testutil.AddCommit("first", "")
 
-	testExecutor.SetResponse(
-		// There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n",
-		nil, "gh", "pr", "view", util.MatchAnyRemainingArgs)
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
 
 	testParseArguments("new", "--min-checks", "4", "--reviewers=mybestie", "1")
 
-	allCommits := templates.GetAllCommits()
-
 	contains := slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
 		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie"}
 		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
@@ -334,3 +328,192 @@ func TestSdNew_WhenDestinationCommitNotSpecifiedAndManyCommitsAndExistingPr_Pads
 	testParseArgumentsWithOut(out, "--log-level=error", "new")
 	assert.Fail("did not panic on cancel")
 }
+
+func TestSdNew_WithStack_BasesPrOnBranchOfCommitBelow(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("new", "2")
+	testParseArguments("new", "--stack", "1")
+
+	contains := slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" &&
+			slices.Equal(next.Args[0:2], []string{"pr", "create"}) &&
+			slices.Contains(next.Args, allCommits[1].Branch)
+	})
+	assert.True(contains, util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh"
+	}))
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[0].Branch)
+	commitsOnNewBranch := templates.GetNewCommits("HEAD")
+	assert.Equal(2, len(commitsOnNewBranch))
+	assert.Equal(allCommits[0].Subject, commitsOnNewBranch[0].Subject)
+	assert.Equal(allCommits[1].Subject, commitsOnNewBranch[1].Subject)
+}
+
+func TestSdNew_WhenCommitReworded_KeepsBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	originalCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("new", "2")
+	testParseArguments("new", "1")
+
+	// Reword the bottom commit, which also rewrites the commit above it.
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", "--detach", "HEAD~1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "--amend", "-m", "reworded")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "cherry-pick", util.GetMainBranchOrDie())
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch", "-f", util.GetMainBranchOrDie(), "HEAD")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+	// Rewrites are followed by the next command.
+	testParseArguments("log")
+
+	rewordedCommits := templates.GetNewCommits("HEAD")
+
+	assert.Equal("reworded", rewordedCommits[1].Subject)
+	assert.Equal(originalCommits[1].Branch, rewordedCommits[1].Branch)
+	assert.Equal(originalCommits[0].Branch, rewordedCommits[0].Branch)
+}
+
+func TestSdNew_WithPrIndicator_UsesCommitFromStack(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testExecutor.SetResponse("https://github.com/owner/repo/pull/42", nil, "gh", "pr", "create", util.MatchAnyRemainingArgs)
+	testParseArguments("new", "1")
+
+	branchInfo := templates.GetBranchInfo("42", templates.IndicatorTypePr)
+
+	assert.Equal(allCommits[0], branchInfo)
+}
+
+func TestSdNew_WithPushRemote_PushesToForkAndCreatesPrWithOwner(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.AddForkRemote(t, "fork-owner")
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("new", "1")
+
+	assert.NotEqual("", util.GetBranchLatestCommit("fork/"+allCommits[0].Branch))
+	assert.Equal("", util.GetBranchLatestCommit("origin/"+allCommits[0].Branch))
+	contains := slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		headIndex := slices.Index(next.Args, "--head")
+		return next.ProgramName == "gh" &&
+			slices.Equal(next.Args[0:2], []string{"pr", "create"}) &&
+			headIndex != -1 && next.Args[headIndex+1] == "fork-owner:"+allCommits[0].Branch
+	})
+	assert.True(contains, util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh"
+	}))
+}
+
+func TestSdNew_WithPushRemoteAndStack_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	testutil.AddForkRemote(t, "fork-owner")
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+
+	testParseArguments("new", "2")
+
+	assert.Panics(func() {
+		testParseArguments("new", "--stack", "1")
+	})
+	assert.False(util.RemoteHasBranch(templates.GetNewCommits("HEAD")[0].Branch))
+}
+
+func TestSdNew_WithChangeId_AddsTrailerAndNamesBranchAfterIt(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	t.Setenv("SD_CHANGE_ID", "true")
+
+	testutil.AddCommit("first", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+
+	testParseArguments("new", "2")
+
+	allCommits := templates.GetNewCommits("HEAD")
+	assert.Equal("third", allCommits[0].Subject)
+	assert.Equal("", allCommits[0].ChangeId)
+	assert.Equal("second", allCommits[1].Subject)
+	assert.NotEqual("", allCommits[1].ChangeId)
+	assert.True(strings.HasSuffix(allCommits[1].Branch, "/"+allCommits[1].ChangeId))
+	assert.True(util.GetLocalHasBranchOrDie(allCommits[1].Branch))
+}
+
+func TestSdNew_WithChangeId_KeepsBranchWhenCommitIsReworded(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	t.Setenv("SD_CHANGE_ID", "true")
+
+	testutil.AddCommit("first", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	testutil.AddCommit("second", "")
+
+	testParseArguments("new", "1")
+
+	before := templates.GetNewCommits("HEAD")[0]
+	testutil.AddCommit("second-changes", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--soft", "HEAD~2")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "-m", "second reworded",
+		"--trailer", templates.ChangeIdTrailer+": "+before.ChangeId)
+
+	after := templates.GetNewCommits("HEAD")[0]
+	assert.Equal("second reworded", after.Subject)
+	assert.Equal(before.ChangeId, after.ChangeId)
+	assert.Equal(before.Branch, after.Branch)
+}
+
+func TestSdNew_WhenStacked_UpdatesStackSectionOfPrBelow(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	// The fake forge only knows of PRs that are set, so set the one that "new" creates as well.
+	setOpenPullRequests(templates.GetNewCommits("HEAD"), 101)
+
+	testParseArguments("new", "1")
+
+	edits := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:3], []string{"pr", "edit", "101"})
+	})
+	if assert.NotEmpty(edits) {
+		args := edits[len(edits)-1].Args
+		assert.Equal([]string{"pr", "edit", "101", "--body"}, args[0:4])
+		assert.Contains(args[4], "#### Stack: **[#101](https://github.com/pull/101)** → [#102](https://github.com/pull/102)\n<!-- /sd-stack -->")
+	}
+}
+
+func TestSdNew_WhenBranchOfOtherPrDeleted_RemovesItsStackEntry(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testParseArguments("new", "2")
+	firstBranch := templates.GetNewCommits("HEAD")[1].Branch
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch", "-D", firstBranch)
+
+	testParseArguments("new", "1")
+
+	_, ok := util.GetStackEntryForBranch(firstBranch)
+	assert.False(ok)
+	_, ok = util.GetStackEntryForBranch(templates.GetNewCommits("HEAD")[0].Branch)
+	assert.True(ok)
+}
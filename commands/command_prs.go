@@ -2,7 +2,9 @@ package commands
 
 import (
 	"flag"
+	"fmt"
 	"log/slog"
+	"text/tabwriter"
 
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
 )
@@ -15,14 +17,48 @@ func createPrsCommand() Command {
 		Summary: "Lists all Pull Requests you have open.",
 		Description: "Lists all Pull Requests you have open.\n" +
 			"\n" +
-			"You must be logged-in, via \"gh auth login\"",
-		Usage:           "sd " + flagSet.Name(),
-		DefaultLogLevel: slog.LevelError,
+			"You must be logged-in, via \"gh auth login\" for Github, or\n" +
+			"\"glab auth login\" for GitLab.",
+		Usage:            "sd " + flagSet.Name(),
+		DefaultLogLevel:  slog.LevelError,
+		StructuredOutput: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() != 0 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
 			}
-			util.ExecuteOrDie(util.ExecuteOptions{Io: asyncConfig.App.Io},
-				"gh", "pr", "list", "--author", "@me")
+			if isStructuredOutput(asyncConfig.App) {
+				printStructuredOutput(asyncConfig.App, getPrsOutput(), prOutputHeaders, prOutput.tsvRow)
+				return
+			}
+			printPrs(asyncConfig.App.Io, getPrsOutput())
 		}}
 }
+
+// Returns the open PRs of the logged in user.
+func getPrsOutput() []prOutput {
+	return util.MapSlice(util.GetForge().GetOpenPullRequests(), func(pullRequest util.PullRequest) prOutput {
+		return prOutput{
+			Number:  pullRequest.Number,
+			Title:   pullRequest.Title,
+			Branch:  pullRequest.HeadBranch,
+			Url:     pullRequest.Url,
+			State:   pullRequest.State.String(),
+			IsDraft: pullRequest.IsDraft,
+		}
+	})
+}
+
+func printPrs(stdIo util.StdIo, prs []prOutput) {
+	writer := tabwriter.NewWriter(stdIo.Out, 0, 0, 3, ' ', 0)
+	util.Fprintln(writer, "PR\tTitle\tBranch\tState")
+	for _, pr := range prs {
+		state := pr.State
+		if pr.IsDraft {
+			state = "draft"
+		}
+		util.Fprintln(writer, fmt.Sprint("#", pr.Number, "\t", pr.Title, "\t", pr.Branch, "\t", state))
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
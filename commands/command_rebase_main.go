@@ -14,7 +14,7 @@ import (
 
 func createRebaseMainCommand() Command {
 	flagSet := flag.NewFlagSet("rebase-main", flag.ContinueOnError)
-
+	autoResolve := flagSet.Bool("auto-resolve", false, "Try to resolve merge conflicts automatically, see description")
 	return Command{
 		FlagSet: flagSet,
 		Summary: "Bring your main branch up to date with remote",
@@ -24,27 +24,51 @@ func createRebaseMainCommand() Command {
 			"This avoids having to manually call \"git reset --hard head\" whenever\n" +
 			"you have merge conflicts with a commit that has already been merged\n" +
 			"but has slight variation with local main because, for example, a\n" +
-			"change was made with the Github Web UI.",
-		Usage: "sd " + flagSet.Name(),
+			"change was made with the Github Web UI.\n" +
+			"\n" +
+			"Any stacked branches, (see \"sd new --stack\"), are restacked first.\n" +
+			"\n" +
+			"With \"--auto-resolve\", each commit that has merge conflicts is resolved\n" +
+			"with the first of these strategies that works:\n" +
+			"\n" +
+			"  branch: replace the changes with those of its associated branch, the\n" +
+			"          same as \"sd replace-conflicts\". Only used if the branch\n" +
+			"          already has the latest changes from origin/" + util.GetMainBranchForHelp() + ".\n" +
+			"  rerere: reuse a resolution recorded by \"git rerere\".\n" +
+			"\n" +
+			"The rebase only stops on a commit that no strategy resolved. A report of\n" +
+			"how each commit was resolved is displayed afterwards.",
+		Usage:    "sd " + flagSet.Name() + " [flags]",
+		Mutating: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() != 0 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
 			}
-			rebaseMain(asyncConfig.App)
+			rebaseMain(asyncConfig.App, *autoResolve)
 		}}
 }
 
-// Bring local main branch up to date with remote
-func rebaseMain(appConfig util.AppConfig) {
+// Bring local main branch up to date with remote. If autoResolve is set then merge conflicts are
+// resolved where possible, see [autoResolveRebase]. Returns false if the rebase failed and has to be
+// continued manually.
+func rebaseMain(appConfig util.AppConfig, autoResolve bool) bool {
 	util.RequireMainBranch()
 	shouldPopStash := util.Stash("rebase-main")
 
 	slog.Info("Fetching...")
-	util.ExecuteOrDie(util.ExecuteOptions{Io: appConfig.Io}, "git", "fetch")
+	fetchArgs := []string{"fetch", "--multiple", util.GetBaseRemote()}
+	if util.IsForkWorkflow() {
+		fetchArgs = append(fetchArgs, util.GetPushRemote())
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{Io: appConfig.Io}, "git", fetchArgs...)
 	slog.Info("Getting merged branches from Github...")
 	mergedBranches := getMergedBranches()
 	slog.Debug(fmt.Sprint("mergedBranches ", mergedBranches))
 	localLogs := templates.GetNewCommits("HEAD")
+	if len(getStackedCommits(localLogs)) > 0 {
+		slog.Info("Restacking stacked branches...")
+		restack(appConfig, mergedBranches)
+	}
 	dropCommits := getDropCommits(localLogs, mergedBranches)
 	slog.Info("Rebasing...")
 	var rebaseError error
@@ -58,36 +82,41 @@ func rebaseMain(appConfig util.AppConfig) {
 			EnvironmentVariables: environmentVariables,
 			Io:                   appConfig.Io,
 		}
-		_, rebaseError = util.Execute(options, "git", "rebase", "-i", "origin/"+util.GetMainBranchOrDie())
+		_, rebaseError = util.Execute(options, "git", append(rerereArgs(autoResolve), "rebase", "-i", util.GetRemoteMainBranchOrDie())...)
 		slog.Info("Deleting merged branches...")
 		deleteBranches(appConfig.Io, dropCommits)
+		util.RemoveStackEntries(util.MapSlice(dropCommits, func(gitLog templates.GitLog) string {
+			return gitLog.Branch
+		}))
 	} else {
 		options := util.ExecuteOptions{Io: appConfig.Io}
-		_, rebaseError = util.Execute(options, "git", "rebase", "origin/"+util.GetMainBranchOrDie())
+		_, rebaseError = util.Execute(options, "git", append(rerereArgs(autoResolve), "rebase", util.GetRemoteMainBranchOrDie())...)
+	}
+	if rebaseError != nil && autoResolve {
+		if autoResolveRebase(appConfig) {
+			rebaseError = nil
+		}
 	}
 	if rebaseError != nil {
 		slog.Warn("Rebase failed, check output ^^ for details. Continue rebase manually.")
-	} else {
-		util.PopStash(shouldPopStash)
+		return false
 	}
+	templates.FollowRewrittenCommits()
+	util.PopStash(shouldPopStash)
+	return true
 }
 
+// Returns the branches of the user's PRs that were merged after the last time local main was
+// rebased.
 func getMergedBranches() []string {
-	mergedBranchesRaw := util.ExecuteOrDie(util.ExecuteOptions{},
-		"gh", "pr", "list", "--author", "@me", "--state", "merged", "--base", util.GetMainBranchOrDie(),
-		"--json", "headRefName,mergeCommit", "--jq", ".[ ] | .headRefName + \" \" +  .mergeCommit.oid")
-	mergedBranchesRawLines := strings.Split(strings.TrimSpace(mergedBranchesRaw), "\n")
-	mergedBranches := make([]string, 0, len(mergedBranchesRawLines))
-	for _, mergedBranchRawLine := range mergedBranchesRawLines {
-		fields := strings.Fields(mergedBranchRawLine)
-		if len(fields) != 2 {
-			break
-		}
-		// Checking for ancestor is more reliable than filtering on merge date via "gh pr list --search".
-		_, mergeBaseErr := util.Execute(util.ExecuteOptions{}, "git", "merge-base", "--is-ancestor", fields[1], "HEAD")
+	mergedPullRequests := util.GetForge().GetMergedPullRequests(util.GetMainBranchOrDie(), "@me")
+	mergedBranches := make([]string, 0, len(mergedPullRequests))
+	for _, mergedPullRequest := range mergedPullRequests {
+		// Checking for ancestor is more reliable than filtering on merge date via a search query.
+		_, mergeBaseErr := util.Execute(util.ExecuteOptions{}, "git", "merge-base", "--is-ancestor", mergedPullRequest.MergeCommit, "HEAD")
 		if mergeBaseErr != nil {
 			// Not an ancestor, so it was merged after the first origin commit.
-			mergedBranches = append(mergedBranches, fields[0])
+			mergedBranches = append(mergedBranches, mergedPullRequest.HeadBranch)
 		}
 	}
 	return mergedBranches
@@ -128,9 +157,9 @@ func deleteBranches(stdIo util.StdIo, dropCommits []templates.GitLog) {
 			util.Execute(util.ExecuteOptions{Io: stdIo}, "git", "branch", "-D", dropCommit.Branch)
 			// Only delete remote branch if it is on the same commit to avoid accidentally deleting
 			// a branch that is not merged.
-			if localHash == util.GetBranchLatestCommit("origin/"+dropCommit.Branch) {
+			if localHash == util.GetBranchLatestCommit(util.GetPushRemote()+"/"+dropCommit.Branch) {
 				// nolint:errcheck
-				util.Execute(util.ExecuteOptions{Io: stdIo}, "git", "push", "--delete", "origin", dropCommit.Branch)
+				util.Execute(util.ExecuteOptions{Io: stdIo}, "git", "push", "--delete", util.GetPushRemote(), dropCommit.Branch)
 			}
 		}
 	}
@@ -14,7 +14,7 @@ import (
 
 func TestSdRebaseMain_WithDifferentCommits_DropsCommits(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "")
 	testutil.AddCommit("second", "rebase-will-keep-this-file")
@@ -27,8 +27,7 @@ func TestSdRebaseMain_WithDifferentCommits_DropsCommits(t *testing.T) {
 
 	testutil.AddCommit("second", "rebase-will-drop-this-file")
 
-	testExecutor.SetResponse(allOriginalCommits[0].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allOriginalCommits[0].Branch, "fakeMergeCommit")
 
 	testParseArguments("rebase-main")
 
@@ -44,7 +43,7 @@ func TestSdRebaseMain_WithDifferentCommits_DropsCommits(t *testing.T) {
 
 func TestSdRebaseMain_WithMulitpleMergedBranches_DropsCommits(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "1")
 	testutil.AddCommit("second", "2")
@@ -60,10 +59,8 @@ func TestSdRebaseMain_WithMulitpleMergedBranches_DropsCommits(t *testing.T) {
 	testutil.AddCommit("third", "3-rebase-will-drop-this-file")
 	testutil.AddCommit("fourth", "4")
 
-	testExecutor.SetResponse(
-		allOriginalCommits[0].Branch+" fakeMergeCommit\n"+
-			allOriginalCommits[1].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allOriginalCommits[0].Branch, "fakeMergeCommit")
+	testutil.SetMergedPullRequest(allOriginalCommits[1].Branch, "fakeMergeCommit")
 
 	testParseArguments("rebase-main")
 
@@ -81,7 +78,7 @@ func TestSdRebaseMain_WithMulitpleMergedBranches_DropsCommits(t *testing.T) {
 
 func TestSdRebaseMain_WithDuplicateBranches_Panics(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "1")
 	testutil.AddCommit("second", "2.1")
@@ -89,8 +86,7 @@ func TestSdRebaseMain_WithDuplicateBranches_Panics(t *testing.T) {
 
 	allOriginalCommits := templates.GetAllCommits()
 
-	testExecutor.SetResponse(allOriginalCommits[0].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allOriginalCommits[0].Branch, "fakeMergeCommit")
 
 	// Return on panic
 	defer func() { _ = recover() }()
@@ -102,7 +98,7 @@ func TestSdRebaseMain_WithDuplicateBranches_Panics(t *testing.T) {
 
 func TestSdRebaseMain_WhenRebaseFails_DropsBranches(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "file-with-conflicts")
 	testutil.CommitFileChange("second", "change-value-to-avoid-same-hash", "1")
@@ -116,8 +112,7 @@ func TestSdRebaseMain_WhenRebaseFails_DropsBranches(t *testing.T) {
 	testutil.CommitFileChange("second", "change-value-to-avoid-same-hash", "2")
 	testutil.CommitFileChange("fourth", "file-with-conflicts", "2")
 
-	testExecutor.SetResponse(allCommits[1].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allCommits[1].Branch, "fakeMergeCommit")
 
 	branches := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch")
 	assert.Contains(branches, "second")
@@ -131,7 +126,7 @@ func TestSdRebaseMain_WhenRebaseFails_DropsBranches(t *testing.T) {
 
 func TestSdRebaseMain_WithMergedPrAlreadyRebased_KeepsCommits(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "")
 	testutil.AddCommit("second", "second-1")
@@ -146,8 +141,7 @@ func TestSdRebaseMain_WithMergedPrAlreadyRebased_KeepsCommits(t *testing.T) {
 
 	// Use the commit of the first "second" commit as the branch
 	// that was merged so that the second "second" commit is not dropped.
-	testExecutor.SetResponse(allCommits[1].Branch+" "+allCommits[1].Commit,
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allCommits[1].Branch, allCommits[1].Commit)
 
 	testParseArguments("rebase-main")
 
@@ -157,7 +151,7 @@ func TestSdRebaseMain_WithMergedPrAlreadyRebased_KeepsCommits(t *testing.T) {
 
 func TestSdRebaseMain_WithDroppedCommits_DropsBranches(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "")
 	testutil.AddCommit("second", "rebase-will-keep-this-file")
@@ -172,11 +166,116 @@ func TestSdRebaseMain_WithDroppedCommits_DropsBranches(t *testing.T) {
 
 	testutil.AddCommit("second", "rebase-will-drop-this-file")
 
-	testExecutor.SetResponse(allOriginalCommits[0].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allOriginalCommits[0].Branch, "fakeMergeCommit")
 
 	testParseArguments("rebase-main")
 
 	assert.False(util.RemoteHasBranch(allOriginalCommits[0].Branch))
 	assert.False(util.GetLocalHasBranchOrDie(allOriginalCommits[0].Branch))
 }
+
+func TestSdRebaseMain_WithAutoResolveAndUpToDateBranch_ResolvesWithBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "file-with-conflicts")
+	testutil.CommitFileChange("second", "file-with-conflicts", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	allCommits := templates.GetAllCommits()
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", allCommits[1].Commit)
+	testutil.CommitFileChange("third", "file-with-conflicts", "2")
+
+	testParseArguments("new", "1")
+	testParseArguments("checkout", "1")
+	_, mergeErr := util.Execute(util.ExecuteOptions{}, "git", "merge", "origin/"+util.GetMainBranchOrDie())
+	assert.NotNil(mergeErr)
+	if writeErr := os.WriteFile("file-with-conflicts", []byte("1\n2"), 0); writeErr != nil {
+		panic(writeErr)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "add", ".")
+	continueOptions := util.ExecuteOptions{EnvironmentVariables: []string{"GIT_EDITOR=true"}}
+	util.ExecuteOrDie(continueOptions, "git", "merge", "--continue")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+
+	out := testParseArguments("rebase-main", "--auto-resolve")
+
+	assert.Regexp("third +branch ", out)
+	allCommits = templates.GetAllCommits()
+	assert.Equal(4, len(allCommits))
+	assert.Equal("third", allCommits[0].Subject)
+	assert.Equal("second", allCommits[1].Subject)
+	contents, readErr := os.ReadFile("file-with-conflicts")
+	assert.Nil(readErr)
+	assert.Regexp("1.?\n2", string(contents))
+}
+
+func TestSdRebaseMain_WithAutoResolveAndRecordedResolution_ResolvesWithRerere(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "file-with-conflicts")
+	testutil.CommitFileChange("second", "file-with-conflicts", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	allCommits := templates.GetAllCommits()
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", allCommits[1].Commit)
+	testutil.CommitFileChange("third", "file-with-conflicts", "2")
+
+	// Record a resolution of the conflict and then abort.
+	_, rebaseErr := util.Execute(util.ExecuteOptions{}, "git", "-c", "rerere.enabled=true", "rebase", "origin/"+util.GetMainBranchOrDie())
+	assert.NotNil(rebaseErr)
+	if writeErr := os.WriteFile("file-with-conflicts", []byte("1\n2\n"), 0); writeErr != nil {
+		panic(writeErr)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "-c", "rerere.enabled=true", "rerere")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rebase", "--abort")
+
+	out := testParseArguments("rebase-main", "--auto-resolve")
+
+	assert.Regexp("third +rerere", out)
+	allCommits = templates.GetAllCommits()
+	assert.Equal(4, len(allCommits))
+	assert.Equal("third", allCommits[0].Subject)
+	contents, readErr := os.ReadFile("file-with-conflicts")
+	assert.Nil(readErr)
+	assert.Regexp("1.?\n2", string(contents))
+}
+
+func TestSdRebaseMain_WithAutoResolveAndNoStrategy_StopsOnCommit(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "file-with-conflicts")
+	testutil.CommitFileChange("second", "file-with-conflicts", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	allCommits := templates.GetAllCommits()
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", allCommits[1].Commit)
+	testutil.CommitFileChange("third", "file-with-conflicts", "2")
+
+	out := testParseArguments("--log-level=info", "rebase-main", "--auto-resolve")
+
+	assert.Regexp("third +\\(not resolved, resolve manually\\)", out)
+	assert.Contains(out, "Rebase failed")
+	assert.True(util.IsRebaseInProgress())
+}
+
+func TestSdRebaseMain_WithPushRemote_RebasesOnBaseRemoteAndDeletesForkBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+	testutil.AddForkRemote(t, "fork-owner")
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "rebase-will-keep-this-file")
+	testParseArguments("new", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	allOriginalCommits := templates.GetAllCommits()
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", allOriginalCommits[1].Commit)
+	testutil.AddCommit("second", "rebase-will-drop-this-file")
+	testutil.SetMergedPullRequest(allOriginalCommits[0].Branch, "fakeMergeCommit")
+
+	testParseArguments("rebase-main")
+
+	allCommits := templates.GetAllCommits()
+	assert.Equal(allOriginalCommits[0].Commit, allCommits[0].Commit)
+	assert.False(util.RemoteHasBranch(allOriginalCommits[0].Branch))
+	assert.False(util.GetLocalHasBranchOrDie(allOriginalCommits[0].Branch))
+}
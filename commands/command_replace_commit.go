@@ -24,7 +24,8 @@ func createReplaceCommitCommand() Command {
 			"This is useful when you make changes within a branch, for example to\n" +
 			"fix a problem found on CI, and want to bring the changes over to your\n" +
 			"local " + util.GetMainBranchForHelp() + " branch.",
-		Usage: "sd " + flagSet.Name() + " [flags] <commitIndicator>",
+		Usage:    "sd " + flagSet.Name() + " [flags] <commitIndicator>",
+		Mutating: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() > 1 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
@@ -64,10 +65,12 @@ func replaceCommitOfBranchInfo(gitLog templates.GitLog) {
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "add", ".")
 	commitSummary := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--no-patch", "--format=%s", gitLog.Commit)
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "-m", strings.TrimSpace(commitSummary))
+	util.RecordStackEntry("HEAD", gitLog.Branch, 0)
 	if len(commitsAfter) != 0 {
 		slog.Info(fmt.Sprint("Cherry picking commits back on top ", commitsAfter))
 		cherryPickAndSkipAllEmpty(commitsAfter)
 	}
+	templates.FollowRewrittenCommits()
 }
 
 func reverseArrayInPlace(array []string) {
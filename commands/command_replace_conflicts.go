@@ -21,7 +21,8 @@ func createReplaceConflictsCommand() Command {
 		Description: "During a rebase that failed because of merge conflicts, replace the\n" +
 			"current uncommitted changes (merge conflicts), with the contents\n" +
 			"(diff between origin/" + util.GetMainBranchForHelp() + " and HEAD) of its associated branch.",
-		Usage: "sd " + flagSet.Name(),
+		Usage:    "sd " + flagSet.Name(),
+		Mutating: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() > 0 {
 				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
@@ -37,8 +38,7 @@ func replaceConflicts(appConfig util.AppConfig, confirmed bool) {
 	checkConfirmed(appConfig, confirmed)
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", "HEAD")
 	slog.Info(fmt.Sprint("Replacing changes (merge conflicts) for failed rebase of commit ", commitWithConflicts, ", with changes from associated branch, ", gitLog.Branch))
-	diff := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "diff", "--binary", "origin/"+util.GetMainBranchOrDie(), gitLog.Branch)
-	util.ExecuteOrDie(util.ExecuteOptions{Io: util.StdIo{In: strings.NewReader(diff), Out: nil, Err: nil}},
+	util.ExecuteOrDie(util.ExecuteOptions{Io: util.StdIo{In: strings.NewReader(getBranchDiff(gitLog.Branch)), Out: nil, Err: nil}},
 		"git", "apply",
 	)
 	slog.Info("Adding changes and continuing rebase")
@@ -47,6 +47,11 @@ func replaceConflicts(appConfig util.AppConfig, confirmed bool) {
 	util.ExecuteOrDie(continueOptions, "git", "rebase", "--continue")
 }
 
+// Returns the changes of branchName, which is the diff between the remote main branch and branchName.
+func getBranchDiff(branchName string) string {
+	return util.ExecuteOrDie(util.ExecuteOptions{}, "git", "diff", "--binary", util.GetRemoteMainBranchOrDie(), branchName)
+}
+
 func getCommitWithConflicts() string {
 	statusLines := strings.Split(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "status"), "\n")
 	lastCommandDoneLine := -1
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createRestackCommand() Command {
+	flagSet := flag.NewFlagSet("restack", flag.ContinueOnError)
+
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Rebase stacked PR branches onto their updated base branches",
+		Description: "Rebases each branch that was created with \"sd new --stack\" onto the\n" +
+			"latest version of its base branch, starting from the bottom of the\n" +
+			"stack, and force pushes any branches that changed.\n" +
+			"\n" +
+			"If a base branch was merged then the branch is rebased onto\n" +
+			"origin/" + util.GetMainBranchForHelp() + " instead, and its PR is retargeted to " + util.GetMainBranchForHelp() + ".\n" +
+			"\n" +
+			"This is done automatically by \"sd update\" and \"sd rebase-main\".",
+		Usage:    "sd " + flagSet.Name(),
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			util.RequireMainBranch()
+			slog.Info("Getting merged branches from Github...")
+			restack(asyncConfig.App, getMergedBranches())
+		}}
+}
+
+// Returns the commits, ordered from the bottom of the stack to the top, whose branches are stacked
+// on another branch.
+func getStackedCommits(localLogs []templates.GitLog) []templates.GitLog {
+	stackedCommits := make([]templates.GitLog, 0)
+	for _, localLog := range slices.Backward(localLogs) {
+		if baseBranch, _ := util.GetStackedBase(localLog.Branch); baseBranch != "" {
+			stackedCommits = append(stackedCommits, localLog)
+		}
+	}
+	return stackedCommits
+}
+
+// Rebases stacked branches onto their base branches, or onto main if their base branch is in
+// mergedBranches.
+func restack(appConfig util.AppConfig, mergedBranches []string) {
+	stackedCommits := getStackedCommits(templates.GetNewCommits("HEAD"))
+	if len(stackedCommits) == 0 {
+		slog.Info("No stacked branches to restack")
+		return
+	}
+	shouldPopStash := util.Stash("restack")
+	originalBranch := util.GetCurrentBranchName()
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		util.PopStash(shouldPopStash)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	for _, stackedCommit := range stackedCommits {
+		restackBranch(appConfig, rollbackManager, stackedCommit.Branch, mergedBranches)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", originalBranch)
+	rollbackManager.Clear()
+}
+
+func restackBranch(appConfig util.AppConfig, rollbackManager *util.GitRollbackManager, branchName string, mergedBranches []string) {
+	baseBranch, baseCommit := util.GetStackedBase(branchName)
+	baseMerged := slices.Contains(mergedBranches, baseBranch) || !util.GetLocalHasBranchOrDie(baseBranch)
+	var newBase string
+	if baseMerged {
+		newBase = util.GetRemoteMainBranchOrDie()
+	} else {
+		newBase = baseBranch
+	}
+	if baseCommit == "" {
+		baseCommit = getForkPoint(newBase, branchName)
+	}
+	newBaseCommit := util.GetBranchLatestCommit(newBase)
+	if newBaseCommit == baseCommit {
+		slog.Info(fmt.Sprint("Branch ", branchName, " is already up to date with ", newBase))
+	} else {
+		slog.Info(fmt.Sprint("Rebasing ", branchName, " onto ", newBase))
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", branchName)
+		rollbackManager.SaveState()
+		util.ExecuteOrDie(util.ExecuteOptions{Io: appConfig.Io}, "git", "rebase", "--onto", newBase, baseCommit, branchName)
+		slog.Info("Force pushing " + branchName)
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "-f", util.GetPushRemote(), branchName)
+	}
+	if baseMerged {
+		slog.Info(fmt.Sprint("Base branch ", baseBranch, " was merged, changing base of PR to ", util.GetMainBranchOrDie()))
+		util.GetForge().EditPullRequest(branchName, util.EditPullRequestOptions{BaseBranch: util.GetMainBranchOrDie()})
+		util.ClearStackedBase(branchName)
+	} else {
+		util.SetStackedBase(branchName, baseBranch, newBaseCommit)
+	}
+}
+
+// Returns the commit that branchName forked from baseBranch, taking into account any rewrites to
+// baseBranch.
+func getForkPoint(baseBranch string, branchName string) string {
+	if forkPoint, err := util.Execute(util.ExecuteOptions{}, "git", "merge-base", "--fork-point", baseBranch, branchName); err == nil {
+		return strings.TrimSpace(forkPoint)
+	}
+	return strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "merge-base", baseBranch, branchName))
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdRestack_WhenBaseBranchChanges_RebasesStackedBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("new", "2")
+	testParseArguments("new", "--stack", "1")
+
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[1].Branch)
+	testutil.AddCommit("first-review-fix", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+
+	testParseArguments("restack")
+
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[0].Branch)
+	commitsOnStackedBranch := templates.GetNewCommits("HEAD")
+	assert.Equal(3, len(commitsOnStackedBranch))
+	assert.Equal(allCommits[0].Subject, commitsOnStackedBranch[0].Subject)
+	assert.Equal("first-review-fix", commitsOnStackedBranch[1].Subject)
+	assert.Equal(util.GetBranchLatestCommit(allCommits[0].Branch), util.GetBranchLatestCommit("origin/"+allCommits[0].Branch))
+}
+
+func TestSdRebaseMain_WhenBaseBranchMerged_RetargetsStackedPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("new", "2")
+	testParseArguments("new", "--stack", "1")
+
+	// Simulate the bottom PR being merged.
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", allCommits[1].Branch+":"+util.GetMainBranchOrDie())
+	testutil.SetMergedPullRequest(allCommits[1].Branch, "fakeMergeCommit")
+
+	testParseArguments("rebase-main")
+
+	contains := slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		ghExpectedArgs := []string{"pr", "edit", allCommits[0].Branch, "--base", util.GetMainBranchOrDie()}
+		return next.ProgramName == "gh" && slices.Equal(next.Args, ghExpectedArgs)
+	})
+	assert.True(contains, util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh"
+	}))
+	baseBranch, _ := util.GetStackedBase(allCommits[0].Branch)
+	assert.Equal("", baseBranch)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[0].Branch)
+	commitsOnStackedBranch := templates.GetNewCommits("HEAD")
+	assert.Equal(1, len(commitsOnStackedBranch))
+	assert.Equal(allCommits[0].Subject, commitsOnStackedBranch[0].Subject)
+}
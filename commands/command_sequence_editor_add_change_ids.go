@@ -0,0 +1,115 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createAddChangeIdsCommand() Command {
+	flagSet := flag.NewFlagSet("sequence-editor-add-change-ids", flag.ContinueOnError)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Sequence editor for git rebase used by new, submit, and migrate-change-ids",
+		Description: "For use as a sequence editor during an interactive git rebase. Amends each commit to\n" +
+			"add a " + templates.ChangeIdTrailer + " trailer with the given change id.",
+		Usage:  "sd " + flagSet.Name() + " commit1=changeId1 [commit2=changeId2...] rebaseFilename",
+		Hidden: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() < 2 {
+				commandError(asyncConfig.App, flagSet, "not enough arguments", command.Usage)
+			}
+			changeIds := make(map[string]string)
+			for _, arg := range flagSet.Args()[0 : len(flagSet.Args())-1] {
+				commit, changeId, ok := strings.Cut(arg, "=")
+				if !ok {
+					commandError(asyncConfig.App, flagSet, "invalid commit and change id: "+arg, command.Usage)
+				}
+				changeIds[commit] = changeId
+			}
+			rebaseFilename := flagSet.Arg(len(flagSet.Args()) - 1)
+
+			addChangeIdsToRebase(changeIds, rebaseFilename)
+		}}
+}
+
+func addChangeIdsToRebase(changeIds map[string]string, rebaseFilename string) {
+	data, err := os.ReadFile(rebaseFilename)
+	slog.Debug(fmt.Sprint("Got changeIds ", changeIds, " rebaseFilename ", rebaseFilename))
+	if err != nil {
+		panic(fmt.Sprint("Could not open ", rebaseFilename, err))
+	}
+	var newText strings.Builder
+	found := 0
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		newText.WriteString(line)
+		newText.WriteString("\n")
+		if !strings.HasPrefix(line, "pick ") {
+			continue
+		}
+		for commit, changeId := range changeIds {
+			if strings.Contains(line, commit) {
+				newText.WriteString("exec git commit --amend --no-edit --no-verify --trailer \"" +
+					templates.ChangeIdTrailer + ": " + changeId + "\"\n")
+				found++
+				break
+			}
+		}
+	}
+	if found != len(changeIds) {
+		panic(fmt.Sprint("Could only find ", found, " of ", len(changeIds), " commits ", changeIds, " in ", string(data)))
+	}
+	err = os.WriteFile(rebaseFilename, []byte(newText.String()), 0)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Adds a new change id trailer to each of gitLogs, which must be new commits on main, by
+// amending them during an interactive rebase that uses sequence-editor-add-change-ids. Returns
+// gitLogs as they are after the rebase, in the same order.
+func addChangeIds(appConfig util.AppConfig, gitLogs []templates.GitLog) []templates.GitLog {
+	if len(gitLogs) == 0 {
+		return gitLogs
+	}
+	newCommits := templates.GetNewCommits("HEAD")
+	oldest := -1
+	changeIds := make([]string, len(gitLogs))
+	editorArgs := make([]string, len(gitLogs))
+	for i, gitLog := range gitLogs {
+		index := slices.IndexFunc(newCommits, func(next templates.GitLog) bool {
+			return next.Commit == gitLog.Commit
+		})
+		if index == -1 {
+			panic("Commit " + gitLog.Commit + " does not exist on " + util.GetMainBranchOrDie() + ". Check `sd log` for available commits.")
+		}
+		// New commits are ordered from newest to oldest.
+		oldest = max(oldest, index)
+		changeIds[i] = templates.NewChangeId()
+		editorArgs[i] = gitLog.Commit + "=" + changeIds[i]
+		slog.Info(fmt.Sprint("Adding change id ", changeIds[i], " to ", gitLog.Commit, " ", gitLog.Subject))
+	}
+	environmentVariables := []string{
+		"GIT_SEQUENCE_EDITOR=" + appConfig.AppExecutable + " sequence-editor-add-change-ids " + strings.Join(editorArgs, " "),
+	}
+	slog.Debug(fmt.Sprint("Using sequence editor ", environmentVariables))
+	options := util.ExecuteOptions{EnvironmentVariables: environmentVariables, Io: appConfig.Io}
+	util.ExecuteOrDie(options, "git", "rebase", "-i", newCommits[oldest].Commit+"^")
+
+	rebasedCommits := templates.GetNewCommits("HEAD")
+	return util.MapSlice(changeIds, func(changeId string) templates.GitLog {
+		index := slices.IndexFunc(rebasedCommits, func(next templates.GitLog) bool {
+			return next.ChangeId == changeId
+		})
+		if index == -1 {
+			panic("Could not find commit with change id " + changeId + " after rebase")
+		}
+		return rebasedCommits[index]
+	})
+}
@@ -5,65 +5,106 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
 )
 
+// Separates the groups of the arguments of "sequence-editor-mark-as-fixup".
+const fixupGroupSeparator = ","
+
+// Commits that are squashed into targetCommit, see [markAsFixup].
+type fixupGroup struct {
+	targetCommit string
+	fixupCommits []string
+}
+
 func createMarkAsFixupCommand() Command {
 	flagSet := flag.NewFlagSet("sequence-editor-mark-as-fixup", flag.ContinueOnError)
 	return Command{
-		FlagSet:     flagSet,
-		Summary:     "Sequence editor for git rebase used by update",
-		Description: "For use as a sequence editor during an interactive git rebase. Marks commits as fixup commits.",
-		Usage:       "sd " + flagSet.Name() + " targetCommit fixupCommit1 [fixupCommit2...] rebaseFilename",
-		Hidden:      true,
+		FlagSet: flagSet,
+		Summary: "Sequence editor for git rebase that marks commits as fixups, used by update and absorb",
+		Description: "For use as a sequence editor during an interactive git rebase. Marks commits as fixup commits.\n" +
+			"\n" +
+			"Each group of a targetCommit and its fixup commits is separated by \"" + fixupGroupSeparator + "\".",
+		Usage:  "sd " + flagSet.Name() + " targetCommit fixupCommit1 [fixupCommit2...] [" + fixupGroupSeparator + " targetCommit fixupCommit1...] rebaseFilename",
+		Hidden: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
 			if flagSet.NArg() < 3 {
 				commandError(asyncConfig.App, flagSet, "not enough arguments", command.Usage)
 			}
+			var groups []fixupGroup
+			for _, groupArgs := range splitArgs(flagSet.Args()[:flagSet.NArg()-1], fixupGroupSeparator) {
+				if len(groupArgs) < 2 {
+					commandError(asyncConfig.App, flagSet, "each targetCommit needs at least one fixup commit", command.Usage)
+				}
+				groups = append(groups, fixupGroup{targetCommit: groupArgs[0], fixupCommits: groupArgs[1:]})
+			}
+			rebaseFilename := flagSet.Arg(flagSet.NArg() - 1)
 
-			targetCommit := flagSet.Arg(0)
-			fixupCommits := flagSet.Args()[1 : len(flagSet.Args())-1]
-			rebaseFilename := flagSet.Arg(len(flagSet.Args()) - 1)
-
-			markAsFixup(targetCommit, fixupCommits, rebaseFilename)
+			markAsFixup(groups, rebaseFilename)
 		}}
 }
 
-func markAsFixup(targetCommit string, fixupCommits []string, rebaseFilename string) {
+// Returns args split into the groups between each separator.
+func splitArgs(args []string, separator string) [][]string {
+	groups := [][]string{{}}
+	for _, arg := range args {
+		if arg == separator {
+			groups = append(groups, []string{})
+		} else {
+			groups[len(groups)-1] = append(groups[len(groups)-1], arg)
+		}
+	}
+	return groups
+}
+
+// Moves the fixup commits of each of groups after its target commit in the rebase todo list
+// rebaseFilename, and marks them as fixups.
+func markAsFixup(groups []fixupGroup, rebaseFilename string) {
 	data, err := os.ReadFile(rebaseFilename)
 
-	slog.Debug(fmt.Sprint("Got targetCommit ", targetCommit, " fixupCommits ", fixupCommits, " rebaseFilename ", rebaseFilename))
+	slog.Debug(fmt.Sprint("Got fixup groups ", groups, " rebaseFilename ", rebaseFilename))
 	if err != nil {
 		panic(fmt.Sprint("Could not open ", rebaseFilename, err))
 	}
+	fullGroups := util.MapSlice(groups, func(group fixupGroup) fixupGroup {
+		return fixupGroup{targetCommit: getFullCommit(group.targetCommit), fixupCommits: util.MapSlice(group.fixupCommits, getFullCommit)}
+	})
 
 	originalText := string(data)
 	var newText strings.Builder
 
-	fixupLines := make([]string, len(fixupCommits))
-	i := 0
+	fixupLines := make([][]string, len(groups))
+	fixupCount := 0
 
 	lines := strings.Split(strings.TrimSuffix(originalText, "\n"), "\n")
 	for _, line := range lines {
-		if isFixupLine(line, fixupCommits) {
-			fixupLines[i] = strings.Replace(line, "pick", "fixup", 1)
-			i++
+		if i := getFixupGroupIndex(line, fullGroups); i != -1 {
+			fixupLines[i] = append(fixupLines[i], strings.Replace(line, "pick", "fixup", 1))
+			fixupCount++
 		}
 	}
-	if i != len(fixupCommits) {
-		panic(fmt.Sprint("Could only find ", i, " of ", len(fixupCommits), " fixup commits ", fixupCommits, " in ", lines))
+	expectedCount := 0
+	for _, group := range groups {
+		expectedCount += len(group.fixupCommits)
+	}
+	if fixupCount != expectedCount {
+		panic(fmt.Sprint("Could only find ", fixupCount, " of ", expectedCount, " fixup commits ", groups, " in ", lines))
 	}
 	for _, line := range lines {
-		if strings.HasPrefix(line, "pick ") && strings.Contains(line, targetCommit) {
+		targetIndex := slices.IndexFunc(fullGroups, func(group fixupGroup) bool {
+			return isPickOf(line, group.targetCommit)
+		})
+		if targetIndex != -1 {
 			newText.WriteString(line)
 			newText.WriteString("\n")
-			for _, fixupLine := range fixupLines {
+			for _, fixupLine := range fixupLines[targetIndex] {
 				newText.WriteString(fixupLine)
 				newText.WriteString("\n")
 			}
-		} else if !isFixupLine(line, fixupCommits) {
+		} else if getFixupGroupIndex(line, fullGroups) == -1 {
 			newText.WriteString(line)
 			newText.WriteString("\n")
 		}
@@ -75,14 +116,23 @@ func markAsFixup(targetCommit string, fixupCommits []string, rebaseFilename stri
 	}
 }
 
-func isFixupLine(line string, fixupCommits []string) bool {
-	if !strings.HasPrefix(line, "pick ") {
-		return false
-	}
-	for _, commit := range fixupCommits {
-		if strings.Contains(line, commit) {
-			return true
-		}
-	}
-	return false
+// Returns the index of the group of groups that line picks a fixup commit of, or -1 if none.
+func getFixupGroupIndex(line string, groups []fixupGroup) int {
+	return slices.IndexFunc(groups, func(group fixupGroup) bool {
+		return slices.ContainsFunc(group.fixupCommits, func(fixupCommit string) bool {
+			return isPickOf(line, fixupCommit)
+		})
+	})
+}
+
+// Returns whether line of a rebase todo list picks fullCommit. Only the commit field is compared,
+// as the subject of a commit can contain the hash of another.
+func isPickOf(line string, fullCommit string) bool {
+	fields := strings.Fields(line)
+	return len(fields) >= 2 && fields[0] == "pick" && strings.HasPrefix(fullCommit, fields[1])
+}
+
+// Returns the full hash of commit.
+func getFullCommit(commit string) string {
+	return strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", commit))
 }
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Prefix of a commit passed to sequence-editor-reorder that is squashed into the commit before it.
+const reorderFixupPrefix = "fixup:"
+
+func createReorderCommand() Command {
+	flagSet := flag.NewFlagSet("sequence-editor-reorder", flag.ContinueOnError)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Sequence editor for git rebase used by move and squash",
+		Description: "For use as a sequence editor during an interactive git rebase. Reorders the commits\n" +
+			"so that they are in the same order as the arguments, oldest first. Commits prefixed\n" +
+			"with \"" + reorderFixupPrefix + "\" are marked as fixup commits of the commit before them.",
+		Usage:  "sd " + flagSet.Name() + " [" + reorderFixupPrefix + "]commit1 [" + reorderFixupPrefix + "]commit2... rebaseFilename",
+		Hidden: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() < 2 {
+				commandError(asyncConfig.App, flagSet, "not enough arguments", command.Usage)
+			}
+			commits := flagSet.Args()[0 : len(flagSet.Args())-1]
+			rebaseFilename := flagSet.Arg(len(flagSet.Args()) - 1)
+
+			reorder(commits, rebaseFilename)
+		}}
+}
+
+func reorder(commits []string, rebaseFilename string) {
+	data, err := os.ReadFile(rebaseFilename)
+	slog.Debug(fmt.Sprint("Got commits ", commits, " rebaseFilename ", rebaseFilename))
+	if err != nil {
+		panic(fmt.Sprint("Could not open ", rebaseFilename, err))
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	var newText strings.Builder
+	usedLines := make([]int, 0, len(commits))
+	for _, commit := range commits {
+		action := "pick"
+		if strings.HasPrefix(commit, reorderFixupPrefix) {
+			action = "fixup"
+			commit = strings.TrimPrefix(commit, reorderFixupPrefix)
+		}
+		fullCommit := getFullCommit(commit)
+		index := slices.IndexFunc(lines, func(line string) bool {
+			return isPickOf(line, fullCommit)
+		})
+		if index == -1 {
+			panic(fmt.Sprint("Could not find commit ", commit, " in ", lines))
+		}
+		newText.WriteString(strings.Replace(lines[index], "pick", action, 1))
+		newText.WriteString("\n")
+		usedLines = append(usedLines, index)
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "pick ") && !slices.Contains(usedLines, i) {
+			panic(fmt.Sprint("Commit is missing from the new order: ", line))
+		}
+		if !slices.Contains(usedLines, i) {
+			newText.WriteString(line)
+			newText.WriteString("\n")
+		}
+	}
+
+	err = os.WriteFile(rebaseFilename, []byte(newText.String()), 0)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Rebases the commits after base into the order of todo, oldest first, using
+// sequence-editor-reorder. Returns the full hashes of the rebased commits, oldest first.
+func rebaseInOrder(appConfig util.AppConfig, base string, todo []string) []string {
+	environmentVariables := []string{
+		"GIT_SEQUENCE_EDITOR=" + appConfig.AppExecutable + " sequence-editor-reorder " + strings.Join(todo, " "),
+	}
+	slog.Debug(fmt.Sprint("Using sequence editor ", environmentVariables))
+	options := util.ExecuteOptions{EnvironmentVariables: environmentVariables, Io: appConfig.Io}
+	util.ExecuteOrDie(options, "git", "rebase", "-i", base)
+	return strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-list", "--reverse", base+"..HEAD"))
+}
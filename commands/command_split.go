@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Maximum length of the summary of a hunk displayed by "sd split".
+const maxHunkSummaryLength = 60
+
+// The diff of a single file of a commit.
+type filePatch struct {
+	filename string
+	// Lines before the first hunk, such as "diff --git" and "+++".
+	header string
+	hunks  []string
+}
+
+// Part of a commit that can be moved to a new commit: a hunk, or a whole file if its changes
+// cannot be split, for example if it is binary or new.
+type splitHunk struct {
+	file *filePatch
+	// Empty if the hunk is the whole file.
+	hunk string
+}
+
+func createSplitCommand() Command {
+	flagSet := flag.NewFlagSet("split", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Split a commit into several commits",
+		Description: "Displays the hunks of a commit and lets you choose which new commit\n" +
+			"each hunk, or file, goes into, along with the subject of each new\n" +
+			"commit. The commit is then replaced on " + util.GetMainBranchForHelp() + " by the new commits,\n" +
+			"oldest first, so that each of them can become its own PR.\n" +
+			"\n" +
+			"New files, deleted files, and binary files cannot be split, so all of\n" +
+			"their changes go into the same commit.",
+		Usage:    "sd " + flagSet.Name() + " [flags] [commitIndicator]",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() > 1 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			selectCommitOptions := interactive.CommitSelectionOptions{
+				Prompt:      "What commit do you want to split?",
+				CommitType:  interactive.CommitTypeNoPr,
+				MultiSelect: false,
+			}
+			targetCommit := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectCommitOptions)
+			split(asyncConfig.App, targetCommit[0])
+		}}
+}
+
+// Replaces gitLog on main with new commits that contain its hunks, as chosen by the user.
+func split(appConfig util.AppConfig, gitLog templates.GitLog) {
+	util.RequireMainBranch()
+	templates.RequireCommitOnMain(gitLog.Commit)
+	if len(getExistingBranches([]templates.GitLog{gitLog})) > 0 {
+		panic("Commit " + gitLog.Commit + " has a PR, only commits without a PR can be split")
+	}
+	if !interactive.InteractiveEnabled(appConfig) {
+		panic("Cannot split " + gitLog.Commit + " because not a terminal")
+	}
+	hunks := getSplitHunks(gitLog.Commit)
+	if len(hunks) < 2 {
+		panic("Commit " + gitLog.Commit + " only has one change, nothing to split")
+	}
+	selection, ok := interactive.GetSplitSelection(appConfig.Io, util.MapSlice(hunks, getSplitHunkRow), gitLog.Subject)
+	if !ok {
+		appConfig.Exit(0)
+		return
+	}
+	if len(selection.Subjects) < 2 {
+		panic("All changes are in the same commit, nothing to split")
+	}
+	shouldPopStash := util.Stash("split " + gitLog.Commit + " " + gitLog.Subject)
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		util.PopStash(shouldPopStash)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", "--detach", gitLog.Commit+"^")
+	patchDir, err := os.MkdirTemp("", "sd-split")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(patchDir)
+	// The body of the commit, including its trailers, goes into the first new commit.
+	body := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--no-patch", "--format=%b", gitLog.Commit))
+	for i, subject := range selection.Subjects {
+		patchFile := filepath.Join(patchDir, fmt.Sprint(i, ".patch"))
+		if err := os.WriteFile(patchFile, []byte(getSplitPatch(hunks, selection.Assignments, i)), 0644); err != nil {
+			panic(err)
+		}
+		slog.Info("Committing " + subject)
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "apply", "--index", patchFile)
+		commitArgs := []string{"commit", "--no-verify", "-m", subject}
+		if i == 0 && body != "" {
+			commitArgs = append(commitArgs, "-m", body)
+		}
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", commitArgs...)
+	}
+	if _, err := util.Execute(util.ExecuteOptions{}, "git", "diff", "--quiet", gitLog.Commit, "HEAD"); err != nil {
+		panic("New commits do not have the same changes as " + gitLog.Commit)
+	}
+	splitHead := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", "HEAD"))
+	slog.Info("Replacing " + gitLog.Commit + " on " + util.GetMainBranchOrDie())
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rebase", "--onto", splitHead, gitLog.Commit, util.GetMainBranchOrDie())
+	rollbackManager.Clear()
+	templates.FollowRewrittenCommits()
+	slog.Info(fmt.Sprint("Split ", gitLog.Commit, " into ", len(selection.Subjects), " commits"))
+}
+
+// Returns the hunks of commit, in the order of its diff.
+func getSplitHunks(commit string) []splitHunk {
+	diff := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--format=",
+		"--no-color", "--no-ext-diff", "--no-renames", "--binary", commit)
+	var hunks []splitHunk
+	for _, file := range parseFilePatches(diff) {
+		if len(file.hunks) == 0 || strings.Contains(file.header, "\nnew file mode ") ||
+			strings.Contains(file.header, "\ndeleted file mode ") {
+			hunks = append(hunks, splitHunk{file: file})
+			continue
+		}
+		for _, hunk := range file.hunks {
+			hunks = append(hunks, splitHunk{file: file, hunk: hunk})
+		}
+	}
+	return hunks
+}
+
+// Parses the output of "git show" or "git diff" into the patch of each file.
+func parseFilePatches(diff string) []*filePatch {
+	var files []*filePatch
+	var current *filePatch
+	var section strings.Builder
+	endSection := func() {
+		if current == nil {
+			section.Reset()
+			return
+		}
+		if len(current.hunks) == 0 && current.header == "" {
+			current.header = section.String()
+		} else {
+			current.hunks = append(current.hunks, section.String())
+		}
+		section.Reset()
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			endSection()
+			current = &filePatch{filename: getDiffFilename(line)}
+			files = append(files, current)
+		case strings.HasPrefix(line, "@@ ") && current != nil:
+			endSection()
+		}
+		section.WriteString(line)
+	}
+	endSection()
+	return files
+}
+
+// Returns the filename of a "diff --git a/filename b/filename" line.
+func getDiffFilename(diffLine string) string {
+	names := strings.TrimSpace(strings.TrimPrefix(diffLine, "diff --git "))
+	if index := strings.Index(names, " b/"); index != -1 {
+		return strings.TrimPrefix(names[:index], "a/")
+	}
+	return names
+}
+
+// Returns the patch of the hunks that are assigned to commitIndex.
+func getSplitPatch(hunks []splitHunk, assignments []int, commitIndex int) string {
+	var patch strings.Builder
+	var lastFile *filePatch
+	for i, hunk := range hunks {
+		if assignments[i] != commitIndex {
+			continue
+		}
+		if hunk.file != lastFile {
+			patch.WriteString(hunk.file.header)
+			lastFile = hunk.file
+		}
+		if hunk.hunk == "" {
+			patch.WriteString(strings.Join(hunk.file.hunks, ""))
+		} else {
+			patch.WriteString(hunk.hunk)
+		}
+	}
+	return patch.String()
+}
+
+func getSplitHunkRow(hunk splitHunk) interactive.SplitHunk {
+	row := interactive.SplitHunk{Filename: hunk.file.filename}
+	switch {
+	case hunk.hunk != "":
+		lines := strings.Split(hunk.hunk, "\n")
+		row.Lines = strings.TrimSpace(strings.Split(strings.TrimPrefix(lines[0], "@@"), "@@")[0])
+		for _, line := range lines[1:] {
+			if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+				row.Summary = line
+				break
+			}
+		}
+	case strings.Contains(hunk.file.header, "\nnew file mode "):
+		row.Summary = "(new file)"
+	case strings.Contains(hunk.file.header, "\ndeleted file mode "):
+		row.Summary = "(deleted file)"
+	case strings.Contains(hunk.file.header, "\nGIT binary patch"):
+		row.Summary = "(binary file)"
+	default:
+		row.Summary = "(whole file)"
+	}
+	if summary := []rune(row.Summary); len(summary) > maxHunkSummaryLength {
+		row.Summary = string(summary[:maxHunkSummaryLength]) + "…"
+	}
+	return row
+}
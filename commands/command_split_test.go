@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Contents of a file with enough lines that changes at its start and end are separate hunks.
+func getSplitFileContents(first string, last string) string {
+	lines := []string{first}
+	for i := range 20 {
+		lines = append(lines, "line "+string(rune('a'+i)))
+	}
+	return strings.Join(append(lines, last), "\n") + "\n"
+}
+
+func TestSdSplit_WhenHunksAssigned_ReplacesCommitWithNewCommits(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "file", getSplitFileContents("start", "end"))
+	testutil.CommitFileChange("second", "file", getSplitFileContents("new start", "new end"))
+	testutil.AddCommit("third", "")
+
+	interactive.SendToProgram(0,
+		interactive.NewMessageKey(tea.KeyDown),
+		interactive.NewMessageRune('2'),
+		interactive.NewMessageKey(tea.KeyEnter),
+		interactive.NewMessageKey(tea.KeyEnter),
+		tea.KeyMsg(tea.Key{Type: tea.KeyRunes, Runes: []rune("change end")}),
+		interactive.NewMessageKey(tea.KeyEnter),
+	)
+	testParseArguments("split", "2")
+
+	allCommits := templates.GetAllCommits()
+	assert.Equal([]string{"third", "change end", "second", "first"}, util.MapSlice(allCommits[0:4], func(gitLog templates.GitLog) string {
+		return gitLog.Subject
+	}))
+	assert.Equal(getSplitFileContents("new start", "end"),
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", allCommits[2].Commit+":file"))
+	contents, err := os.ReadFile("file")
+	assert.Nil(err)
+	assert.Equal(getSplitFileContents("new start", "new end"), string(contents))
+}
+
+func TestSdSplit_WhenCommitHasBody_KeepsBodyInFirstCommit(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "file", getSplitFileContents("start", "end"))
+	testutil.CommitFileChange("second", "file", getSplitFileContents("new start", "new end"))
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "--amend", "-m", "second", "-m", "The body.")
+
+	interactive.SendToProgram(0,
+		interactive.NewMessageKey(tea.KeyDown),
+		interactive.NewMessageRune('2'),
+		interactive.NewMessageKey(tea.KeyEnter),
+		interactive.NewMessageKey(tea.KeyEnter),
+		tea.KeyMsg(tea.Key{Type: tea.KeyRunes, Runes: []rune("change end")}),
+		interactive.NewMessageKey(tea.KeyEnter),
+	)
+	testParseArguments("split", "1")
+
+	allCommits := templates.GetAllCommits()
+	assert.Equal("The body.", strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", "--format=%b", allCommits[1].Commit)))
+	assert.Equal("", strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", "--format=%b", allCommits[0].Commit)))
+}
+
+func TestSdSplit_WhenHunkSummaryTooLong_TruncatesByRune(t *testing.T) {
+	row := getSplitHunkRow(splitHunk{
+		file: &filePatch{filename: "file"},
+		hunk: "@@ -1 +1 @@\n+" + strings.Repeat("é", maxHunkSummaryLength+5) + "\n",
+	})
+
+	assert.True(t, utf8.ValidString(row.Summary))
+	assert.Equal(t, maxHunkSummaryLength+1, utf8.RuneCountInString(row.Summary))
+}
+
+func TestSdSplit_WhenWholeFileAssigned_MovesAllHunksOfFile(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "file", getSplitFileContents("start", "end"))
+	testutil.CommitFileChange("second", "file", getSplitFileContents("new start", "new end"))
+	testutil.CommitFileChange("third", "other", "contents\n")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--soft", "HEAD~2")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "-m", "combined")
+
+	interactive.SendToProgram(0,
+		interactive.NewMessageRune('f'),
+		interactive.NewMessageRune('2'),
+		interactive.NewMessageKey(tea.KeyEnter),
+		tea.KeyMsg(tea.Key{Type: tea.KeyRunes, Runes: []rune(" other")}),
+		interactive.NewMessageKey(tea.KeyEnter),
+		tea.KeyMsg(tea.Key{Type: tea.KeyRunes, Runes: []rune("file")}),
+		interactive.NewMessageKey(tea.KeyEnter),
+	)
+	testParseArguments("split", "1")
+
+	allCommits := templates.GetAllCommits()
+	assert.Equal("file", allCommits[0].Subject)
+	assert.Equal("combined other", allCommits[1].Subject)
+	assert.Equal("other\n", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--name-only", "--format=", allCommits[1].Commit))
+}
+
+func TestSdSplit_WhenCancelled_DoesNotChangeCommits(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.CommitFileChange("first", "file", getSplitFileContents("start", "end"))
+	testutil.CommitFileChange("second", "file", getSplitFileContents("new start", "new end"))
+	commitsBefore := templates.GetAllCommits()
+
+	interactive.SendToProgram(0, interactive.NewMessageKey(tea.KeyEsc))
+	assert.Panics(func() {
+		// Panics instead of exiting.
+		testParseArguments("split", "1")
+	})
+
+	assert.Equal(commitsBefore, templates.GetAllCommits())
+}
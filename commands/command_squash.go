@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createSquashCommand() Command {
+	flagSet := flag.NewFlagSet("squash", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Squash commits on " + util.GetMainBranchForHelp() + " into another commit and combine their PRs",
+		Description: "Squashes commits on " + util.GetMainBranchForHelp() + " into a target commit, keeping the commit\n" +
+			"message of the target commit.\n" +
+			"\n" +
+			"If the target commit has a PR then the PRs of the squashed commits are\n" +
+			"closed, and the PR of the target commit is updated with their changes.\n" +
+			"If it does not have a PR then the PR of the oldest squashed commit is\n" +
+			"used for it instead. PRs that were stacked on a closed PR are\n" +
+			"retargeted to the remaining PR.",
+		Usage:    "sd " + flagSet.Name() + " [flags] [targetCommitIndicator [commitIndicator...]]",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			selectTargetOptions := interactive.CommitSelectionOptions{
+				Prompt:      "What commit do you want to squash commits into?",
+				CommitType:  interactive.CommitTypeBoth,
+				MultiSelect: false,
+			}
+			targetCommit := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectTargetOptions)[0]
+			selectCommitsOptions := interactive.CommitSelectionOptions{
+				Prompt:          "What commits do you want to squash into \"" + targetCommit.Subject + "\"?",
+				CommitType:      interactive.CommitTypeBoth,
+				MultiSelect:     true,
+				DisabledCommits: []string{targetCommit.Commit},
+			}
+			var commitsFromCommandLine []string
+			if flagSet.NArg() > 1 {
+				commitsFromCommandLine = flagSet.Args()[1:]
+			}
+			commitsToSquash := getTargetCommits(asyncConfig.App, command, commitsFromCommandLine, indicatorTypeString, selectCommitsOptions)
+			squash(asyncConfig.App, targetCommit, commitsToSquash)
+		}}
+}
+
+// Squashes commitsToSquash into target on main, and closes or retargets the PRs that are no
+// longer needed.
+func squash(appConfig util.AppConfig, target templates.GitLog, commitsToSquash []templates.GitLog) {
+	util.RequireMainBranch()
+	templates.RequireCommitOnMain(target.Commit)
+	squashedCommits := make([]string, 0, len(commitsToSquash))
+	for _, commit := range commitsToSquash {
+		templates.RequireCommitOnMain(commit.Commit)
+		if commit.Commit == target.Commit {
+			panic("Cannot squash " + commit.Commit + " into itself")
+		}
+		squashedCommits = append(squashedCommits, commit.Commit)
+	}
+	order := getCommitOrder()
+	oldest := slices.Index(order, target.Commit)
+	var newOrder []string
+	for i, commit := range order {
+		if slices.Contains(squashedCommits, commit) {
+			oldest = min(oldest, i)
+			continue
+		}
+		newOrder = append(newOrder, commit)
+		if commit == target.Commit {
+			// Keep the squashed commits in their original order.
+			for _, squashedCommit := range order {
+				if slices.Contains(squashedCommits, squashedCommit) {
+					newOrder = append(newOrder, reorderFixupPrefix+squashedCommit)
+				}
+			}
+		}
+	}
+	// Use the PR of the target commit, or else the PR of the oldest squashed commit.
+	remainingBranch := ""
+	var closedBranches []string
+	for _, gitLog := range append([]templates.GitLog{target}, getOldestFirst(commitsToSquash)...) {
+		if !util.GetLocalHasBranchOrDie(gitLog.Branch) {
+			continue
+		}
+		if remainingBranch == "" {
+			remainingBranch = gitLog.Branch
+		} else {
+			closedBranches = append(closedBranches, gitLog.Branch)
+		}
+	}
+
+	shouldPopStash := util.Stash("squash into " + target.Commit + " " + target.Subject)
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		util.PopStash(shouldPopStash)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	slog.Info(fmt.Sprint("Squashing ", commitsToSquash, " into ", target.Commit, " ", target.Subject))
+	rebasedCommits := rebaseInOrder(appConfig, order[oldest]+"^", newOrder[oldest:])
+	// The fixups were squashed, so the position of target is the number of picked commits before it.
+	targetIndex := slices.IndexFunc(slices.DeleteFunc(slices.Clone(newOrder[oldest:]), func(commit string) bool {
+		return strings.HasPrefix(commit, reorderFixupPrefix)
+	}), func(commit string) bool {
+		return commit == target.Commit
+	})
+	updatedTarget := target
+	updatedTarget.Commit = strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", "--short", rebasedCommits[targetIndex]))
+	rollbackManager.Clear()
+	if remainingBranch != "" {
+		util.RecordStackEntry(updatedTarget.Commit, remainingBranch, 0)
+		updatedTarget.Branch = remainingBranch
+	}
+	templates.FollowRewrittenCommits()
+	for _, closedBranch := range closedBranches {
+		closeSquashedBranch(closedBranch, remainingBranch)
+	}
+	if remainingBranch != "" {
+		pushUpdatedBranches(rollbackManager, []templates.GitLog{updatedTarget})
+	}
+	if len(closedBranches) > 0 && len(getStackedCommits(templates.GetNewCommits("HEAD"))) > 0 {
+		slog.Info("Restacking branches stacked on the squashed PRs")
+		restack(appConfig, []string{})
+	}
+}
+
+// Returns gitLogs ordered from the bottom of the stack to the top.
+func getOldestFirst(gitLogs []templates.GitLog) []templates.GitLog {
+	order := getCommitOrder()
+	sorted := slices.Clone(gitLogs)
+	slices.SortFunc(sorted, func(a templates.GitLog, b templates.GitLog) int {
+		return slices.Index(order, a.Commit) - slices.Index(order, b.Commit)
+	})
+	return sorted
+}
+
+// Closes the PR of branchName, which was squashed into the PR of remainingBranch, and deletes the
+// branch. Any branches stacked on branchName are stacked on remainingBranch instead.
+func closeSquashedBranch(branchName string, remainingBranch string) {
+	slog.Info("Closing PR of " + branchName + " as it was squashed into " + remainingBranch)
+	util.GetForge().ClosePullRequest(branchName, "Squashed into the PR of "+remainingBranch)
+	for _, gitLog := range templates.GetNewCommits("HEAD") {
+		if baseBranch, baseCommit := util.GetStackedBase(gitLog.Branch); baseBranch == branchName {
+			slog.Info(fmt.Sprint("Changing base of PR of ", gitLog.Branch, " to ", remainingBranch))
+			util.GetForge().EditPullRequest(gitLog.Branch, util.EditPullRequestOptions{BaseBranch: remainingBranch})
+			util.SetStackedBase(gitLog.Branch, remainingBranch, baseCommit)
+		}
+	}
+	if _, err := util.Execute(util.ExecuteOptions{}, "git", "push", util.GetPushRemote(), "--delete", branchName); err != nil {
+		slog.Warn(fmt.Sprint("Could not delete remote branch ", branchName, ": ", err))
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch", "-D", branchName)
+	util.ClearStackedBase(branchName)
+	util.RemoveStackEntries([]string{branchName})
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func getPrCloseArgs(testExecutor *util.TestExecutor) [][]string {
+	closes := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:2], []string{"pr", "close"})
+	})
+	return util.MapSlice(closes, func(next util.ExecutedResponse) []string {
+		return next.Args
+	})
+}
+
+func TestSdSquash_WhenBothHavePrs_ClosesSquashedPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-file")
+	testutil.AddCommit("second", "second-file")
+	testutil.AddCommit("third", "third-file")
+	testParseArguments("new", "3")
+	testParseArguments("new", "2")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("squash", "3", "2")
+
+	newCommits := templates.GetNewCommits("HEAD")
+	assert.Equal([]string{"third", "first"}, getSubjects(newCommits))
+	assert.Equal(allCommits[2].Branch, newCommits[1].Branch)
+	assert.Equal([][]string{
+		{"pr", "close", allCommits[1].Branch, "--comment", "Squashed into the PR of " + allCommits[2].Branch},
+	}, getPrCloseArgs(testExecutor))
+	assert.False(util.GetLocalHasBranchOrDie(allCommits[1].Branch))
+	branchFiles := util.ExecuteOrDie(util.ExecuteOptions{}, "git", "ls-tree", "--name-only", "origin/"+allCommits[2].Branch)
+	assert.Contains(branchFiles, "first-file")
+	assert.Contains(branchFiles, "second-file")
+}
+
+func TestSdSquash_WhenOnlySquashedCommitHasPr_UsesItsPr(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-file")
+	testutil.AddCommit("second", "second-file")
+	testParseArguments("new", "1")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("squash", "2", "1")
+
+	newCommits := templates.GetNewCommits("HEAD")
+	assert.Equal([]string{"first"}, getSubjects(newCommits))
+	assert.Equal(allCommits[0].Branch, newCommits[0].Branch)
+	assert.Equal(0, len(getPrCloseArgs(testExecutor)))
+}
+
+func TestSdSquash_WhenSquashingIntoItself_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	assert.Panics(func() {
+		testParseArguments("squash", "1", "1")
+	})
+	assert.Equal(allCommits, templates.GetNewCommits("HEAD"))
+}
+
+func TestSdSquash_WhenPrStackedOnSquashedPr_RetargetsIt(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-file")
+	testutil.AddCommit("second", "second-file")
+	testutil.AddCommit("third", "third-file")
+	testParseArguments("new", "3")
+	testParseArguments("new", "2")
+	testParseArguments("new", "--stack", "1")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("squash", "3", "2")
+
+	assert.True(slices.ContainsFunc(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args, []string{"pr", "edit", allCommits[0].Branch, "--base", allCommits[2].Branch})
+	}))
+	baseBranch, _ := util.GetStackedBase(allCommits[0].Branch)
+	assert.Equal(allCommits[2].Branch, baseBranch)
+}
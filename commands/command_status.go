@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createStatusCommand() Command {
+	flagSet := flag.NewFlagSet("status", flag.ContinueOnError)
+	minChecks := flagSet.Int("min-checks", 0,
+		"Minimum number of checks to consider a PR's checks complete. Defaults to the\n"+
+			"\"min-checks\" config, where -1 means to use 4 or the average number\n"+
+			"of checks of merged PRs, whatever is less.")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Displays the PR state, checks, and approvals of each commit",
+		Description: "Displays each commit on " + util.GetMainBranchForHelp() + " that is not in the remote branch,\n" +
+			"along with the state, checks, and approvers of its PR. The status of all\n" +
+			"PRs is fetched from Github with a single request.\n" +
+			"\n" +
+			"Use \"sd --output json status\" for a stable format to use in scripts.",
+		Usage:            "sd " + flagSet.Name() + " [flags]",
+		DefaultLogLevel:  slog.LevelError,
+		StructuredOutput: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			records := getStatusOutput(*minChecks)
+			if isStructuredOutput(asyncConfig.App) {
+				printStructuredOutput(asyncConfig.App, records, commitOutputHeaders, commitOutput.tsvRow)
+			} else {
+				printStatus(asyncConfig.App.Io, records)
+			}
+		}}
+}
+
+// Returns a record of each new commit along with the status of its PR.
+func getStatusOutput(minChecks int) []commitOutput {
+	util.RequireMainBranch()
+	records := getLogOutput()
+	branchNames := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.HasBranch {
+			branchNames = append(branchNames, record.Branch)
+		}
+	}
+	statuses := map[string]util.PullRequestStatus{}
+	if len(branchNames) > 0 {
+		statuses = util.GetPullRequestStatuses(branchNames, minChecks)
+	}
+	codeOwners := readRepoCodeOwners()
+	for i, record := range records {
+		records[i].Owners = getCommitOwners(codeOwners, record.Commit)
+		status, ok := statuses[record.Branch]
+		if !ok {
+			continue
+		}
+		records[i].PrNumber = status.Number
+		records[i].PrUrl = status.Url
+		records[i].State = status.State.String()
+		records[i].Checks = checksOutput{
+			Passing: status.Checks.Passing,
+			Failing: status.Checks.Failing,
+			Pending: status.Checks.Pending,
+			Total:   status.Checks.Total(),
+		}
+		records[i].Approvers = status.Approvers
+	}
+	return records
+}
+
+// Returns the code owners of the files changed by commit, sorted.
+func getCommitOwners(codeOwners util.CodeOwners, commit string) []string {
+	owners := []string{}
+	for _, filename := range getCommitFiles(commit) {
+		owners = append(owners, getFileOwners(codeOwners, filename)...)
+	}
+	slices.Sort(owners)
+	return slices.Compact(owners)
+}
+
+func printStatus(stdIo util.StdIo, records []commitOutput) {
+	writer := tabwriter.NewWriter(stdIo.Out, 0, 0, 2, ' ', 0)
+	util.Fprintln(writer, "\tCommit\tSubject\tPR\tState\tChecks\tApprovers")
+	for _, record := range records {
+		pr := ""
+		checks := ""
+		if record.PrNumber != 0 {
+			pr = fmt.Sprint("#", record.PrNumber)
+		}
+		if record.State != "" {
+			checks = fmt.Sprint(record.Checks.Passing, "/", record.Checks.Total)
+			if record.Checks.Failing > 0 {
+				checks += fmt.Sprint(" (", record.Checks.Failing, " failed)")
+			}
+		}
+		util.Fprintln(writer, fmt.Sprint(record.Index, ".")+"\t"+record.Commit+"\t"+record.Subject+"\t"+
+			pr+"\t"+record.State+"\t"+checks+"\t"+strings.Join(record.Approvers, ","))
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdStatus_OutputsStatusOfEachPr(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testParseArguments("new", "2")
+	allCommits := templates.GetNewCommits("HEAD")
+	testutil.SetOpenPullRequest(allCommits[1].Branch, 2, "mybestie")
+
+	out := testParseArguments("status", "--min-checks=2")
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Equal(3, len(lines))
+	assert.Contains(lines[1], allCommits[0].Commit)
+	assert.NotContains(lines[1], "open")
+	assert.Contains(lines[2], allCommits[1].Commit)
+	assert.Contains(lines[2], "open")
+	assert.Contains(lines[2], "2/2")
+	assert.Contains(lines[2], "mybestie")
+}
+
+func TestSdStatus_WithJsonOutput_OutputsRecords(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "first-file")
+	testParseArguments("new", "1")
+	allCommits := templates.GetNewCommits("HEAD")
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 1, "mybestie")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "mkdir", "-p", ".github")
+	if writeErr := os.WriteFile(".github/CODEOWNERS", []byte("first-file firstOwner\n"), os.ModePerm); writeErr != nil {
+		panic(writeErr)
+	}
+
+	out := testParseArguments("--output", "json", "status", "--min-checks=2")
+
+	var records []commitOutput
+	assert.Nil(json.Unmarshal([]byte(out), &records))
+	assert.Equal([]commitOutput{{
+		Index:     1,
+		Commit:    allCommits[0].Commit,
+		Subject:   "first",
+		Branch:    allCommits[0].Branch,
+		HasBranch: true,
+		State:     "open",
+		Checks:    checksOutput{Passing: 1, Total: 1},
+		Approvers: []string{"mybestie"},
+		Owners:    []string{"firstOwner"},
+	}}, records)
+}
+
+func TestSdStatus_WithTsvOutput_OutputsHeaderAndRows(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	out := testParseArguments("--output", "tsv", "status")
+
+	assert.Equal(strings.Join(commitOutputHeaders, "\t")+"\n"+
+		"1\t"+allCommits[0].Commit+"\tfirst\t"+allCommits[0].Branch+"\tfalse\t0\t\t\t0\t0\t0\t0\t\t\n", out)
+}
+
+func TestSdStatus_WithUnsupportedOutput_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	assert.Panics(func() {
+		testParseArguments("--output", "json", "rebase-main")
+	})
+}
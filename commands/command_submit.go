@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// What submit did for a commit.
+type submitAction string
+
+const (
+	submitActionCreated   submitAction = "created"
+	submitActionUpdated   submitAction = "updated"
+	submitActionUnchanged submitAction = "unchanged"
+)
+
+type submitResult struct {
+	gitLog templates.GitLog
+	action submitAction
+	// Output of "gh pr create" if a PR was created.
+	pr string
+}
+
+func createSubmitCommand() Command {
+	flagSet := flag.NewFlagSet("submit", flag.ContinueOnError)
+
+	draft := flagSet.Bool("draft", false, "Whether to create new PRs as draft")
+	featureFlag := flagSet.String("feature-flag", "", "Value for FEATURE_FLAG in description of new PRs")
+	stack := flagSet.Bool("stack", false,
+		"Whether to stack each new PR on top of the PR of the commit below it,\n"+
+			"same as \"sd new --stack\".")
+
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Create or update PRs for all commits on " + util.GetMainBranchForHelp(),
+		Description: "Creates a PR for every commit on " + util.GetMainBranchForHelp() + " that does not have one yet,\n" +
+			"and updates the branch of every commit whose contents differ from its\n" +
+			"PR branch, for example after amending the commit on " + util.GetMainBranchForHelp() + ".\n" +
+			"\n" +
+			"This is the inverse of \"sd replace-commit\". Updates are added as a new\n" +
+			"commit on the PR branch, so they do not require a force push.\n" +
+			"\n" +
+			"All branches are pushed at once and a summary of what was done for\n" +
+			"each commit is displayed at the end.",
+		Usage:    "sd " + flagSet.Name() + " [flags]",
+		Mutating: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			results := submit(asyncConfig.App, *draft, *featureFlag, *stack)
+			printSubmitResults(asyncConfig.App.Io, results)
+		}}
+}
+
+// Creates or updates the branches of all new commits, pushes them, and creates any missing PRs.
+func submit(appConfig util.AppConfig, draft bool, featureFlag string, stack bool) []submitResult {
+	util.RequireMainBranch()
+	newCommits := templates.GetNewCommits("HEAD")
+	if len(newCommits) == 0 {
+		slog.Info("No new commits to submit")
+		return []submitResult{}
+	}
+	if stack {
+		requireStackingSupported()
+	}
+	shouldPopStash := util.Stash("submit")
+	rollbackManager := &util.GitRollbackManager{}
+	rollbackManager.SaveState()
+	defer func() {
+		r := recover()
+		if r != nil {
+			rollbackManager.Restore(r)
+		}
+		util.PopStash(shouldPopStash)
+		if r != nil {
+			panic(r)
+		}
+	}()
+	if util.GetConfigBool("change-id") {
+		withoutChangeIds := util.FilterSlice(newCommits, func(gitLog templates.GitLog) bool {
+			return gitLog.ChangeId == "" && !util.GetLocalHasBranchOrDie(gitLog.Branch)
+		})
+		if len(withoutChangeIds) > 0 {
+			addChangeIds(appConfig, withoutChangeIds)
+			newCommits = templates.GetNewCommits("HEAD")
+		}
+	}
+	mainBranch := util.GetMainBranchOrDie()
+	results := make([]submitResult, 0, len(newCommits))
+	previousBranch := ""
+	// Go from the bottom of the stack so that stacked branches can be based on the ones below them.
+	for _, gitLog := range slices.Backward(newCommits) {
+		var action submitAction
+		if util.GetLocalHasBranchOrDie(gitLog.Branch) {
+			action = syncSubmitBranch(rollbackManager, gitLog)
+		} else {
+			baseBranch := mainBranch
+			if stack && previousBranch != "" {
+				baseBranch = previousBranch
+			}
+			createSubmitBranch(rollbackManager, gitLog, baseBranch)
+			action = submitActionCreated
+		}
+		results = append(results, submitResult{gitLog: gitLog, action: action})
+		previousBranch = gitLog.Branch
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", mainBranch)
+
+	pushSubmitBranches(results)
+	rollbackManager.Clear()
+
+	localStack := templates.GetStack()
+	for i, result := range results {
+		if result.action != submitActionCreated {
+			continue
+		}
+		baseBranch, _ := util.GetStackedBase(result.gitLog.Branch)
+		if baseBranch == "" {
+			baseBranch = mainBranch
+		}
+		prText := templates.GetPullRequestText(result.gitLog.Commit, featureFlag, localStack)
+		slog.Info(fmt.Sprint("Creating PR for ", result.gitLog.Commit, " ", result.gitLog.Subject))
+		results[i].pr = strings.TrimSpace(createPr(prText, baseBranch, result.gitLog.Branch, draft))
+		addCreatedPullRequest(&localStack, result.gitLog.Branch, results[i].pr, prText)
+	}
+	for _, result := range results {
+		util.RecordStackEntry(result.gitLog.Commit, result.gitLog.Branch, getPullRequestNumber(result.pr))
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "config", "advice.skippedCherryPicks", "false")
+	updateStackSections(localStack)
+	// Return in the same order as "sd log".
+	slices.Reverse(results)
+	return results
+}
+
+// Creates the branch for gitLog based on baseBranch, without switching to it.
+func createSubmitBranch(rollbackManager *util.GitRollbackManager, gitLog templates.GitLog, baseBranch string) {
+	var commitToBranchFrom string
+	if baseBranch == util.GetMainBranchOrDie() {
+		commitToBranchFrom = util.FirstOriginMainCommit(baseBranch)
+	} else {
+		commitToBranchFrom = util.GetBranchLatestCommit(baseBranch)
+	}
+	slog.Info(fmt.Sprint("Creating branch ", gitLog.Branch, " based off ", baseBranch))
+	cherryPickOnto(commitToBranchFrom, gitLog.Commit)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch", "--no-track", gitLog.Branch, "HEAD")
+	rollbackManager.CreatedBranch(gitLog.Branch)
+	if baseBranch != util.GetMainBranchOrDie() {
+		util.SetStackedBase(gitLog.Branch, baseBranch, commitToBranchFrom)
+	}
+}
+
+// Adds a commit to the branch of gitLog if its contents differ from the commit.
+func syncSubmitBranch(rollbackManager *util.GitRollbackManager, gitLog templates.GitLog) submitAction {
+	_, commitToDiffFrom := util.GetStackedBase(gitLog.Branch)
+	if commitToDiffFrom == "" {
+		commitToDiffFrom = util.FirstOriginMainCommit(gitLog.Branch)
+	}
+	cherryPickOnto(commitToDiffFrom, gitLog.Commit)
+	expectedTree := getTree("HEAD")
+	if expectedTree == getTree(gitLog.Branch) {
+		slog.Info(fmt.Sprint("Branch ", gitLog.Branch, " is already up to date"))
+		return submitActionUnchanged
+	}
+	slog.Info(fmt.Sprint("Updating branch ", gitLog.Branch, " with changes from ", gitLog.Commit))
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", gitLog.Branch)
+	rollbackManager.SaveState()
+	updatedCommit := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{},
+		"git", "commit-tree", expectedTree, "-p", gitLog.Branch, "-m", gitLog.Subject))
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", updatedCommit)
+	return submitActionUpdated
+}
+
+// Leaves HEAD detached at a cherry-pick of commit onto baseCommit.
+func cherryPickOnto(baseCommit string, commit string) {
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", "--detach", baseCommit)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "cherry-pick", commit)
+}
+
+func getTree(commitish string) string {
+	return strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", commitish+"^{tree}"))
+}
+
+// Pushes all created and updated branches with a single git push.
+func pushSubmitBranches(results []submitResult) {
+	pushArgs := []string{"push", "-u", util.GetPushRemote()}
+	for _, result := range results {
+		switch result.action {
+		case submitActionCreated:
+			// Force push new branches in case a stale remote branch has the same name.
+			pushArgs = append(pushArgs, "+"+result.gitLog.Branch)
+		case submitActionUpdated:
+			pushArgs = append(pushArgs, result.gitLog.Branch)
+		}
+	}
+	if len(pushArgs) == 3 {
+		slog.Info("No branches to push")
+		return
+	}
+	slog.Info(fmt.Sprint("Pushing ", len(pushArgs)-3, " branches"))
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", pushArgs...)
+}
+
+// Updates the PR branches of gitLogs to match their commits and pushes them. Commits without a
+// branch are skipped.
+func pushUpdatedBranches(rollbackManager *util.GitRollbackManager, gitLogs []templates.GitLog) {
+	var results []submitResult
+	for _, gitLog := range gitLogs {
+		if !util.GetLocalHasBranchOrDie(gitLog.Branch) {
+			continue
+		}
+		results = append(results, submitResult{gitLog: gitLog, action: syncSubmitBranch(rollbackManager, gitLog)})
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+	pushSubmitBranches(results)
+	rollbackManager.Clear()
+}
+
+func printSubmitResults(stdIo util.StdIo, results []submitResult) {
+	if len(results) == 0 {
+		return
+	}
+	writer := tabwriter.NewWriter(stdIo.Out, 0, 0, 3, ' ', 0)
+	util.Fprintln(writer, "Index\tCommit\tAction\tBranch\tPR")
+	for i, result := range results {
+		util.Fprintln(writer, fmt.Sprint(i+1, "\t", result.gitLog.Commit, "\t", result.action, "\t", result.gitLog.Branch, "\t", result.pr))
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdSubmit_WhenNoPrs_CreatesPrForEachCommit(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testExecutor.Responses = nil
+
+	out := testParseArguments("submit")
+
+	assert.Equal(util.GetMainBranchOrDie(), util.GetCurrentBranchName())
+	for _, commit := range allCommits {
+		assert.True(util.RemoteHasBranch(commit.Branch))
+		assert.Contains(out, commit.Branch)
+	}
+	assert.Contains(out, string(submitActionCreated))
+	pushes := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "git" && len(next.Args) > 0 && next.Args[0] == "push"
+	})
+	assert.Equal(1, len(pushes))
+	prCreates := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args[0:2], []string{"pr", "create"})
+	})
+	assert.Equal(2, len(prCreates))
+}
+
+func TestSdSubmit_WhenCommitAmended_AddsCommitToBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testParseArguments("new", "2")
+	testParseArguments("new", "1")
+
+	// Amend the bottom commit on main.
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", "--detach", "HEAD~1")
+	if err := os.WriteFile("first", []byte("amended"), os.ModePerm); err != nil {
+		panic(err)
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "--amend", "-a", "--no-edit")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "cherry-pick", util.GetMainBranchOrDie())
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "branch", "-f", util.GetMainBranchOrDie(), "HEAD")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+	allCommits := templates.GetNewCommits("HEAD")
+
+	out := testParseArguments("submit")
+
+	assert.Contains(out, string(submitActionUpdated))
+	assert.Contains(out, string(submitActionUnchanged))
+	branchCommits := templates.GetNewCommits(allCommits[1].Branch)
+	assert.Equal(2, len(branchCommits))
+	assert.Equal("", util.ExecuteOrDie(util.ExecuteOptions{}, "git", "diff", allCommits[1].Commit, allCommits[1].Branch))
+	assert.Equal(util.GetBranchLatestCommit(allCommits[1].Branch), util.GetBranchLatestCommit("origin/"+allCommits[1].Branch))
+	assert.Equal(1, len(templates.GetNewCommits(allCommits[0].Branch)))
+}
+
+func TestSdSubmit_WithStack_BasesEachPrOnBranchBelow(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	allCommits := templates.GetNewCommits("HEAD")
+
+	testParseArguments("submit", "--stack")
+
+	baseBranch, _ := util.GetStackedBase(allCommits[0].Branch)
+	assert.Equal(allCommits[1].Branch, baseBranch)
+	bottomBase, _ := util.GetStackedBase(allCommits[1].Branch)
+	assert.Equal("", bottomBase)
+}
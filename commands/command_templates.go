@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"flag"
+	"log/slog"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createTemplatesCommand() Command {
+	flagSet := flag.NewFlagSet("templates", flag.ContinueOnError)
+	indicatorTypeString := addIndicatorFlag(flagSet)
+	featureFlag := flagSet.String("feature-flag", "", "Value for FEATURE_FLAG in PR description")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Previews the branch name, PR title, and PR description of a commit",
+		Description: "Renders the templates of the branch name, PR title, and PR description\n" +
+			"for a commit, without creating a PR, so that changes to the templates\n" +
+			"can be previewed. See \"sd new --help\" for the values of the templates.\n" +
+			"\n" +
+			"Templates are read from " + templates.RepoTemplatesDir + "/ in the repository and from\n" +
+			"~/.gh-stacked-diff/, which takes precedence. Every .template file in\n" +
+			"them can be included in another as a partial, for example\n" +
+			"ticket.template via {{template \"ticket\" .}}.\n" +
+			"\n" +
+			"Templates can use these functions, which follow the sprig library:\n" +
+			"\n" +
+			"   lower, upper, title, trim             change case or trim spaces\n" +
+			"   trimPrefix, trimSuffix PREFIX S       remove a prefix or suffix\n" +
+			"   replace OLD NEW S                     replace all OLD with NEW\n" +
+			"   contains, hasPrefix, hasSuffix SUB S  test for a substring\n" +
+			"   trunc N S                             first N characters, or last -N\n" +
+			"   split SEP S, join SEP LIST            split or join a list\n" +
+			"   indent N S                            indent each line by N spaces\n" +
+			"   regexMatch REGEX S                    whether REGEX matches\n" +
+			"   regexFind REGEX S                     first match of REGEX\n" +
+			"   regexReplaceAll REGEX S REPLACEMENT   replace matches, ${1} for groups\n" +
+			"   now, date LAYOUT TIME                 format a time, such as\n" +
+			"                                         {{now | date \"2006-01-02\"}}\n" +
+			"   default DEFAULT VALUE                 DEFAULT if VALUE is empty\n" +
+			"   empty VALUE                           whether VALUE is empty\n" +
+			"   coalesce VALUE...                     first VALUE that is not empty\n" +
+			"\n" +
+			"For example {{.CommitSummary | lower | trunc 50}}.",
+		Usage: "sd " + flagSet.Name() + " [flags] render [commitIndicator]",
+		// Avoid logging in between the rendered templates.
+		DefaultLogLevel: slog.LevelError,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() == 0 {
+				commandError(asyncConfig.App, flagSet, "missing templates command", command.Usage)
+			}
+			if flagSet.Arg(0) != "render" {
+				commandError(asyncConfig.App, flagSet, "unknown templates command "+flagSet.Arg(0), command.Usage)
+			}
+			if flagSet.NArg() > 2 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			selectCommitOptions := interactive.CommitSelectionOptions{
+				Prompt:      "What commit do you want to render the templates of?",
+				CommitType:  interactive.CommitTypeBoth,
+				MultiSelect: false,
+			}
+			targetCommits := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(1)}, indicatorTypeString, selectCommitOptions)
+			renderTemplates(asyncConfig.App, targetCommits[0], *featureFlag)
+		}}
+}
+
+// Outputs the branch name, PR title, and PR description of gitLog.
+func renderTemplates(appConfig util.AppConfig, gitLog templates.GitLog, featureFlag string) {
+	prText := templates.GetPullRequestText(gitLog.Commit, featureFlag, templates.GetStack())
+	util.Fprintln(appConfig.Io.Out, "Branch: "+gitLog.Branch)
+	util.Fprintln(appConfig.Io.Out, "Title: "+prText.Title)
+	util.Fprintln(appConfig.Io.Out, "")
+	util.Fprintln(appConfig.Io.Out, prText.Description)
+}
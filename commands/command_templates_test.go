@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdTemplates_Render_OutputsBranchTitleAndDescription(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetAllCommits()
+
+	out := testParseArguments("templates", "--feature-flag", "my_flag", "render", allCommits[0].Commit)
+
+	assert.Contains(out, "Branch: "+allCommits[0].Branch+"\nTitle: first\n\n")
+	assert.Contains(out, "#### Feature flag(s): `my_flag`")
+	assert.NotContains(out, "#### Ticket")
+}
+
+func TestSdTemplates_Render_WithTicket_LinksTicket(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("CONV-123 Add feature", "")
+
+	out := testParseArguments("templates", "render", "1")
+
+	assert.Contains(out, "#### Ticket: [CONV-123]("+util.GetConfigString("jira-url")+"CONV-123)")
+}
+
+func TestSdTemplates_Render_WithPartialsAndFunctions_UsesUserTemplatesOverRepo(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	writeTemplate(templates.RepoTemplatesDir, "pr-title.template",
+		`{{template "ticket-prefix" .}}{{.CommitSummaryWithoutTicket | lower | trunc 11}}`)
+	writeTemplate(templates.RepoTemplatesDir, "ticket-prefix.template", `[{{.TicketNumber}}] `)
+	writeTemplate(util.GetUserConfigDir(), "ticket-prefix.template", `{{.TicketNumber | default "NONE"}}: `)
+	testutil.AddCommit("CONV-123 Add Amazing Feature", "")
+
+	out := testParseArguments("templates", "render", "1")
+
+	assert.Contains(out, "Title: CONV-123: add amazing\n")
+}
+
+func TestSdTemplates_Render_WithStack_LinksPullRequestsOfStack(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	testutil.AddCommit("third", "")
+	setOpenPullRequests(templates.GetNewCommits("HEAD"), 101)
+
+	out := testParseArguments("templates", "render", "2")
+
+	assert.Contains(out, "#### Stack: [#101](https://github.com/pull/101) → **[#102](https://github.com/pull/102)** → [#103](https://github.com/pull/103)\n")
+}
+
+func TestSdTemplates_Render_WithStackData_UsesPositionAndChangedFiles(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	writeTemplate(util.GetUserConfigDir(), "pr-description.template",
+		`{{.StackPosition}}/{{.StackSize}} {{join "," .ChangedFiles}} below:{{.PreviousPullRequest.Link}}{{if not .NextPullRequest}} top{{end}}`)
+	testutil.AddCommit("first", "")
+	testutil.AddCommit("second", "")
+	setOpenPullRequests(templates.GetNewCommits("HEAD")[1:], 101)
+
+	out := testParseArguments("templates", "render", "1")
+
+	assert.Contains(out, "2/2 second below:[#101](https://github.com/pull/101) top")
+}
+
+func TestSdTemplates_Render_WithFilenameWithSpace_KeepsWholeFilename(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	writeTemplate(util.GetUserConfigDir(), "pr-description.template", `{{join "|" .ChangedFiles}}`)
+	testutil.AddCommit("first", "file with spaces.txt")
+
+	out := testParseArguments("templates", "render", "1")
+
+	assert.Contains(out, "\nfile with spaces.txt\n")
+}
+
+func TestSdTemplates_Render_WithChangeId_RendersChangeIdInDescription(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	writeTemplate(util.GetUserConfigDir(), "pr-description.template", `id:{{.ChangeId}}`)
+	testutil.AddCommit("first", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "commit", "--amend", "-m", "first", "-m", templates.ChangeIdTrailer+": abc123")
+
+	out := testParseArguments("templates", "render", "1")
+
+	assert.Contains(out, "id:abc123")
+}
+
+func TestSdTemplates_WithUnknownCommand_Panics(t *testing.T) {
+	testutil.InitTest(t, slog.LevelError)
+
+	assert.Panics(t, func() {
+		testParseArguments("templates", "list")
+	})
+}
+
+func writeTemplate(dir string, filename string, text string) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, filename), []byte(text), os.ModePerm); err != nil {
+		panic(err)
+	}
+}
+
+// Sets an open PR for each of gitLogs, numbered from the bottom of the stack starting at number.
+func setOpenPullRequests(gitLogs []templates.GitLog, number int) {
+	for i, gitLog := range slices.Backward(gitLogs) {
+		pullRequestNumber := number + len(gitLogs) - 1 - i
+		testutil.GetFakeGithubClient().SetPullRequest(util.PullRequest{
+			Number:     pullRequestNumber,
+			HeadBranch: gitLog.Branch,
+			BaseBranch: util.GetMainBranchOrDie(),
+			State:      util.PullRequestStateOpen,
+			Title:      gitLog.Subject,
+			Body:       "<!-- sd-stack -->\n<!-- /sd-stack -->\n",
+			Url:        fmt.Sprint("https://github.com/pull/", pullRequestNumber),
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+const localRefPrefix = "refs/heads/"
+
+func getRemoteRefPrefix() string {
+	return "refs/remotes/" + util.GetPushRemote() + "/"
+}
+
+func createUndoCommand() Command {
+	flagSet := flag.NewFlagSet("undo", flag.ContinueOnError)
+	list := flagSet.Bool("list", false, "List the commands that can be undone instead of undoing one")
+	remote := flagSet.Bool("remote", false,
+		"Also restore the branches on origin that the command pushed.\n"+
+			"Otherwise only the local branches are restored.")
+	redo := flagSet.Bool("redo", false, "Redo the most recently undone command")
+	force := flagSet.Bool("force", false, "Undo even if the branches were changed since the command was executed")
+
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Undo the most recent command that changed branches",
+		Description: "Restores the branches to how they were before the most recent sd\n" +
+			"command that changed them, such as new, update, rebase-main, or\n" +
+			"replace-commit. Running it again undoes the command before that.\n" +
+			"\n" +
+			"Each command that changes branches records the commits of the branches\n" +
+			"that it changed, including the branches that it pushed, and any stash\n" +
+			"that it created and did not pop. A command that leaves a rebase stopped\n" +
+			"on a merge conflict is not recorded, and undo cannot be run until the\n" +
+			"rebase is continued or aborted.\n" +
+			"\n" +
+			"Use \"--remote\" to also restore the branches on origin.",
+		Usage: "sd " + flagSet.Name() + " [flags]",
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			if !*list && util.IsRebaseInProgress() {
+				panic("Cannot undo or redo while a rebase is in progress, finish it with \"git rebase --continue\" or \"git rebase --abort\" first")
+			}
+			if *list {
+				printJournal(asyncConfig.App)
+			} else if *redo {
+				redoJournalEntry(asyncConfig.App, *remote, *force)
+			} else {
+				undoJournalEntry(asyncConfig.App, *remote, *force)
+			}
+		}}
+}
+
+// Restores the state from before the most recent journal entry that has not been undone.
+func undoJournalEntry(appConfig util.AppConfig, remote bool, force bool) {
+	entries := util.ReadJournal(appConfig)
+	index := slices.IndexFunc(entries, func(entry util.JournalEntry) bool {
+		return entry.Undone
+	})
+	if index == -1 {
+		index = len(entries)
+	}
+	// Undo the entry before the oldest undone entry.
+	index--
+	if index < 0 {
+		panic("Nothing to undo")
+	}
+	entry := entries[index]
+	slog.Info(fmt.Sprint("Undoing \"sd ", entry.Command, "\" from ", entry.Time.Format(time.DateTime)))
+	restoreJournalRefs(entry, entry.After, entry.Before, entry.HeadBefore, remote, force)
+	for _, stash := range slices.Backward(entry.Stashes) {
+		restoreJournalStash(stash)
+	}
+	entries[index].Undone = true
+	util.WriteJournal(appConfig, entries)
+}
+
+// Restores the state from after the oldest undone journal entry.
+func redoJournalEntry(appConfig util.AppConfig, remote bool, force bool) {
+	entries := util.ReadJournal(appConfig)
+	index := slices.IndexFunc(entries, func(entry util.JournalEntry) bool {
+		return entry.Undone
+	})
+	if index == -1 {
+		panic("Nothing to redo")
+	}
+	entry := entries[index]
+	slog.Info(fmt.Sprint("Redoing \"sd ", entry.Command, "\" from ", entry.Time.Format(time.DateTime)))
+	restoreJournalRefs(entry, entry.Before, entry.After, entry.HeadAfter, remote, force)
+	entries[index].Undone = false
+	util.WriteJournal(appConfig, entries)
+}
+
+// Changes the refs of entry from the expected commits to the target commits, and then switches to
+// the head branch.
+func restoreJournalRefs(entry util.JournalEntry, expected map[string]string, target map[string]string, head string, remote bool, force bool) {
+	changedRefs := entry.ChangedRefs()
+	localRefs := util.FilterSlice(changedRefs, func(ref string) bool {
+		return strings.HasPrefix(ref, localRefPrefix)
+	})
+	remoteRefs := util.FilterSlice(changedRefs, func(ref string) bool {
+		return strings.HasPrefix(ref, getRemoteRefPrefix())
+	})
+	if !force {
+		current := util.GetJournalRefs()
+		for _, ref := range localRefs {
+			if current[ref] != expected[ref] {
+				panic(fmt.Sprint("Branch ", strings.TrimPrefix(ref, localRefPrefix), " was changed since \"sd ", entry.Command, "\".\n",
+					"Use \"--force\" to restore it anyway."))
+			}
+		}
+	}
+	shouldPopStash := util.Stash("undo")
+	// Detach so that the current branch can also be restored.
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", "--detach")
+	for _, ref := range localRefs {
+		if target[ref] == "" {
+			slog.Info("Deleting branch " + strings.TrimPrefix(ref, localRefPrefix))
+			util.ExecuteOrDie(util.ExecuteOptions{}, "git", "update-ref", "-d", ref)
+		} else {
+			slog.Info(fmt.Sprint("Restoring branch ", strings.TrimPrefix(ref, localRefPrefix), " to ", target[ref]))
+			util.ExecuteOrDie(util.ExecuteOptions{}, "git", "update-ref", ref, target[ref])
+		}
+	}
+	if head != "HEAD" && util.GetLocalHasBranchOrDie(head) {
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", head)
+	} else {
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+	}
+	if len(remoteRefs) > 0 {
+		if remote {
+			restoreRemoteRefs(remoteRefs, expected, target)
+		} else {
+			slog.Info(fmt.Sprint("Not restoring branches on origin, use \"--remote\" to restore them: ",
+				strings.Join(util.MapSlice(remoteRefs, func(ref string) string {
+					return strings.TrimPrefix(ref, getRemoteRefPrefix())
+				}), ", ")))
+		}
+	}
+	util.PopStash(shouldPopStash)
+}
+
+// Pushes the target commits of remoteRefs to origin, with a single git push. The push fails if any
+// of the branches on origin are no longer at their expected commits.
+func restoreRemoteRefs(remoteRefs []string, expected map[string]string, target map[string]string) {
+	pushArgs := []string{"push"}
+	refSpecs := make([]string, 0, len(remoteRefs))
+	for _, ref := range remoteRefs {
+		branchName := strings.TrimPrefix(ref, getRemoteRefPrefix())
+		pushArgs = append(pushArgs, "--force-with-lease="+branchName+":"+expected[ref])
+		refSpecs = append(refSpecs, target[ref]+":refs/heads/"+branchName)
+	}
+	pushArgs = append(pushArgs, util.GetPushRemote())
+	pushArgs = append(pushArgs, refSpecs...)
+	slog.Info("Restoring branches on " + util.GetPushRemote())
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", pushArgs...)
+}
+
+// Pops the stash with commit stash if it is still in the stash list.
+func restoreJournalStash(stash string) {
+	stashes := strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "stash", "list", "--format=%H"))
+	stashIndex := slices.Index(stashes, stash)
+	if stashIndex == -1 {
+		return
+	}
+	slog.Info("Popping stash that was created by the command")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "stash", "pop", fmt.Sprint("stash@{", stashIndex, "}"))
+}
+
+// Prints the journal entries, most recent first.
+func printJournal(appConfig util.AppConfig) {
+	entries := util.ReadJournal(appConfig)
+	if len(entries) == 0 {
+		util.Fprintln(appConfig.Io.Out, "No commands to undo")
+		return
+	}
+	writer := tabwriter.NewWriter(appConfig.Io.Out, 0, 0, 3, ' ', 0)
+	util.Fprintln(writer, "Time\tCommand\tBranches\tUndone")
+	for _, entry := range slices.Backward(entries) {
+		branches := make([]string, 0)
+		for _, ref := range entry.ChangedRefs() {
+			if strings.HasPrefix(ref, localRefPrefix) {
+				branches = append(branches, strings.TrimPrefix(ref, localRefPrefix))
+			}
+		}
+		undone := ""
+		if entry.Undone {
+			undone = "yes"
+		}
+		util.Fprintln(writer, entry.Time.Format(time.DateTime)+"\tsd "+entry.Command+"\t"+strings.Join(branches, ", ")+"\t"+undone)
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
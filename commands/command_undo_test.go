@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdUndo_AfterNew_DeletesBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "1")
+	assert.True(util.GetLocalHasBranchOrDie(allCommits[0].Branch))
+
+	testParseArguments("undo")
+
+	assert.False(util.GetLocalHasBranchOrDie(allCommits[0].Branch))
+	assert.True(util.RemoteHasBranch(allCommits[0].Branch))
+	assert.Equal(util.GetMainBranchOrDie(), util.GetCurrentBranchName())
+}
+
+func TestSdUndo_WithRemote_DeletesRemoteBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "1")
+
+	testParseArguments("undo", "--remote")
+
+	assert.False(util.GetLocalHasBranchOrDie(allCommits[0].Branch))
+	assert.False(util.RemoteHasBranch(allCommits[0].Branch))
+}
+
+func TestSdUndo_AfterReplaceCommit_RestoresMain(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	testutil.AddCommit("second", "")
+	mainCommit := util.GetBranchLatestCommit(util.GetMainBranchOrDie())
+	branchName := templates.GetNewCommits("HEAD")[1].Branch
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", branchName)
+	testutil.AddCommit("on branch", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+	testParseArguments("replace-commit", "2")
+	assert.NotEqual(mainCommit, util.GetBranchLatestCommit(util.GetMainBranchOrDie()))
+
+	testParseArguments("undo")
+
+	assert.Equal(mainCommit, util.GetBranchLatestCommit(util.GetMainBranchOrDie()))
+	assert.True(util.GetLocalHasBranchOrDie(branchName))
+}
+
+func TestSdUndo_WhenBranchChangedSinceCommand_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", allCommits[0].Branch)
+	testutil.AddCommit("on branch", "")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
+
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "undo")
+	})
+	assert.Contains(out.String(), "Branch "+allCommits[0].Branch+" was changed since \"sd new 1\"")
+	assert.True(util.GetLocalHasBranchOrDie(allCommits[0].Branch))
+
+	testParseArguments("undo", "--force")
+
+	assert.False(util.GetLocalHasBranchOrDie(allCommits[0].Branch))
+}
+
+func TestSdUndo_WithRedo_RestoresBranch(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "1")
+	branchCommit := util.GetBranchLatestCommit(allCommits[0].Branch)
+	testParseArguments("undo")
+
+	testParseArguments("undo", "--redo")
+
+	assert.Equal(branchCommit, util.GetBranchLatestCommit(allCommits[0].Branch))
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "undo", "--redo")
+	})
+	assert.Contains(out.String(), "Nothing to redo")
+}
+
+func TestSdUndo_WithList_ListsCommands(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetNewCommits("HEAD")
+	testParseArguments("new", "1")
+
+	out := testParseArguments("undo", "--list")
+
+	assert.Contains(out, "sd new 1")
+	assert.Contains(out, allCommits[0].Branch)
+}
+
+func TestSdUndo_WhenNothingToUndo_Panics(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	out := testutil.NewWriteRecorder()
+	assert.Panics(func() {
+		testParseArgumentsWithOut(out, "undo")
+	})
+	assert.Contains(out.String(), "Nothing to undo")
+}
+
+func TestSdUndo_WhenRebaseStopped_PanicsAndDoesNotRecordIt(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "file-with-conflicts")
+	testutil.CommitFileChange("second", "file-with-conflicts", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", templates.GetAllCommits()[1].Commit)
+	testutil.CommitFileChange("third", "file-with-conflicts", "2")
+	appConfig := util.AppConfig{UserCacheDir: getTestAppCacheDir()}
+	journalLength := len(util.ReadJournal(appConfig))
+	testParseArguments("rebase-main")
+	assert.True(util.IsRebaseInProgress())
+
+	assert.Panics(func() {
+		testParseArguments("undo")
+	})
+	assert.Equal(journalLength, len(util.ReadJournal(appConfig)))
+}
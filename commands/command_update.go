@@ -19,6 +19,7 @@ func createUpdateCommand() Command {
 	flagSet := flag.NewFlagSet("update", flag.ContinueOnError)
 	indicatorTypeString := addIndicatorFlag(flagSet)
 	reviewers, silent, minChecks := addReviewersFlags(flagSet)
+	pickCount, pickStrategy := addPickReviewersFlags(flagSet)
 	return Command{
 		FlagSet: flagSet,
 		Summary: "Add commits from " + util.GetMainBranchForHelp() + " to an existing PR",
@@ -33,18 +34,20 @@ func createUpdateCommand() Command {
 			"   [up,k]     moves cursor up\n" +
 			"   [down,j]   moves cursor down\n" +
 			"   [q,esc]    cancels\n",
+		Mutating: true,
 		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			pick := checkPickReviewersFlags(asyncConfig.App, command, pickCount, pickStrategy)
 			destCommit := getDestCommit(asyncConfig.App, command, indicatorTypeString)
 			commitsToCherryPick := getCommitsToCherryPick(asyncConfig.App, command, indicatorTypeString)
 			if *reviewers == "" && flagSet.NArg() < 2 {
-				*reviewers = interactive.UserSelection(asyncConfig)
+				*reviewers = interactive.UserSelection(asyncConfig, getSuggestedReviewers([]templates.GitLog{destCommit}))
 				if *reviewers != "" {
 					slog.Info("Using reviewers " + *reviewers)
 				}
 			}
 			updatePr(asyncConfig.App, destCommit, commitsToCherryPick)
 			if *reviewers != "" {
-				addReviewersToPr(asyncConfig, []templates.GitLog{destCommit}, true, *silent, *minChecks, *reviewers, 30*time.Second)
+				addReviewersToPr(asyncConfig, []templates.GitLog{destCommit}, true, *silent, *minChecks, util.GetConfigInt("check-retries"), *reviewers, pick, false, 30*time.Second)
 			}
 		}}
 }
@@ -93,11 +96,11 @@ func updatePr(appConfig util.AppConfig, destCommit templates.GitLog, commitsToCh
 		}
 	}()
 	slog.Info("Fast forwarding in case there were any commits made via github web interface")
-	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "fetch", "origin", destCommit.Branch)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "fetch", util.GetPushRemote(), destCommit.Branch)
 	forcePush := false
-	if _, err := util.Execute(util.ExecuteOptions{Io: appConfig.Io}, "git", "merge", "--ff-only", "origin/"+destCommit.Branch); err != nil {
+	if _, err := util.Execute(util.ExecuteOptions{Io: appConfig.Io}, "git", "merge", "--ff-only", util.GetPushRemote()+"/"+destCommit.Branch); err != nil {
 		slog.Info(fmt.Sprint("Could not fast forward to match origin. Rebasing instead. ", err))
-		util.ExecuteOrDie(util.ExecuteOptions{Io: appConfig.Io}, "git", "rebase", "origin", destCommit.Branch)
+		util.ExecuteOrDie(util.ExecuteOptions{Io: appConfig.Io}, "git", "rebase", util.GetRemoteMainBranchOrDie(), destCommit.Branch)
 		// As we rebased, a force push may be required.
 		forcePush = true
 	}
@@ -122,12 +125,12 @@ func updatePr(appConfig util.AppConfig, destCommit templates.GitLog, commitsToCh
 	}
 	slog.Info("Pushing to remote")
 	if forcePush {
-		if _, err := util.Execute(util.ExecuteOptions{}, "git", "push", "origin", destCommit.Branch); err != nil {
+		if _, err := util.Execute(util.ExecuteOptions{}, "git", "push", util.GetPushRemote(), destCommit.Branch); err != nil {
 			slog.Info("Regular push failed, force pushing instead.")
-			util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "-f", "origin", destCommit.Branch)
+			util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "-f", util.GetPushRemote(), destCommit.Branch)
 		}
 	} else {
-		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", destCommit.Branch)
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", util.GetPushRemote(), destCommit.Branch)
 	}
 	slog.Info("Switching back to " + util.GetMainBranchOrDie())
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "switch", util.GetMainBranchOrDie())
@@ -144,6 +147,18 @@ func updatePr(appConfig util.AppConfig, destCommit templates.GitLog, commitsToCh
 	options := util.ExecuteOptions{EnvironmentVariables: environmentVariables, Io: appConfig.Io}
 	util.ExecuteOrDie(options, "git", "rebase", "-i", destCommit.Commit+"^")
 	rollbackManager.Clear()
+	// The rebase squashed the fixups into the first commit after the parent of destCommit.
+	updatedCommit := strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-list", "--reverse", destCommit.Commit+"^..HEAD"))[0]
+	util.RecordStackEntry(updatedCommit, destCommit.Branch, 0)
+	templates.FollowRewrittenCommits()
+	if slices.ContainsFunc(getStackedCommits(templates.GetNewCommits("HEAD")), func(stackedCommit templates.GitLog) bool {
+		baseBranch, _ := util.GetStackedBase(stackedCommit.Branch)
+		return baseBranch == destCommit.Branch
+	}) {
+		slog.Info("Restacking branches stacked on " + destCommit.Branch)
+		restack(appConfig, []string{})
+	}
+	updateStackSections(templates.GetStack())
 }
 
 func checkNotMerged(appConfig util.AppConfig, branchName string) {
@@ -136,13 +136,7 @@ func TestSdUpdate_WithReviewers_AddReviewers(t *testing.T) {
 
 	allCommits := templates.GetAllCommits()
 
-	testExecutor.SetResponse(
-		// There has to be at least 4 checks, each with 3 values: status, conclusion, and state.
-		"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n"+
-			"SUCCESS\nSUCCESS\nSUCCESS\n",
-		nil, "gh", "pr", "view", util.MatchAnyRemainingArgs)
+	testutil.SetOpenPullRequest(allCommits[1].Branch, 4)
 
 	testParseArguments("update", "--min-checks", "4", "--reviewers=mybestie", "2", "1")
 
@@ -322,7 +316,7 @@ func TestSdUpdate_WhenDestinationCommitNotSpecifiedAndMultiplePossibleValues_Upd
 
 func TestSdUpdate_WhenBranchAlreadyMergedAndUserDoesNotConfirm_Cancels(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 	testutil.AddCommit("first", "")
 
 	testParseArguments("new", "1")
@@ -333,8 +327,7 @@ func TestSdUpdate_WhenBranchAlreadyMergedAndUserDoesNotConfirm_Cancels(t *testin
 
 	// Are you sure you want to update this PR?
 	interactive.SendToProgram(0, interactive.NewMessageRune('n'))
-	testExecutor.SetResponse(allCommits[1].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allCommits[1].Branch, "fakeMergeCommit")
 
 	defer func() {
 		r := recover()
@@ -350,7 +343,7 @@ func TestSdUpdate_WhenBranchAlreadyMergedAndUserDoesNotConfirm_Cancels(t *testin
 
 func TestSdUpdate_WhenBranchAlreadyMergedAndUserConfirms_Updates(t *testing.T) {
 	assert := assert.New(t)
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 	testutil.AddCommit("first", "")
 
 	testParseArguments("new", "1")
@@ -358,8 +351,7 @@ func TestSdUpdate_WhenBranchAlreadyMergedAndUserConfirms_Updates(t *testing.T) {
 	testutil.AddCommit("second", "")
 
 	allCommits := templates.GetAllCommits()
-	testExecutor.SetResponse(allCommits[1].Branch+" fakeMergeCommit",
-		nil, "gh", "pr", "list", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allCommits[1].Branch, "fakeMergeCommit")
 
 	// Are you sure you want to update this PR?
 	interactive.SendToProgram(0, interactive.NewMessageRune('y'))
@@ -3,8 +3,6 @@ package commands
 import (
 	"flag"
 	"log/slog"
-	"strings"
-	"time"
 
 	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
 	"github.com/joshallenit/gh-stacked-diff/v2/templates"
@@ -15,12 +13,13 @@ func createWaitForMergeCommand() Command {
 	flagSet := flag.NewFlagSet("wait-for-merge", flag.ContinueOnError)
 
 	indicatorTypeString := addIndicatorFlag(flagSet)
-	silent := addSilentFlag(flagSet, "")
+	silent := addSilentFlag(flagSet, "the PR has been merged")
 
 	return Command{
 		FlagSet: flagSet,
 		Summary: "Waits for a pull request to be merged",
-		Description: "Waits for a pull request to be merged. Polls PR every 30 seconds.\n" +
+		Description: "Waits for a pull request to be merged. Polls PR every \"poll-frequency\",\n" +
+			"30 seconds by default, see \"sd config\".\n" +
 			"\n" +
 			"Useful for your own custom scripting.",
 		Usage: "sd " + flagSet.Name() + " [flags] <commit hash or pull request number>",
@@ -34,22 +33,23 @@ func createWaitForMergeCommand() Command {
 				MultiSelect: false,
 			}
 			targetCommit := getTargetCommits(asyncConfig.App, command, []string{flagSet.Arg(0)}, indicatorTypeString, selectCommitOptions)
-			waitForMerge(targetCommit[0], *silent)
+			waitForMerge(asyncConfig.App, targetCommit[0], *silent)
 		}}
 }
 
 // Waits for a pull request to be merged.
-func waitForMerge(targetCommit templates.GitLog, silent bool) {
-	for getMergedAt(targetCommit.Branch) == "" {
+func waitForMerge(appConfig util.AppConfig, targetCommit templates.GitLog, silent bool) {
+	for !isMerged(targetCommit.Branch) {
 		slog.Info("Not merged yet...")
-		util.Sleep(30 * time.Second)
+		util.Sleep(util.GetConfigDuration("poll-frequency"))
 	}
 	slog.Info("Merged!")
 	if !silent {
-		util.ExecuteOrDie(util.ExecuteOptions{}, "say", "P R has been merged")
+		util.GetNotifier(appConfig).Notify(util.Notification{Title: "PR has been merged", Message: targetCommit.Subject})
 	}
 }
 
-func getMergedAt(branchName string) string {
-	return strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "gh", "pr", "view", branchName, "--json", "mergedAt", "--jq", ".mergedAt"))
+func isMerged(branchName string) bool {
+	pullRequest, ok := util.GetForge().GetPullRequests([]string{branchName})[branchName]
+	return ok && pullRequest.State == util.PullRequestStateMerged
 }
@@ -14,13 +14,28 @@ import (
 func TestSdWaitForMerge_WaitsForMerge(t *testing.T) {
 	assert := assert.New(t)
 
-	testExecutor := testutil.InitTest(t, slog.LevelError)
+	testutil.InitTest(t, slog.LevelError)
 
 	testutil.AddCommit("first", "")
 	allCommits := templates.GetAllCommits()
-	testExecutor.SetResponse("2025-01-01", nil, "gh", "pr", "view", util.MatchAnyRemainingArgs)
+	testutil.SetMergedPullRequest(allCommits[0].Branch, "fakeMergeCommit")
 
 	out := testParseArguments("--log-level=info", "wait-for-merge", allCommits[0].Commit)
 
 	assert.Contains(out, "Merged!")
+	assert.Equal([]util.Notification{{Title: "PR has been merged", Message: "first"}}, testutil.GetFakeNotifier().Notifications())
+}
+
+func TestSdWaitForMerge_WhenSilent_DoesNotNotify(t *testing.T) {
+	assert := assert.New(t)
+
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	allCommits := templates.GetAllCommits()
+	testutil.SetMergedPullRequest(allCommits[0].Branch, "fakeMergeCommit")
+
+	testParseArguments("wait-for-merge", "--silent", allCommits[0].Commit)
+
+	assert.Empty(testutil.GetFakeNotifier().Notifications())
 }
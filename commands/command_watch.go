@@ -0,0 +1,430 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func createWatchCommand() Command {
+	flagSet := flag.NewFlagSet("watch", flag.ContinueOnError)
+	detach := flagSet.Bool("detach", false, "Run in the background, logging to watch.log in the user cache directory")
+	reviewers := flagSet.String("reviewers", "",
+		"Comma-separated list of Github usernames, teams, or @groups to add\n"+
+			"as reviewers once checks have passed. Reviewers are not added if empty.")
+	rebaseMainAfterMerge := flagSet.Bool("rebase-main", false,
+		"Run \"sd rebase-main\" after a PR is merged, if "+util.GetMainBranchForHelp()+" is checked out\n"+
+			"without uncommitted changes. A rebase with conflicts is aborted.")
+	pollFrequency := flagSet.Duration("poll-frequency", 0,
+		"Frequency which to poll PRs. For valid formats see https://pkg.go.dev/time#ParseDuration")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Watches your PRs in the background and acts on their events",
+		Description: "Watches the PRs of the commits on " + util.GetMainBranchForHelp() + " and notifies you when their checks\n" +
+			"fail, when they are reviewed, and when they are merged, see the \"notifier\"\n" +
+			"config. Optionally adds reviewers once checks pass, like \"sd add-reviewers\",\n" +
+			"and runs \"sd rebase-main\" after a merge.\n" +
+			"\n" +
+			"One watch process watches all your repositories: running \"sd watch\" in\n" +
+			"another repository while it is running adds that repository to it. What\n" +
+			"has been seen is saved, so that you are not notified twice when it is\n" +
+			"restarted.\n" +
+			"\n" +
+			"   status   displays the watched PRs, queried from the watch process\n" +
+			"   stop     stops the watch process",
+		Usage: "sd " + flagSet.Name() + " [flags]\n" +
+			"       sd " + flagSet.Name() + " status\n" +
+			"       sd " + flagSet.Name() + " stop",
+		StructuredOutput: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() > 1 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			if isStructuredOutput(asyncConfig.App) && flagSet.Arg(0) != "status" {
+				commandError(asyncConfig.App, flagSet, "--output is only supported by watch status", command.Usage)
+			}
+			switch flagSet.Arg(0) {
+			case "":
+				repo := util.WatchedRepo{
+					Dir:        strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", "--show-toplevel")),
+					Reviewers:  *reviewers,
+					RebaseMain: *rebaseMainAfterMerge,
+				}
+				watch(asyncConfig.App, repo, *pollFrequency, *detach)
+			case "status":
+				printWatchStatus(asyncConfig.App, requestWatchOrDie(asyncConfig.App, watchRequest{Command: watchCommandStatus}))
+			case "stop":
+				requestWatchOrDie(asyncConfig.App, watchRequest{Command: watchCommandStop})
+				util.Fprintln(asyncConfig.App.Io.Out, "Stopped watching")
+			default:
+				commandError(asyncConfig.App, flagSet, "unknown watch command "+flagSet.Arg(0), command.Usage)
+			}
+		}}
+}
+
+// Values of [watchRequest.Command].
+const (
+	watchCommandAdd    = "add"
+	watchCommandStatus = "status"
+	watchCommandStop   = "stop"
+)
+
+// Request sent to the watch process over its socket, as a line of JSON.
+type watchRequest struct {
+	// One of the watchCommand constants.
+	Command string `json:"command"`
+	// Repository to add, for watchCommandAdd.
+	Repo util.WatchedRepo `json:"repo"`
+}
+
+// Response of the watch process to a [watchRequest].
+type watchResponse struct {
+	Pid           int               `json:"pid"`
+	PollFrequency string            `json:"pollFrequency"`
+	Repos         []watchRepoStatus `json:"repos"`
+}
+
+// Status of a watched repository.
+type watchRepoStatus struct {
+	util.WatchedRepo
+	util.WatchRepoState
+	// Why the last check failed, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// Watches repo, or adds it to the watch process if one is already running.
+func watch(appConfig util.AppConfig, repo util.WatchedRepo, pollFrequency time.Duration, detach bool) {
+	if _, ok := requestWatch(appConfig, watchRequest{Command: watchCommandAdd, Repo: repo}); ok {
+		util.Fprintln(appConfig.Io.Out, "Added "+repo.Dir+" to the running watch process, see \"sd watch status\"")
+		return
+	}
+	if detach {
+		startDetachedWatch(appConfig, repo, pollFrequency)
+		return
+	}
+	addWatchedRepo(appConfig, repo)
+	newWatchDaemon(appConfig, pollFrequency).run()
+}
+
+// Adds or replaces repo in the persisted list of watched repositories.
+func addWatchedRepo(appConfig util.AppConfig, repo util.WatchedRepo) []util.WatchedRepo {
+	repos := slices.DeleteFunc(util.ReadWatchedRepos(appConfig), func(next util.WatchedRepo) bool {
+		return next.Dir == repo.Dir
+	})
+	repos = append(repos, repo)
+	util.WriteWatchedRepos(appConfig, repos)
+	return repos
+}
+
+// Starts "sd watch" as a background process that outlives this one, and waits for it to listen on
+// its socket.
+func startDetachedWatch(appConfig util.AppConfig, repo util.WatchedRepo, pollFrequency time.Duration) {
+	executable, err := os.Executable()
+	if err != nil {
+		panic(fmt.Sprint("Cannot determine executable ", err))
+	}
+	logFilename := filepath.Join(util.GetWatchDir(appConfig), "watch.log")
+	logFile, err := os.OpenFile(logFilename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		panic("Could not open " + logFilename + ": " + err.Error())
+	}
+	defer logFile.Close()
+	args := []string{"watch", "--poll-frequency", pollFrequency.String(), "--reviewers", repo.Reviewers}
+	if repo.RebaseMain {
+		args = append(args, "--rebase-main")
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = repo.Dir
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		panic("Could not start watch process: " + err.Error())
+	}
+	pid := cmd.Process.Pid
+	if err := cmd.Process.Release(); err != nil {
+		panic(err)
+	}
+	for range 50 {
+		if _, ok := requestWatch(appConfig, watchRequest{Command: watchCommandStatus}); ok {
+			util.Fprintln(appConfig.Io.Out, fmt.Sprint("Watching in the background with pid ", pid, ", logging to ", logFilename))
+			return
+		}
+		util.Sleep(100 * time.Millisecond)
+	}
+	panic("Watch process did not start, check " + logFilename)
+}
+
+// Watches the persisted list of repositories, answering requests over a socket.
+type watchDaemon struct {
+	appConfig     util.AppConfig
+	pollFrequency time.Duration
+	// Guards statuses, which are keyed by repository directory.
+	mutex    sync.Mutex
+	statuses map[string]watchRepoStatus
+	// Signals the watch loop to poll now, for example because a repository was added.
+	pollNow chan struct{}
+	stop    context.CancelFunc
+}
+
+func newWatchDaemon(appConfig util.AppConfig, pollFrequency time.Duration) *watchDaemon {
+	return &watchDaemon{
+		appConfig:     appConfig,
+		pollFrequency: pollFrequency,
+		statuses:      make(map[string]watchRepoStatus),
+		pollNow:       make(chan struct{}, 1),
+	}
+}
+
+// Polls the watched repositories every pollFrequency until stopped by a request or an interrupt.
+func (d *watchDaemon) run() {
+	listener := listenWatchSocket(d.appConfig)
+	defer listener.Close()
+	// Keep running when the terminal that started it is closed.
+	signal.Ignore(syscall.SIGHUP)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	d.stop = stop
+	defer stop()
+	go d.serve(listener)
+	slog.Info(fmt.Sprint("Watching with pid ", os.Getpid(), ", checking every ", d.pollFrequency))
+	for {
+		for _, repo := range util.ReadWatchedRepos(d.appConfig) {
+			d.poll(ctx, repo)
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopped watching")
+			return
+		case <-d.pollNow:
+		case <-time.After(d.pollFrequency):
+		}
+	}
+}
+
+// Checks repo with "sd watch-poll" in its own process, so that no state is shared between
+// repositories.
+func (d *watchDaemon) poll(ctx context.Context, repo util.WatchedRepo) {
+	if ctx.Err() != nil {
+		return
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		panic(fmt.Sprint("Cannot determine executable ", err))
+	}
+	args := []string{"--log-level=" + lowestSupportedLogLevel().String(), "watch-poll"}
+	if repo.Reviewers != "" {
+		args = append(args, "--reviewers", repo.Reviewers)
+	}
+	if repo.RebaseMain {
+		args = append(args, "--rebase-main")
+	}
+	slog.Info("Checking " + repo.Dir)
+	var errOut bytes.Buffer
+	options := util.ExecuteOptions{
+		Dir: repo.Dir,
+		Io:  util.StdIo{Out: d.appConfig.Io.Out, Err: io.MultiWriter(d.appConfig.Io.Err, &errOut)},
+	}
+	status := watchRepoStatus{WatchedRepo: repo}
+	if _, err := util.Execute(options, executable, args...); err != nil {
+		status.Error = strings.TrimSpace(errOut.String())
+		if status.Error == "" {
+			status.Error = err.Error()
+		}
+		slog.Warn("Could not check " + repo.Dir + ": " + status.Error)
+	}
+	if _, statErr := os.Stat(repo.Dir); statErr == nil {
+		status.WatchRepoState = util.ReadWatchRepoState(repo.Dir)
+	}
+	d.mutex.Lock()
+	d.statuses[repo.Dir] = status
+	d.mutex.Unlock()
+}
+
+func (d *watchDaemon) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("Could not accept connection: " + err.Error())
+			continue
+		}
+		go d.handle(conn)
+	}
+}
+
+func (d *watchDaemon) handle(conn net.Conn) {
+	defer conn.Close()
+	var request watchRequest
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		slog.Warn("Could not read request: " + err.Error())
+		return
+	}
+	response := d.respond(request)
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		slog.Warn("Could not send response: " + err.Error())
+	}
+}
+
+func (d *watchDaemon) respond(request watchRequest) watchResponse {
+	switch request.Command {
+	case watchCommandAdd:
+		slog.Info("Adding " + request.Repo.Dir)
+		addWatchedRepo(d.appConfig, request.Repo)
+		select {
+		case d.pollNow <- struct{}{}:
+		default:
+		}
+	case watchCommandStop:
+		d.stop()
+	}
+	response := watchResponse{Pid: os.Getpid(), PollFrequency: d.pollFrequency.String(), Repos: []watchRepoStatus{}}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, repo := range util.ReadWatchedRepos(d.appConfig) {
+		status, ok := d.statuses[repo.Dir]
+		if !ok {
+			status = watchRepoStatus{WatchedRepo: repo, WatchRepoState: util.WatchRepoState{PullRequests: []util.WatchedPullRequest{}}}
+		}
+		response.Repos = append(response.Repos, status)
+	}
+	return response
+}
+
+// Listens on the socket of the watch process, replacing the socket of one that did not exit
+// cleanly.
+func listenWatchSocket(appConfig util.AppConfig) net.Listener {
+	socketFilename := getWatchSocketFilename(appConfig)
+	if _, err := os.Stat(socketFilename); err == nil {
+		// Callers first check that the socket does not respond, so it is stale.
+		if err := os.Remove(socketFilename); err != nil {
+			panic("Could not remove stale socket " + socketFilename + ": " + err.Error())
+		}
+	}
+	listener, err := net.Listen("unix", socketFilename)
+	if err != nil {
+		panic("Could not listen on " + socketFilename + ": " + err.Error())
+	}
+	return listener
+}
+
+// Sends request to the watch process. Returns false if it is not running.
+func requestWatch(appConfig util.AppConfig, request watchRequest) (watchResponse, bool) {
+	conn, err := net.DialTimeout("unix", getWatchSocketFilename(appConfig), 5*time.Second)
+	if err != nil {
+		slog.Debug("Watch process is not running: " + err.Error())
+		return watchResponse{}, false
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(30 * time.Second)); err != nil {
+		panic(err)
+	}
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		panic("Could not send request to watch process: " + err.Error())
+	}
+	var response watchResponse
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		panic("Could not read response from watch process: " + err.Error())
+	}
+	return response, true
+}
+
+// Sends request to the watch process. Panics if it is not running.
+func requestWatchOrDie(appConfig util.AppConfig, request watchRequest) watchResponse {
+	response, ok := requestWatch(appConfig, request)
+	if !ok {
+		panic("Not watching, start with \"sd watch\"")
+	}
+	return response
+}
+
+// Returns the socket of the watch process. It is in the temp dir because socket paths are limited
+// to around 100 characters, and named after the watch dir so that it is specific to the user.
+func getWatchSocketFilename(appConfig util.AppConfig) string {
+	hash := sha256.Sum256([]byte(util.GetWatchDir(appConfig)))
+	return filepath.Join(os.TempDir(), "gh-stacked-diff-"+hex.EncodeToString(hash[:6])+".sock")
+}
+
+func printWatchStatus(appConfig util.AppConfig, response watchResponse) {
+	if isStructuredOutput(appConfig) {
+		records := make([]watchOutput, 0)
+		for _, repo := range response.Repos {
+			for _, pullRequest := range repo.PullRequests {
+				records = append(records, watchOutput{
+					Repository:     repo.Dir,
+					Number:         pullRequest.Number,
+					Subject:        pullRequest.Subject,
+					Branch:         pullRequest.Branch,
+					Url:            pullRequest.Url,
+					State:          pullRequest.State,
+					Checks:         pullRequest.Checks,
+					Reviews:        pullRequest.Reviews,
+					ReviewersAdded: pullRequest.ReviewersAdded,
+				})
+			}
+		}
+		printStructuredOutput(appConfig, records, watchOutputHeaders, watchOutput.tsvRow)
+		return
+	}
+	util.Fprintln(appConfig.Io.Out, fmt.Sprint("Watching with pid ", response.Pid, ", checking every ", response.PollFrequency))
+	for _, repo := range response.Repos {
+		util.Fprintln(appConfig.Io.Out, "")
+		util.Fprintln(appConfig.Io.Out, repo.Dir+getWatchedRepoOptions(repo))
+		if repo.Error != "" {
+			util.Fprintln(appConfig.Io.Out, "Last check failed: "+repo.Error)
+		}
+		if repo.LastPoll.IsZero() {
+			util.Fprintln(appConfig.Io.Out, "Not checked yet")
+			continue
+		}
+		if len(repo.PullRequests) == 0 {
+			util.Fprintln(appConfig.Io.Out, "No PRs")
+			continue
+		}
+		writer := tabwriter.NewWriter(appConfig.Io.Out, 0, 0, 2, ' ', 0)
+		util.Fprintln(writer, "PR\tSubject\tState\tChecks\tReviews")
+		for _, pullRequest := range repo.PullRequests {
+			util.Fprintln(writer, fmt.Sprint("#", pullRequest.Number, "\t", pullRequest.Subject, "\t", pullRequest.State, "\t",
+				pullRequest.Checks, "\t", pullRequest.Reviews))
+		}
+		if err := writer.Flush(); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Returns a description of the options of repo, such as " (reviewers: octocat, rebase-main)".
+func getWatchedRepoOptions(repo watchRepoStatus) string {
+	options := make([]string, 0, 2)
+	if repo.Reviewers != "" {
+		options = append(options, "reviewers: "+repo.Reviewers)
+	}
+	if repo.RebaseMain {
+		options = append(options, "rebase-main")
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(options, ", ") + ")"
+}
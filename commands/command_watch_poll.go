@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Values of [util.WatchedPullRequest.Checks].
+const (
+	watchChecksPassing = "passing"
+	watchChecksFailing = "failing"
+	watchChecksPending = "pending"
+)
+
+func createWatchPollCommand() Command {
+	flagSet := flag.NewFlagSet("watch-poll", flag.ContinueOnError)
+	reviewers := flagSet.String("reviewers", "", "Comma-separated list of Github usernames to add as reviewers once checks have passed")
+	rebaseMainAfterMerge := flagSet.Bool("rebase-main", false, "Run rebase-main after a PR is merged")
+	return Command{
+		FlagSet: flagSet,
+		Summary: "Checks the PRs of the repository once, used by watch",
+		Description: "Checks the PRs of the commits on " + util.GetMainBranchForHelp() + " once and acts on any changes since\n" +
+			"the previous check. Used by \"sd watch\" so that each repository is checked\n" +
+			"in its own process.",
+		Usage:  "sd " + flagSet.Name() + " [flags]",
+		Hidden: true,
+		OnSelected: func(asyncConfig util.AsyncAppConfig, command Command) {
+			if flagSet.NArg() != 0 {
+				commandError(asyncConfig.App, flagSet, "too many arguments", command.Usage)
+			}
+			pollWatchedRepo(asyncConfig.App, *reviewers, *rebaseMainAfterMerge)
+		}}
+}
+
+// Checks the PRs of the new commits of the current repository and acts on any changes since the
+// state persisted by the previous check: notifies of failed checks, new reviews, and merges, adds
+// reviewers once checks pass if reviewers is set, and runs rebase-main after a merge if
+// rebaseMainAfterMerge is set.
+func pollWatchedRepo(appConfig util.AppConfig, reviewers string, rebaseMainAfterMerge bool) util.WatchRepoState {
+	previous := util.ReadWatchRepoState("")
+	gitLogs := templates.GetNewCommits(util.GetMainBranchOrDie())
+	existingBranches := getExistingBranches(gitLogs)
+	gitLogs = util.FilterSlice(gitLogs, func(gitLog templates.GitLog) bool {
+		return slices.Contains(existingBranches, gitLog.Branch)
+	})
+	state := util.WatchRepoState{LastPoll: time.Now(), PullRequests: make([]util.WatchedPullRequest, 0, len(gitLogs))}
+	anyMerged := false
+	if len(gitLogs) > 0 {
+		minChecks := util.GetConfigInt("min-checks")
+		if minChecks == -1 {
+			minChecks = util.GetMinChecks()
+		}
+		pullRequests := util.GetForge().GetPullRequests(util.MapSlice(gitLogs, func(gitLog templates.GitLog) string {
+			return gitLog.Branch
+		}))
+		for _, gitLog := range gitLogs {
+			pullRequest, ok := pullRequests[gitLog.Branch]
+			if !ok {
+				continue
+			}
+			watched, merged := updateWatchedPullRequest(appConfig, gitLog, pullRequest, minChecks, previous.PullRequests, reviewers)
+			anyMerged = anyMerged || merged
+			state.PullRequests = append(state.PullRequests, watched)
+		}
+	}
+	// Persist before rebasing so that events are not repeated if the rebase fails.
+	util.WriteWatchRepoState(state)
+	if anyMerged && rebaseMainAfterMerge {
+		rebaseMainAfterWatchedMerge(appConfig)
+	}
+	return state
+}
+
+// Returns the new state of the PR of gitLog, after acting on any changes since its state in
+// previousPullRequests, and whether it was merged since then. A PR that was not watched before is
+// only acted on for its checks, so that its existing reviews, or an old merge, are not notified.
+func updateWatchedPullRequest(appConfig util.AppConfig, gitLog templates.GitLog, pullRequest util.PullRequest, minChecks int, previousPullRequests []util.WatchedPullRequest, reviewers string) (util.WatchedPullRequest, bool) {
+	status := util.NewPullRequestStatus(pullRequest, minChecks)
+	watched := util.WatchedPullRequest{
+		Branch:  gitLog.Branch,
+		Subject: gitLog.Subject,
+		Number:  pullRequest.Number,
+		Url:     pullRequest.Url,
+		State:   status.State.String(),
+		Checks:  getWatchedChecks(status.Checks),
+		Reviews: len(pullRequest.Reviews),
+	}
+	previous := util.WatchedPullRequest{State: watched.State, Checks: watchChecksPending, Reviews: watched.Reviews}
+	if index := slices.IndexFunc(previousPullRequests, func(next util.WatchedPullRequest) bool {
+		return next.Branch == gitLog.Branch
+	}); index != -1 {
+		previous = previousPullRequests[index]
+	}
+	watched.ReviewersAdded = previous.ReviewersAdded
+	merged := watched.State != previous.State && watched.State == util.PullRequestStateMerged.String()
+	if merged {
+		slog.Info(fmt.Sprint("Merged ", gitLog.Subject))
+		util.GetNotifier(appConfig).Notify(util.Notification{Title: "PR has been merged", Message: gitLog.Subject, Url: watched.Url})
+	}
+	if watched.State != util.PullRequestStateOpen.String() {
+		return watched, merged
+	}
+	if watched.Checks != previous.Checks && watched.Checks == watchChecksFailing {
+		slog.Info(fmt.Sprint("Checks failed for ", gitLog.Subject))
+		util.GetNotifier(appConfig).Notify(util.Notification{
+			Title:   "Checks failed",
+			Message: gitLog.Subject + ": " + getCheckNames(status.Checks.FailingChecks()),
+			Url:     watched.Url,
+		})
+	}
+	if watched.Reviews > previous.Reviews {
+		message := strings.Join(util.MapSlice(pullRequest.Reviews[previous.Reviews:], getReviewDescription), ", ")
+		slog.Info(fmt.Sprint("New reviews for ", gitLog.Subject, ": ", message))
+		util.GetNotifier(appConfig).Notify(util.Notification{Title: "New review", Message: message + " on " + gitLog.Subject, Url: watched.Url})
+	}
+	if watched.Checks == watchChecksPassing && reviewers != "" && !watched.ReviewersAdded {
+		requestReviews(appConfig, gitLog, reviewers, util.GetPickReviewersOptions(), false)
+		watched.ReviewersAdded = true
+	}
+	return watched, merged
+}
+
+// Returns one of the watchChecks constants for checks.
+func getWatchedChecks(checks util.PullRequestChecksStatus) string {
+	if checks.IsFailing() {
+		return watchChecksFailing
+	}
+	if checks.IsSuccess() {
+		return watchChecksPassing
+	}
+	return watchChecksPending
+}
+
+// Returns a description of review such as "octocat approved".
+func getReviewDescription(review util.PullRequestReview) string {
+	switch review.State {
+	case "APPROVED":
+		return review.Author + " approved"
+	case "CHANGES_REQUESTED":
+		return review.Author + " requested changes"
+	case "COMMENTED":
+		return review.Author + " commented"
+	default:
+		return review.Author + " " + strings.ToLower(strings.ReplaceAll(review.State, "_", " "))
+	}
+}
+
+// Runs rebase-main, recorded in the journal so that it can be undone, if main is checked out and
+// the user is not in the middle of something. The rebase is aborted if it has a merge conflict,
+// rather than leaving it half done in the user's checkout.
+func rebaseMainAfterWatchedMerge(appConfig util.AppConfig) {
+	if util.GetCurrentBranchName() != util.GetMainBranchOrDie() {
+		slog.Warn("Not running rebase-main because " + util.GetMainBranchOrDie() + " is not checked out")
+		return
+	}
+	if reason, busy := getWorkingTreeBusyReason(); busy {
+		slog.Warn("Not running rebase-main because " + reason)
+		return
+	}
+	slog.Info("Running rebase-main")
+	util.StartJournalEntry("rebase-main")
+	succeeded := rebaseMain(appConfig, false)
+	if !succeeded {
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rebase", "--abort")
+	}
+	util.FinishJournalEntry(appConfig)
+	if !succeeded {
+		util.GetNotifier(appConfig).Notify(util.Notification{
+			Title:   "Rebase aborted",
+			Message: "rebase-main had a merge conflict in " + util.GetRepoName() + ", run \"sd rebase-main\" manually",
+		})
+	}
+}
+
+// Returns why the working tree should not be changed in the background, because the user has
+// uncommitted changes, is in the middle of a rebase, or another git command is running.
+func getWorkingTreeBusyReason() (string, bool) {
+	if util.IsRebaseInProgress() {
+		return "a rebase is in progress", true
+	}
+	indexLock := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", "--git-path", "index.lock"))
+	if _, err := os.Stat(indexLock); err == nil {
+		return "another git command is running (" + indexLock + " exists)", true
+	}
+	if strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "status", "--porcelain")) != "" {
+		return "there are uncommitted changes", true
+	}
+	return "", false
+}
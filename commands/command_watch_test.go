@@ -0,0 +1,166 @@
+package commands
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/testutil"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+func TestSdWatchPoll_WhenChecksFail_NotifiesOnce(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	setFailingChecksPullRequest(allCommits[0].Branch)
+
+	testParseArguments("watch-poll")
+	testParseArguments("watch-poll")
+
+	assert.Equal([]util.Notification{{Title: "Checks failed", Message: "first: test"}}, testutil.GetFakeNotifier().Notifications())
+	state := util.ReadWatchRepoState("")
+	assert.Equal(1, len(state.PullRequests))
+	assert.Equal(watchChecksFailing, state.PullRequests[0].Checks)
+}
+
+func TestSdWatchPoll_WhenMergedAndReviewed_Notifies(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 0)
+	testParseArguments("watch-poll")
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 0, "mybestie")
+	testParseArguments("watch-poll")
+	testutil.SetMergedPullRequest(allCommits[0].Branch, "fakeMergeCommit")
+	testParseArguments("watch-poll")
+
+	assert.Equal([]util.Notification{
+		{Title: "New review", Message: "mybestie approved on first"},
+		{Title: "PR has been merged", Message: "first"},
+	}, testutil.GetFakeNotifier().Notifications())
+}
+
+func TestSdWatchPoll_WithReviewers_AddsReviewersOnceChecksPass(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 4)
+
+	testParseArguments("watch-poll", "--reviewers=mybestie")
+	testParseArguments("watch-poll", "--reviewers=mybestie")
+
+	addReviewers := util.FilterSlice(testExecutor.Responses, func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "gh" && slices.Equal(next.Args, []string{"pr", "edit", allCommits[0].Branch, "--add-reviewer", "mybestie"})
+	})
+	assert.Equal(1, len(addReviewers))
+	assert.True(util.ReadWatchRepoState("").PullRequests[0].ReviewersAdded)
+}
+
+func TestSdWatchPoll_WithRebaseMainAndUncommittedChanges_DoesNotRebase(t *testing.T) {
+	assert := assert.New(t)
+	testExecutor := testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 0)
+	testParseArguments("watch-poll")
+	testutil.SetMergedPullRequest(allCommits[0].Branch, "fakeMergeCommit")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "touch", "uncommitted")
+	responsesBefore := len(testExecutor.Responses)
+
+	testParseArguments("watch-poll", "--rebase-main")
+
+	assert.False(slices.ContainsFunc(testExecutor.Responses[responsesBefore:], func(next util.ExecutedResponse) bool {
+		return next.ProgramName == "git" && (next.Args[0] == "stash" || next.Args[0] == "rebase")
+	}))
+	assert.Equal("first", templates.GetAllCommits()[0].Subject)
+}
+
+func TestSdWatchPoll_WithRebaseMainAndConflict_AbortsRebase(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "file-with-conflicts")
+	testutil.CommitFileChange("second", "file-with-conflicts", "1")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "push", "origin", util.GetMainBranchOrDie())
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", templates.GetAllCommits()[1].Commit)
+	testutil.CommitFileChange("third", "file-with-conflicts", "2")
+	testutil.CommitFileChange("fourth", "file-with-conflicts", "3")
+	testParseArguments("new", "2")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[1].Branch, 0)
+	testParseArguments("watch-poll")
+	testutil.SetMergedPullRequest(allCommits[1].Branch, "fakeMergeCommit")
+
+	testParseArguments("watch-poll", "--rebase-main")
+
+	assert.False(util.IsRebaseInProgress())
+	assert.Equal(util.GetMainBranchOrDie(), util.GetCurrentBranchName())
+	assert.Contains(testutil.GetFakeNotifier().Notifications(), util.Notification{
+		Title:   "Rebase aborted",
+		Message: "rebase-main had a merge conflict in " + util.GetRepoName() + ", run \"sd rebase-main\" manually",
+	})
+}
+
+func TestSdWatch_Status_QueriesWatchProcess(t *testing.T) {
+	assert := assert.New(t)
+	testutil.InitTest(t, slog.LevelError)
+
+	testutil.AddCommit("first", "")
+	testParseArguments("new", "1")
+	allCommits := templates.GetAllCommits()
+	testutil.SetOpenPullRequest(allCommits[0].Branch, 0)
+	testParseArguments("watch-poll")
+
+	appConfig := util.AppConfig{UserCacheDir: getTestAppCacheDir()}
+	repo := util.WatchedRepo{
+		Dir:       strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", "--show-toplevel")),
+		Reviewers: "mybestie",
+	}
+	addWatchedRepo(appConfig, repo)
+	t.Cleanup(func() {
+		// The cache dir is shared by all tests.
+		if err := os.Remove(filepath.Join(util.GetWatchDir(appConfig), "repos.json")); err != nil {
+			panic(err)
+		}
+	})
+	daemon := newWatchDaemon(appConfig, time.Minute)
+	daemon.statuses[repo.Dir] = watchRepoStatus{WatchedRepo: repo, WatchRepoState: util.ReadWatchRepoState("")}
+	listener := listenWatchSocket(appConfig)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+	go daemon.serve(listener)
+
+	out := testParseArguments("watch", "status")
+
+	assert.Contains(out, "checking every 1m0s")
+	assert.Contains(out, repo.Dir+" (reviewers: mybestie)")
+	assert.Regexp("#0 +first +open +pending +0", out)
+}
+
+func TestSdWatch_Status_WhenNotRunning_Panics(t *testing.T) {
+	testutil.InitTest(t, slog.LevelError)
+
+	assert.Panics(t, func() {
+		testParseArguments("watch", "status")
+	})
+}
@@ -2,9 +2,10 @@ package commands
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"os"
-	"runtime"
+	"slices"
 
 	"github.com/fatih/color"
 	"github.com/joshallenit/gh-stacked-diff/v2/templates"
@@ -34,27 +35,85 @@ func checkIndicatorFlag(appConfig util.AppConfig, command Command, indicatorType
 
 func addReviewersFlags(flagSet *flag.FlagSet) (*string, *bool, *int) {
 	reviewers := flagSet.String("reviewers", "",
-		"Comma-separated list of Github usernames to add as reviewers once\n"+
-			"checks have passed.")
+		"Comma-separated list of Github usernames, teams such as org/team, or\n"+
+			"groups such as @web, see \"reviewer-groups\" config, to add as\n"+
+			"reviewers once checks have passed.")
 	silent := addSilentFlag(flagSet, "reviewers have been added")
-	minChecks := flagSet.Int("min-checks", -1,
+	minChecks := flagSet.Int("min-checks", 0,
 		"Minimum number of checks to wait for before verifying that checks\n"+
 			"have passed before adding reviewers. It takes some time for checks\n"+
 			"to be added to a PR by Github, and if you add-reviewers too soon it\n"+
-			"will think that they have all passed. Default of -1 means to use 4 \n"+
-			"or the average number of checks of merged PRs, whatever is less.")
+			"will think that they have all passed. Defaults to the \"min-checks\"\n"+
+			"config, where -1 means to use 4 or the average number of checks of\n"+
+			"merged PRs, whatever is less.")
 	return reviewers, silent, minChecks
 }
 
+// Adds the flags to pick some of the reviewers for each PR, see [util.PickReviewers].
+func addPickReviewersFlags(flagSet *flag.FlagSet) (*int, *string) {
+	count := flagSet.Int("pick-reviewers", 0,
+		"Number of reviewers to pick from \"--reviewers\" for each PR instead\n"+
+			"of adding all of them. Default of 0 means to add all of them.")
+	strategy := flagSet.String("pick-strategy", "",
+		"How to pick reviewers when \"--pick-reviewers\" is set:\n"+
+			"   round-robin       the least recently picked, across runs\n"+
+			"   fewest-requests   the fewest open review requests on Github\n"+
+			"   random            at random\n")
+	return count, strategy
+}
+
+func checkPickReviewersFlags(appConfig util.AppConfig, command Command, count *int, strategy *string) util.PickReviewersOptions {
+	if !util.IsValidPickStrategy(*strategy) {
+		commandError(appConfig, command.FlagSet, "Invalid pick strategy: "+*strategy, command.Usage)
+	}
+	return util.PickReviewersOptions{Count: *count, Strategy: *strategy}
+}
+
 func addSilentFlag(flagSet *flag.FlagSet, usageUseCase string) *bool {
-	if runtime.GOOS == "darwin" {
-		// Only supported on Mac OS X because it uses "say" command.
-		return flagSet.Bool("silent", false, "Whether to use voice output (false) or be silent (true) to notify that "+usageUseCase+".")
-	} else {
-		silent := new(bool)
-		*silent = true
-		return silent
+	return flagSet.Bool("silent", false,
+		"Whether to be silent (true) instead of notifying that "+usageUseCase+".\n"+
+			"See the \"notifier\" config for how notifications are sent.")
+}
+
+// Config settings that flags default to, keyed by flag name, for flags that are not named after
+// their config setting. Any other flag named after a config setting defaults to that setting.
+var flagConfigKeys = map[string]string{
+	"method": "merge-method",
+}
+
+// Returns the config setting that flag defaults to, or false if it does not default to one.
+func getFlagConfigKey(flag *flag.Flag) (util.ConfigKey, bool) {
+	name := flag.Name
+	if configName, ok := flagConfigKeys[name]; ok {
+		name = configName
+	}
+	index := slices.IndexFunc(util.GetConfigKeys(), func(key util.ConfigKey) bool {
+		return key.Name == name
+	})
+	if index == -1 {
+		return util.ConfigKey{}, false
 	}
+	return util.GetConfigKeys()[index], true
+}
+
+// Sets the flags of flagSet that were not set on the command line, and that default to a config
+// setting, to the value of that setting. Flags are registered with zero values so that config files
+// are only read by the command that is run, after its flags are parsed.
+func applyConfigFlags(flagSet *flag.FlagSet) {
+	setFlags := make(map[string]bool)
+	flagSet.Visit(func(flag *flag.Flag) {
+		setFlags[flag.Name] = true
+	})
+	flagSet.VisitAll(func(flag *flag.Flag) {
+		key, ok := getFlagConfigKey(flag)
+		if !ok || setFlags[flag.Name] {
+			return
+		}
+		value := util.GetConfigValue(key.Name)
+		if err := flag.Value.Set(value.Value); err != nil {
+			panic(fmt.Sprint("Invalid value for ", key.Name, " from ", value.SourceDescription(), ": ", err))
+		}
+	})
 }
 
 func commandHelp(appConfig util.AppConfig, flagSet *flag.FlagSet, description string, usage string, isError bool) {
@@ -91,6 +150,13 @@ func printUsage(flagSet *flag.FlagSet, usage string, out io.Writer) {
 		util.Fprintln(out, "")
 		util.Fprintln(out, "flags:")
 		util.Fprintln(out, "")
+		// Show the default of the config setting rather than the zero value that the flag has
+		// until applyConfigFlags, without reading the config files.
+		flagSet.VisitAll(func(flag *flag.Flag) {
+			if key, ok := getFlagConfigKey(flag); ok {
+				flag.DefValue = key.Default
+			}
+		})
 		flagSet.SetOutput(out)
 		flagSet.PrintDefaults()
 		flagSet.SetOutput(io.Discard)
@@ -0,0 +1,12 @@
+package commands
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Starts cmd in a new process group so that it is not stopped along with the console that started
+// it.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
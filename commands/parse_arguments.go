@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
 )
 
@@ -39,6 +40,13 @@ func parseArguments(appConfig util.AppConfig, commandLine *flag.FlagSet, command
 			"   error\n"+
 			"Default is info, except on commands that are for output purposes,\n"+
 			"(namely branch-name and log), which have a default of error.")
+	outputString := commandLine.String("output", string(util.OutputFormatText),
+		"Output format of read-only commands:\n"+
+			"   text   human readable\n"+
+			"   json   JSON array of records with a stable schema\n"+
+			"   tsv    tab separated values with a header row\n"+
+			"Supported by branch-name, checks, code-owners, config, log, prs, status, and\n"+
+			"watch status.")
 	parseErr := commandLine.Parse(commandLineArgs)
 	var logLevelVar *slog.LevelVar
 	if parseErr == nil {
@@ -52,23 +60,7 @@ func parseArguments(appConfig util.AppConfig, commandLine *flag.FlagSet, command
 	}
 	// parseErr is dealt with below via commandError and commandHelp.
 
-	commands := []Command{
-		createAddReviewersCommand(),
-		createBranchNameCommand(),
-		createCheckoutCommand(),
-		createCodeOwnersCommand(),
-		createDropAlreadyMergedCommand(),
-		createLogCommand(),
-		createMarkAsFixupCommand(),
-		createNewCommand(),
-		createPrsCommand(),
-		createRebaseMainCommand(),
-		createReplaceCommitCommand(),
-		createReplaceConflictsCommand(),
-		createUpdateCommand(),
-		createVersionCommand(),
-		createWaitForMergeCommand(),
-	}
+	commands := newCommands()
 
 	commandLineDescription := "Stacked Diff Workflow"
 	commandLineUsage := "sd [top-level-flags] <command> [<args>]\n" +
@@ -110,6 +102,15 @@ func parseArguments(appConfig util.AppConfig, commandLine *flag.FlagSet, command
 		}
 	}
 
+	appConfig.Output = util.OutputFormat(*outputString)
+	if !appConfig.Output.IsValid() {
+		commandError(appConfig, commandLine, "invalid output format "+*outputString, commandLineUsage)
+	}
+	if appConfig.Output != util.OutputFormatText && !commands[selectedIndex].StructuredOutput {
+		commandError(appConfig, commands[selectedIndex].FlagSet,
+			"--output "+*outputString+" is not supported by "+commands[selectedIndex].FlagSet.Name(), commands[selectedIndex].Usage)
+	}
+
 	if *logLevelString == "" {
 		logLevelVar.Set(commands[selectedIndex].DefaultLogLevel)
 	}
@@ -128,8 +129,59 @@ func parseArguments(appConfig util.AppConfig, commandLine *flag.FlagSet, command
 	slog.Debug("User cache dir: " + appConfig.UserCacheDir)
 	// Note: call GetMainBranchOrDie early as it has useful error messages.
 	slog.Debug(fmt.Sprint("Using main branch " + util.GetMainBranchOrDie()))
+	applyConfigFlags(commands[selectedIndex].FlagSet)
 	asyncConfig := util.AsyncAppConfig{App: appConfig, GracefulRecover: recoverFunc}
+	// Read the stack store and templates again in case another command of the same process, such as
+	// in tests, or git itself changed them since they were read.
+	util.ResetStackStores()
+	templates.ResetTemplates()
+	if commands[selectedIndex].Mutating {
+		util.StartJournalEntry(strings.Join(commandLine.Args(), " "))
+	}
 	commands[selectedIndex].OnSelected(asyncConfig, commands[selectedIndex])
+	if commands[selectedIndex].Mutating {
+		util.FinishJournalEntry(appConfig)
+	}
+}
+
+// Returns all the sd commands.
+func newCommands() []Command {
+	return []Command{
+		createAbsorbCommand(),
+		createAddChangeIdsCommand(),
+		createAddReviewersCommand(),
+		createAmendPrCommand(),
+		createBranchNameCommand(),
+		createCheckoutCommand(),
+		createChecksCommand(),
+		createCodeOwnersCommand(),
+		createConfigCommand(),
+		createDashboardCommand(),
+		createDropAlreadyMergedCommand(),
+		createLandCommand(),
+		createLogCommand(),
+		createMarkAsFixupCommand(),
+		createMigrateChangeIdsCommand(),
+		createMoveCommand(),
+		createNewCommand(),
+		createPrsCommand(),
+		createRebaseMainCommand(),
+		createReorderCommand(),
+		createReplaceCommitCommand(),
+		createReplaceConflictsCommand(),
+		createRestackCommand(),
+		createSplitCommand(),
+		createSquashCommand(),
+		createStatusCommand(),
+		createSubmitCommand(),
+		createTemplatesCommand(),
+		createUndoCommand(),
+		createUpdateCommand(),
+		createVersionCommand(),
+		createWaitForMergeCommand(),
+		createWatchCommand(),
+		createWatchPollCommand(),
+	}
 }
 
 func getCommandSummaries(commands []Command) []string {
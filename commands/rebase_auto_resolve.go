@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// How a commit with merge conflicts was resolved by [autoResolveRebase].
+type autoResolution struct {
+	commit  string
+	subject string
+	// Empty if no strategy resolved the commit.
+	strategy string
+}
+
+// A way of resolving the merge conflicts of a commit. Returns a description of how the conflicts
+// were resolved, or false if the strategy could not resolve them.
+type autoResolveStrategy func(gitLog templates.GitLog) (string, bool)
+
+// Returns the arguments for git that enable "git rerere" so that conflicts it has seen before are
+// resolved with its recorded resolution.
+func rerereArgs(autoResolve bool) []string {
+	if !autoResolve {
+		return []string{}
+	}
+	return []string{"-c", "rerere.enabled=true"}
+}
+
+// Resolves the merge conflicts of each commit of the rebase that is in progress, continuing the
+// rebase until it is done or a commit could not be resolved. Returns false if the rebase still has
+// to be continued manually.
+func autoResolveRebase(appConfig util.AppConfig) bool {
+	strategies := []autoResolveStrategy{resolveWithBranch, resolveWithRerere}
+	var resolutions []autoResolution
+	defer func() {
+		printAutoResolutions(appConfig.Io, resolutions)
+	}()
+	for util.IsRebaseInProgress() {
+		commit := getCommitWithConflicts()
+		if len(resolutions) > 0 && resolutions[len(resolutions)-1].commit == commit {
+			// The strategy did not resolve all the conflicts, leave the rest to the user.
+			resolutions[len(resolutions)-1].strategy += " (incomplete)"
+			return false
+		}
+		gitLog := templates.GetBranchInfo(commit, templates.IndicatorTypeCommit)
+		resolution := autoResolution{commit: commit, subject: gitLog.Subject}
+		for _, strategy := range strategies {
+			if description, ok := strategy(gitLog); ok {
+				resolution.strategy = description
+				break
+			}
+		}
+		resolutions = append(resolutions, resolution)
+		if resolution.strategy == "" {
+			slog.Warn(fmt.Sprint("Could not resolve merge conflicts of ", commit, " ", gitLog.Subject,
+				" automatically"))
+			return false
+		}
+		slog.Info(fmt.Sprint("Resolved merge conflicts of ", commit, " ", gitLog.Subject, " with ",
+			resolution.strategy))
+		continueOptions := util.ExecuteOptions{EnvironmentVariables: []string{"GIT_EDITOR=true"}, Io: appConfig.Io}
+		// An error means that the next commit has conflicts, which is checked by the next iteration.
+		// nolint:errcheck
+		util.Execute(continueOptions, "git", append(rerereArgs(true), "rebase", "--continue")...)
+	}
+	return true
+}
+
+// Replaces the changes of gitLog with those of its associated branch, the same as
+// replace-conflicts. Only used if the branch already has the latest changes from origin/main,
+// otherwise its diff would revert them.
+func resolveWithBranch(gitLog templates.GitLog) (string, bool) {
+	if !util.GetLocalHasBranchOrDie(gitLog.Branch) {
+		return "", false
+	}
+	if _, err := util.Execute(util.ExecuteOptions{}, "git", "merge-base", "--is-ancestor",
+		util.GetRemoteMainBranchOrDie(), gitLog.Branch); err != nil {
+		slog.Debug("Branch " + gitLog.Branch + " does not have the latest changes, not using its diff")
+		return "", false
+	}
+	diff := getBranchDiff(gitLog.Branch)
+	if diff == "" {
+		return "", false
+	}
+	// The working tree has the merge conflicts, so check whether the diff applies with a separate
+	// index of HEAD.
+	indexDir, err := os.MkdirTemp("", "sd-auto-resolve")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(indexDir)
+	indexOptions := util.ExecuteOptions{
+		EnvironmentVariables: []string{"GIT_INDEX_FILE=" + filepath.Join(indexDir, "index")},
+	}
+	util.ExecuteOrDie(indexOptions, "git", "read-tree", "HEAD")
+	indexOptions.Io = util.StdIo{In: strings.NewReader(diff)}
+	if _, err := util.Execute(indexOptions, "git", "apply", "--cached", "--check"); err != nil {
+		slog.Debug(fmt.Sprint("Diff of ", gitLog.Branch, " does not apply: ", err))
+		return "", false
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "reset", "--hard", "HEAD")
+	util.ExecuteOrDie(util.ExecuteOptions{Io: util.StdIo{In: strings.NewReader(diff)}}, "git", "apply", "--index")
+	return "branch " + gitLog.Branch, true
+}
+
+// Resolves the conflicts of gitLog with the resolutions recorded by "git rerere".
+func resolveWithRerere(gitLog templates.GitLog) (string, bool) {
+	conflicts := strings.Fields(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "diff", "--name-only", "--diff-filter=U"))
+	if len(conflicts) == 0 {
+		return "", false
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", append(rerereArgs(true), "rerere")...)
+	remaining := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", append(rerereArgs(true), "rerere", "remaining")...))
+	if remaining != "" {
+		slog.Debug("No recorded resolution for " + remaining)
+		return "", false
+	}
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", append([]string{"add", "--"}, conflicts...)...)
+	return "rerere", true
+}
+
+func printAutoResolutions(stdIo util.StdIo, resolutions []autoResolution) {
+	if len(resolutions) == 0 {
+		return
+	}
+	writer := tabwriter.NewWriter(stdIo.Out, 0, 0, 3, ' ', 0)
+	util.Fprintln(writer, "Commit\tSubject\tResolved with")
+	for _, resolution := range resolutions {
+		strategy := resolution.strategy
+		if strategy == "" {
+			strategy = "(not resolved, resolve manually)"
+		}
+		util.Fprintln(writer, resolution.commit+"\t"+resolution.subject+"\t"+strategy)
+	}
+	if err := writer.Flush(); err != nil {
+		panic(err)
+	}
+}
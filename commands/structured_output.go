@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Record of a commit as output by "sd log --output" and "sd status --output".
+// Fields are never removed or renamed so that scripts can rely on them.
+type commitOutput struct {
+	// List index as used by commitIndicator, 1 based.
+	Index   int    `json:"index"`
+	Commit  string `json:"commit"`
+	Subject string `json:"subject"`
+	Branch  string `json:"branch"`
+	// Whether the branch exists locally, which in this workflow means that there is a PR.
+	HasBranch bool `json:"hasBranch"`
+	// Zero if there is no PR, or if it is not known.
+	PrNumber int    `json:"prNumber"`
+	PrUrl    string `json:"prUrl"`
+	// One of "open", "merged", "closed", or empty if there is no PR.
+	State     string       `json:"state"`
+	Checks    checksOutput `json:"checks"`
+	Approvers []string     `json:"approvers"`
+	Owners    []string     `json:"owners"`
+}
+
+type checksOutput struct {
+	Passing int `json:"passing"`
+	Failing int `json:"failing"`
+	Pending int `json:"pending"`
+	Total   int `json:"total"`
+}
+
+var commitOutputHeaders = []string{
+	"index", "commit", "subject", "branch", "hasBranch", "prNumber", "prUrl", "state",
+	"checksPassing", "checksFailing", "checksPending", "checksTotal", "approvers", "owners",
+}
+
+func (record commitOutput) tsvRow() []string {
+	return []string{
+		fmt.Sprint(record.Index), record.Commit, record.Subject, record.Branch, fmt.Sprint(record.HasBranch),
+		fmt.Sprint(record.PrNumber), record.PrUrl, record.State,
+		fmt.Sprint(record.Checks.Passing), fmt.Sprint(record.Checks.Failing),
+		fmt.Sprint(record.Checks.Pending), fmt.Sprint(record.Checks.Total),
+		strings.Join(record.Approvers, ","), strings.Join(record.Owners, ","),
+	}
+}
+
+// Record of a PR as output by "sd prs --output".
+type prOutput struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Branch string `json:"branch"`
+	Url    string `json:"url"`
+	// One of "open", "merged", or "closed".
+	State   string `json:"state"`
+	IsDraft bool   `json:"isDraft"`
+}
+
+var prOutputHeaders = []string{"number", "title", "branch", "url", "state", "isDraft"}
+
+func (record prOutput) tsvRow() []string {
+	return []string{fmt.Sprint(record.Number), record.Title, record.Branch, record.Url, record.State, fmt.Sprint(record.IsDraft)}
+}
+
+// Record of a check of a PR as output by "sd checks --output".
+type checkOutput struct {
+	Name string `json:"name"`
+	// For example "SUCCESS", "FAILURE", or "IN_PROGRESS".
+	State string `json:"state"`
+	Url   string `json:"url"`
+	// Empty if the check was not run by Github Actions or GitLab CI.
+	RunId string `json:"runId"`
+}
+
+var checkOutputHeaders = []string{"name", "state", "url", "runId"}
+
+func (record checkOutput) tsvRow() []string {
+	return []string{record.Name, record.State, record.Url, record.RunId}
+}
+
+// Record of a commit as output by "sd branch-name --output".
+type branchNameOutput struct {
+	Commit  string `json:"commit"`
+	Subject string `json:"subject"`
+	Branch  string `json:"branch"`
+}
+
+var branchNameOutputHeaders = []string{"commit", "subject", "branch"}
+
+func (record branchNameOutput) tsvRow() []string {
+	return []string{record.Commit, record.Subject, record.Branch}
+}
+
+// Record of an owner as output by "sd code-owners --output".
+type codeOwnersOutput struct {
+	// Comma separated owners of the files, or "unowned".
+	Owner string   `json:"owner"`
+	Files []string `json:"files"`
+}
+
+var codeOwnersOutputHeaders = []string{"owner", "files"}
+
+func (record codeOwnersOutput) tsvRow() []string {
+	return []string{record.Owner, strings.Join(record.Files, ",")}
+}
+
+// Record of an owner of the files changed by a commit, as output by
+// "sd code-owners --output <format> commitIndicator".
+type commitCodeOwnersOutput struct {
+	Commit  string `json:"commit"`
+	Subject string `json:"subject"`
+	// Branch whose changed files were used, empty if the commit does not have one.
+	Branch string `json:"branch"`
+	// Comma separated owners of the files, prefixed by their GitLab section if any, or "unowned".
+	Owner string   `json:"owner"`
+	Files []string `json:"files"`
+	// Owners that have approved the latest commit of the PR.
+	ApprovedBy []string `json:"approvedBy"`
+	// One of "approved", "missing", or "optional". Empty if the commit does not have a PR or the
+	// files are unowned.
+	Approval string `json:"approval"`
+}
+
+var commitCodeOwnersOutputHeaders = []string{"commit", "subject", "branch", "owner", "files", "approvedBy", "approval"}
+
+func (record commitCodeOwnersOutput) tsvRow() []string {
+	return []string{record.Commit, record.Subject, record.Branch, record.Owner, strings.Join(record.Files, ","),
+		strings.Join(record.ApprovedBy, ","), record.Approval}
+}
+
+// Record of a config value as output by "sd config list --output".
+type configOutput struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// One of "default", "repo", "user", or "env".
+	Source string `json:"source"`
+	// File or environment variable that the value came from. Empty for defaults.
+	Location string `json:"location"`
+}
+
+var configOutputHeaders = []string{"key", "value", "source", "location"}
+
+func (record configOutput) tsvRow() []string {
+	return []string{record.Key, record.Value, record.Source, record.Location}
+}
+
+// Record of a watched PR as output by "sd watch status --output".
+type watchOutput struct {
+	// Root directory of the repository of the PR.
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+	Subject    string `json:"subject"`
+	Branch     string `json:"branch"`
+	Url        string `json:"url"`
+	// One of "open", "merged", or "closed".
+	State string `json:"state"`
+	// One of "passing", "failing", or "pending".
+	Checks         string `json:"checks"`
+	Reviews        int    `json:"reviews"`
+	ReviewersAdded bool   `json:"reviewersAdded"`
+}
+
+var watchOutputHeaders = []string{"repository", "number", "subject", "branch", "url", "state", "checks", "reviews", "reviewersAdded"}
+
+func (record watchOutput) tsvRow() []string {
+	return []string{
+		record.Repository, fmt.Sprint(record.Number), record.Subject, record.Branch, record.Url, record.State,
+		record.Checks, fmt.Sprint(record.Reviews), fmt.Sprint(record.ReviewersAdded),
+	}
+}
+
+// Prints records as a JSON array, or as tab separated values with headers as the first row.
+func printStructuredOutput[T any](appConfig util.AppConfig, records []T, headers []string, toRow func(T) []string) {
+	switch appConfig.Output {
+	case util.OutputFormatJson:
+		encoder := json.NewEncoder(appConfig.Io.Out)
+		encoder.SetIndent("", "  ")
+		if records == nil {
+			records = []T{}
+		}
+		if err := encoder.Encode(records); err != nil {
+			panic(err)
+		}
+	case util.OutputFormatTsv:
+		util.Fprintln(appConfig.Io.Out, strings.Join(headers, "\t"))
+		for _, record := range records {
+			row := util.MapSlice(toRow(record), func(value string) string {
+				// Keep each record on a single line.
+				return strings.NewReplacer("\t", " ", "\n", " ", "\r", "").Replace(value)
+			})
+			util.Fprintln(appConfig.Io.Out, strings.Join(row, "\t"))
+		}
+	default:
+		panic("Not a structured output format: " + string(appConfig.Output))
+	}
+}
+
+// Returns whether appConfig is set to output JSON or TSV instead of text.
+func isStructuredOutput(appConfig util.AppConfig) bool {
+	return appConfig.Output == util.OutputFormatJson || appConfig.Output == util.OutputFormatTsv
+}
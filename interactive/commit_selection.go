@@ -23,6 +23,8 @@ type CommitSelectionOptions struct {
 	CommitType  CommitType
 	MultiSelect bool
 	Prompt      string
+	// Commits that cannot be selected, such as the commit being moved by "sd move".
+	DisabledCommits []string
 }
 
 // Returns an empty array if user cancelled.
@@ -39,6 +41,9 @@ func GetCommitSelection(stdIo util.StdIo, options CommitSelectionOptions) ([]tem
 	rows := make([][]string, 0, len(newCommits))
 
 	rowEnabled := func(row int) bool {
+		if slices.Contains(options.DisabledCommits, newCommits[row].Commit) {
+			return false
+		}
 		if options.CommitType == CommitTypeBoth {
 			return true
 		}
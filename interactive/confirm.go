@@ -32,10 +32,15 @@ func (m confirmModel) View() string {
 	return promptStyle.Render(m.prompt) + " (y/n): "
 }
 
-func ConfirmOrDie(appConfig util.AppConfig, prompt string) {
+// Returns whether the user answered yes to prompt.
+func Confirm(appConfig util.AppConfig, prompt string) bool {
 	initialModel := confirmModel{prompt: prompt}
 	finalModel := runProgram(appConfig.Io, newProgram(initialModel, appConfig.Io))
-	if !finalModel.(confirmModel).confirmed {
+	return finalModel.(confirmModel).confirmed
+}
+
+func ConfirmOrDie(appConfig util.AppConfig, prompt string) {
+	if !Confirm(appConfig, prompt) {
 		appConfig.Exit(0)
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
@@ -12,6 +13,41 @@ import (
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
 )
 
+// Action that the user selected from the dashboard.
+type DashboardAction string
+
+const (
+	// User quit the dashboard without selecting an action.
+	DashboardActionNone          DashboardAction = ""
+	DashboardActionNew           DashboardAction = "new"
+	DashboardActionUpdate        DashboardAction = "update"
+	DashboardActionAddReviewers  DashboardAction = "add-reviewers"
+	DashboardActionCheckout      DashboardAction = "checkout"
+	DashboardActionReplaceCommit DashboardAction = "replace-commit"
+)
+
+// Returned by [ShowDashboard].
+type DashboardSelection struct {
+	Action DashboardAction
+	// Commit of the highlighted row when the action was selected.
+	Commit templates.GitLog
+}
+
+type dashboardKeyAction struct {
+	key    string
+	action DashboardAction
+	// Whether the action requires the commit to have a PR, or requires it to not have one.
+	requiresPr bool
+}
+
+var dashboardKeyActions = []dashboardKeyAction{
+	{key: "n", action: DashboardActionNew, requiresPr: false},
+	{key: "u", action: DashboardActionUpdate, requiresPr: true},
+	{key: "a", action: DashboardActionAddReviewers, requiresPr: true},
+	{key: "c", action: DashboardActionCheckout, requiresPr: true},
+	{key: "r", action: DashboardActionReplaceCommit, requiresPr: true},
+}
+
 type dashboardRow struct {
 	index  string
 	pr     bool
@@ -20,13 +56,18 @@ type dashboardRow struct {
 }
 
 type dashboardModel struct {
-	spinner spinner.Model
-	table   table.Model
-	rows    []dashboardRow
+	spinner       spinner.Model
+	table         table.Model
+	rows          []dashboardRow
+	selection     DashboardSelection
+	completed     bool
+	lastRefresh   time.Time
+	pollFrequency time.Duration
+	fetchStatuses func(rows []dashboardRow) tea.Cmd
 }
 
 func (m dashboardModel) Init() tea.Cmd {
-	return m.spinner.Tick
+	return tea.Batch(m.spinner.Tick, m.fetchStatuses(slices.Clone(m.rows)))
 }
 
 func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -34,10 +75,26 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "Q", "esc", "ctrl+c":
+			m.completed = true
+			return m, tea.Quit
+		}
+		if keyAction, ok := m.getKeyAction(msg.String()); ok {
+			m.selection = DashboardSelection{Action: keyAction.action, Commit: m.rows[m.cursor()].log}
+			m.completed = true
 			return m, tea.Quit
 		}
-	case updateDashboardRowMsg:
-		m.rows[msg.index] = msg.row
+	case updateDashboardStatusesMsg:
+		for i, status := range msg.statuses {
+			m.rows[i].status = &status
+		}
+		m.lastRefresh = msg.refreshed
+		return m, tea.Tick(m.pollFrequency, func(time.Time) tea.Msg {
+			return refreshDashboardMsg{}
+		})
+	case refreshDashboardMsg:
+		return m, m.fetchStatuses(slices.Clone(m.rows))
+	case tea.WindowSizeMsg:
+		m.table.SetHeight(min(max(msg.Height-10, 5), 20))
 		return m, nil
 	}
 	var tableCmd tea.Cmd
@@ -47,51 +104,97 @@ func (m dashboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(tableCmd, spinnerCmd)
 }
 
+// Returns the action for the key if it is enabled for the highlighted row.
+func (m dashboardModel) getKeyAction(key string) (dashboardKeyAction, bool) {
+	index := slices.IndexFunc(dashboardKeyActions, func(next dashboardKeyAction) bool {
+		return next.key == key
+	})
+	if index == -1 || len(m.rows) == 0 {
+		return dashboardKeyAction{}, false
+	}
+	keyAction := dashboardKeyActions[index]
+	return keyAction, keyAction.requiresPr == m.rows[m.cursor()].pr
+}
+
+func (m dashboardModel) cursor() int {
+	return max(m.table.Cursor(), 0)
+}
+
 func (m dashboardModel) View() string {
+	if m.completed {
+		return ""
+	}
 	m.table.SetRows(m.getTableRows())
 	if m.table.Cursor() == -1 {
 		m.table.SetCursor(0)
 	}
-	return m.table.View() + "\n"
+	var lastRefresh string
+	if m.lastRefresh.IsZero() {
+		lastRefresh = "Fetching status " + m.spinner.View()
+	} else {
+		lastRefresh = "Last refreshed " + m.lastRefresh.Format(time.TimeOnly)
+	}
+	return m.table.View() + "\n" +
+		lastRefresh + "\n" +
+		"\n" +
+		"Controls:\n" +
+		"   n     new\n" +
+		"   u     update\n" +
+		"   a     add-reviewers\n" +
+		"   c     checkout\n" +
+		"   r     replace-commit\n" +
+		"   q     quit\n"
 }
 
 func (m dashboardModel) getTableRows() []table.Row {
 	tableRows := make([]table.Row, len(m.rows))
 	for i, row := range m.rows {
 		pr := ""
-		checksPassed := ""
+		checks := ""
 		approved := ""
 		if row.pr {
-			pr = "pr"
+			pr = "✅"
 			if row.status != nil {
-				if row.status.Checks.IsSuccess() {
-					checksPassed = "passesd"
-				} else if row.status.Checks.IsFailing() {
-					checksPassed = "failed"
-				} else {
-					checksPassed = fmt.Sprint(row.status.Checks.PercentageComplete())
-				}
-				approved = strings.Join(row.status.Approvers, "\n")
+				checks = getChecksDescription(row.status.Checks)
+				approved = strings.Join(row.status.Approvers, ", ")
 			} else {
-				checksPassed = m.spinner.View()
+				checks = m.spinner.View()
 				approved = m.spinner.View()
 			}
 		}
-		tableRows[i] = table.Row{row.index, pr, checksPassed, approved, row.log.Commit, row.log.Subject + "\nnext 2"}
+		tableRows[i] = table.Row{row.index, pr, checks, approved, row.log.Commit, row.log.Subject}
 	}
 	return tableRows
 }
 
-type updateDashboardRowMsg struct {
-	index int
-	row   dashboardRow
+func getChecksDescription(checks util.PullRequestChecksStatus) string {
+	switch {
+	case checks.IsSuccess():
+		return "passed"
+	case checks.IsFailing():
+		return fmt.Sprint("failed ", checks.Failing, "/", checks.Total())
+	case checks.Total() < checks.MinChecks:
+		return fmt.Sprint("waiting ", checks.Total(), "/", checks.MinChecks)
+	default:
+		return fmt.Sprint(int(checks.PercentageComplete()*100), "%")
+	}
 }
 
-var _ tea.Model = dashboardModel{}
-var _ tea.Msg = updateDashboardRowMsg{}
+type updateDashboardStatusesMsg struct {
+	// Status for each row index that has a PR.
+	statuses  map[int]util.PullRequestStatus
+	refreshed time.Time
+}
 
-func ShowDashboard(asyncConfig util.AsyncAppConfig, minChecks int) {
+type refreshDashboardMsg struct{}
 
+var _ tea.Model = dashboardModel{}
+var _ tea.Msg = updateDashboardStatusesMsg{}
+var _ tea.Msg = refreshDashboardMsg{}
+
+// Shows the new commits along with the status of their PRs, polling the status every pollFrequency.
+// Returns the action that the user selected for the highlighted commit.
+func ShowDashboard(asyncConfig util.AsyncAppConfig, minChecks int, pollFrequency time.Duration) DashboardSelection {
 	columns := []string{"Index", "PR", "Checks", "Approved", "Commit", "Summary"}
 	newCommits := templates.GetNewCommits("HEAD")
 	gitBranchArgs := make([]string, 0, len(newCommits)+2)
@@ -122,26 +225,36 @@ func ShowDashboard(asyncConfig util.AsyncAppConfig, minChecks int) {
 		table.WithWrapCursor(true),
 	)
 	initialModel := dashboardModel{
-		spinner: spinner.New(),
-		table:   t,
-		rows:    rows,
+		spinner:       spinner.New(),
+		table:         t,
+		rows:          rows,
+		pollFrequency: pollFrequency,
+		fetchStatuses: func(rows []dashboardRow) tea.Cmd {
+			return func() tea.Msg {
+				defer asyncConfig.GracefulRecover()
+				return fetchDashboardStatuses(rows, minChecks)
+			}
+		},
 	}
 	initialModel.spinner.Spinner = spinner.Dot
-	program := newProgram(initialModel, asyncConfig.App.Io)
-	go updateDashboardData(asyncConfig, program, rows, minChecks)
-	runProgram(asyncConfig.App.Io, program)
-	// finalModel := runProgram(asyncConfig.App.Io, program)
-	// finalDashboardModel := finalModel.(dashboardModel)
-	// println("finalDashboardModel", fmt.Sprint(finalDashboardModel))
+	finalModel := runProgram(asyncConfig.App.Io, newProgram(initialModel, asyncConfig.App.Io))
+	return finalModel.(dashboardModel).selection
 }
 
-func updateDashboardData(asyncConfig util.AsyncAppConfig, program *tea.Program, rows []dashboardRow, minChecks int) {
-	defer asyncConfig.GracefulRecover()
-	for i, row := range rows {
+// Fetches the status of all the PRs with a single request.
+func fetchDashboardStatuses(rows []dashboardRow, minChecks int) updateDashboardStatusesMsg {
+	branchNames := make([]string, 0, len(rows))
+	for _, row := range rows {
 		if row.pr {
-			status := util.GetPullRequestStatus(row.log.Branch, minChecks)
-			row.status = &status
-			program.Send(updateDashboardRowMsg{index: i, row: row})
+			branchNames = append(branchNames, row.log.Branch)
+		}
+	}
+	branchStatuses := util.GetPullRequestStatuses(branchNames, minChecks)
+	statuses := make(map[int]util.PullRequestStatus)
+	for i, row := range rows {
+		if status, ok := branchStatuses[row.log.Branch]; ok && row.pr {
+			statuses[i] = status
 		}
 	}
+	return updateDashboardStatusesMsg{statuses: statuses, refreshed: time.Now()}
 }
@@ -0,0 +1,141 @@
+package interactive
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+type reorderModel struct {
+	table table.Model
+	rows  [][]string
+	// Index in rows of each displayed row, in the order that they are displayed.
+	order []int
+	// Whether the row under the cursor moves with the cursor.
+	moving    bool
+	prompt    string
+	completed bool
+	cancelled bool
+}
+
+var _ tea.Model = reorderModel{}
+
+func (m reorderModel) Init() tea.Cmd { return nil }
+
+func (m reorderModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.table.SetStyleFunc(m.createStyleFunc())
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "q", "Q", "ctrl+c":
+			m.cancelled = true
+			return m, tea.Quit
+		case " ":
+			m.moving = !m.moving
+			m.table.SetStyleFunc(m.createStyleFunc())
+			return m, nil
+		case "enter":
+			m.completed = true
+			return m, tea.Quit
+		case "up", "k":
+			if m.moving {
+				m.moveCursorRow(-1)
+				return m, nil
+			}
+		case "down", "j":
+			if m.moving {
+				m.moveCursorRow(1)
+				return m, nil
+			}
+		}
+	case tea.WindowSizeMsg:
+		m.table.SetHeight(min(max(msg.Height-14, 5), 20))
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// Swaps the row under the cursor with the row offset from it, and keeps the cursor on the moved row.
+func (m *reorderModel) moveCursorRow(offset int) {
+	cursor := m.table.Cursor()
+	other := cursor + offset
+	if cursor < 0 || other < 0 || other >= len(m.order) {
+		return
+	}
+	m.order = slices.Clone(m.order)
+	m.order[cursor], m.order[other] = m.order[other], m.order[cursor]
+	m.table.SetRows(getReorderRows(m.rows, m.order))
+	m.table.SetCursor(other)
+	m.table.SetStyleFunc(m.createStyleFunc())
+}
+
+// This needs to be recreated everytime the model changes so that the model reference is updated.
+func (m reorderModel) createStyleFunc() func(tableModel table.Model, row int, col int) lipgloss.Style {
+	return func(tableModel table.Model, row int, col int) lipgloss.Style {
+		switch {
+		case row < 0 || row >= len(tableModel.Rows()):
+			return baseStyle
+		case row == tableModel.Cursor() && m.moving:
+			return selectedHighlightRowStyle
+		case row == tableModel.Cursor():
+			return highlightEnabledStyle
+		case m.order[row] != row:
+			return selectedRowStyle
+		default:
+			return enabledRowStyle
+		}
+	}
+}
+
+func (m reorderModel) View() string {
+	if m.completed || m.cancelled {
+		return ""
+	}
+	return promptStyle.Render(m.prompt) + "\n" +
+		m.table.View() + "\n" +
+		"\n" +
+		"Controls:\n" +
+		"   up/down   select commit, or move it when it is picked up\n" +
+		"   space     pick up or put down the selected commit\n" +
+		"   enter     confirm the new order\n" +
+		"   esc       quit\n"
+}
+
+func getReorderRows(rows [][]string, order []int) []table.Row {
+	return util.MapSlice(order, func(index int) table.Row {
+		return rows[index]
+	})
+}
+
+// Displays rows and lets the user reorder them. Returns the indexes of rows in their new order,
+// or false if the user cancelled.
+func GetReorderSelection(prompt string, columns []string, rows [][]string, stdIo util.StdIo) ([]int, bool) {
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	t := table.New(
+		table.WithColumns(util.MapSlice(columns, func(columnName string) table.Column {
+			return table.Column{Title: columnName}
+		})),
+		table.WithRows(getReorderRows(rows, order)),
+		table.WithFocused(true),
+		table.WithWrapCursor(true),
+	)
+	initialModel := reorderModel{
+		table:  t,
+		rows:   rows,
+		order:  order,
+		prompt: prompt,
+	}
+	finalModel := runProgram(stdIo, newProgram(initialModel, stdIo)).(reorderModel)
+	if !finalModel.completed {
+		return nil, false
+	}
+	return finalModel.order, true
+}
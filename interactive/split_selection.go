@@ -0,0 +1,211 @@
+package interactive
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Highest commit number that a hunk can be assigned to.
+const maxSplitCommits = 9
+
+// A change that can be moved to a new commit by [GetSplitSelection].
+type SplitHunk struct {
+	Filename string
+	// Lines of the change, for example "-10,2 +10,3".
+	Lines string
+	// Short description of the change, such as its first changed line.
+	Summary string
+}
+
+// Commits that the hunks were assigned to, as returned by [GetSplitSelection].
+type SplitSelection struct {
+	// Index of the commit that each hunk is assigned to, in the same order as the hunks.
+	Assignments []int
+	// Subject of each commit, oldest first.
+	Subjects []string
+}
+
+type splitModel struct {
+	table       table.Model
+	hunks       []SplitHunk
+	assignments []int
+	// Whether a commit number assigns all the hunks of the file instead of a single hunk.
+	fileMode bool
+	// Commit numbers that were used, sorted, once the hunks are assigned.
+	commitNumbers []int
+	subjects      []string
+	textInput     textinput.Model
+	completed     bool
+	cancelled     bool
+}
+
+var _ tea.Model = splitModel{}
+
+func (m splitModel) Init() tea.Cmd { return nil }
+
+func (m splitModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && (keyMsg.Type == tea.KeyCtrlC || keyMsg.Type == tea.KeyEsc) {
+		m.cancelled = true
+		return m, tea.Quit
+	}
+	if m.commitNumbers != nil {
+		return m.updateSubject(msg)
+	}
+	m.table.SetStyleFunc(m.createStyleFunc())
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "Q":
+			m.cancelled = true
+			return m, tea.Quit
+		case "f", "F":
+			m.fileMode = !m.fileMode
+			return m, nil
+		case "enter":
+			m.commitNumbers = slices.Compact(slices.Sorted(slices.Values(m.assignments)))
+			m.textInput.SetValue(m.subjects[0])
+			m.textInput.SetCursor(len(m.textInput.Value()))
+			return m, textinput.Blink
+		}
+		if msg.Type == tea.KeyRunes && len(msg.Runes) == 1 && msg.Runes[0] >= '1' && msg.Runes[0] <= '0'+maxSplitCommits {
+			m.assign(int(msg.Runes[0] - '0'))
+			return m, nil
+		}
+	case tea.WindowSizeMsg:
+		m.table.SetHeight(min(max(msg.Height-14, 5), 20))
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// Assigns the hunk under the cursor, or all the hunks of its file, to commit number.
+func (m *splitModel) assign(number int) {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.hunks) {
+		return
+	}
+	m.assignments = slices.Clone(m.assignments)
+	for i, hunk := range m.hunks {
+		if i == cursor || (m.fileMode && hunk.Filename == m.hunks[cursor].Filename) {
+			m.assignments[i] = number
+		}
+	}
+	m.table.SetRows(getSplitRows(m.hunks, m.assignments))
+}
+
+func (m splitModel) updateSubject(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.Type == tea.KeyEnter {
+		subject := strings.TrimSpace(m.textInput.Value())
+		if subject == "" {
+			return m, nil
+		}
+		m.subjects = slices.Clone(m.subjects)
+		m.subjects[len(m.subjects)-1] = subject
+		if len(m.subjects) == len(m.commitNumbers) {
+			m.completed = true
+			return m, tea.Quit
+		}
+		m.subjects = append(m.subjects, "")
+		m.textInput.SetValue("")
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// This needs to be recreated everytime the model changes so that the model reference is updated.
+func (m splitModel) createStyleFunc() func(tableModel table.Model, row int, col int) lipgloss.Style {
+	return func(tableModel table.Model, row int, col int) lipgloss.Style {
+		switch {
+		case row < 0 || row >= len(tableModel.Rows()):
+			return baseStyle
+		case row == tableModel.Cursor():
+			return highlightEnabledStyle
+		case m.fileMode && m.hunks[row].Filename == m.hunks[tableModel.Cursor()].Filename:
+			return selectedRowStyle
+		default:
+			return enabledRowStyle
+		}
+	}
+}
+
+func (m splitModel) View() string {
+	if m.completed || m.cancelled {
+		return ""
+	}
+	if m.commitNumbers != nil {
+		return promptStyle.Render(fmt.Sprint("Subject of commit ", len(m.subjects), " of ", len(m.commitNumbers), "?")) + "\n" +
+			m.textInput.View() + "\n"
+	}
+	assignMode := "hunk"
+	if m.fileMode {
+		assignMode = "file"
+	}
+	return promptStyle.Render("What commit should each change be in?") + "\n" +
+		m.table.View() + "\n" +
+		"\n" +
+		"Controls:\n" +
+		"   up/down   select change\n" +
+		"   1-9       move " + assignMode + " to that commit, 1 is the oldest\n" +
+		"   f         toggle moving a single hunk or the whole file\n" +
+		"   enter     confirm and enter the commit subjects\n" +
+		"   esc       quit\n"
+}
+
+func getSplitRows(hunks []SplitHunk, assignments []int) []table.Row {
+	rows := make([]table.Row, len(hunks))
+	for i, hunk := range hunks {
+		rows[i] = table.Row{fmt.Sprint(assignments[i]), hunk.Filename, hunk.Lines, hunk.Summary}
+	}
+	return rows
+}
+
+// Displays hunks and lets the user assign each of them to a new commit and then enter the
+// subject of each commit. All hunks start in the first commit, whose subject defaults to
+// defaultSubject. Returns false if the user cancelled.
+func GetSplitSelection(stdIo util.StdIo, hunks []SplitHunk, defaultSubject string) (SplitSelection, bool) {
+	assignments := make([]int, len(hunks))
+	for i := range assignments {
+		assignments[i] = 1
+	}
+	t := table.New(
+		table.WithColumns([]table.Column{
+			{Title: "Commit", Width: 6},
+			{Title: "File", Width: 30},
+			{Title: "Lines", Width: 14},
+			{Title: "Change", Width: 50},
+		}),
+		table.WithRows(getSplitRows(hunks, assignments)),
+		table.WithFocused(true),
+		table.WithWrapCursor(true),
+	)
+	input := textinput.New()
+	input.Focus()
+	input.Width = 100
+	initialModel := splitModel{
+		table:       t,
+		hunks:       hunks,
+		assignments: assignments,
+		subjects:    []string{defaultSubject},
+		textInput:   input,
+	}
+	finalModel := runProgram(stdIo, newProgram(initialModel, stdIo)).(splitModel)
+	if !finalModel.completed {
+		return SplitSelection{}, false
+	}
+	// Renumber the commits so that there are no gaps.
+	compacted := util.MapSlice(finalModel.assignments, func(number int) int {
+		return slices.Index(finalModel.commitNumbers, number)
+	})
+	return SplitSelection{Assignments: compacted, Subjects: finalModel.subjects}, true
+}
@@ -14,8 +14,10 @@ const REVIEWERS_HISTORY_FILE = "reviewers.history"
 const all_collaborators_file = "all-collaborators.cache"
 
 type userSelectionModel struct {
-	textInput     textinput.Model
-	history       []string
+	textInput textinput.Model
+	history   []string
+	// Suggested reviewers, such as code owners, which are shown first.
+	suggested     []string
 	suggestions   []string
 	breakingChars []rune
 	historyIndex  int
@@ -45,7 +47,7 @@ func (m userSelectionModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 	case setSuggestionsMsg:
-		m.suggestions = msg.suggestions
+		m.suggestions = getReviewerSuggestions(m.suggested, msg.suggestions)
 		m.setSuggestions()
 		return m, nil
 	case tea.WindowSizeMsg:
@@ -74,6 +76,9 @@ func (m userSelectionModel) View() string {
 		users = users[0:min(max(0, m.windowWidth-len(USER_PREFIX)), len(users))]
 	}
 	users = USER_PREFIX + users + "\n"
+	if len(m.suggested) > 0 {
+		users = "   suggested " + strings.Join(m.suggested, " ") + "\n" + users
+	}
 	return promptStyle.Render("Reviewers to add when checks pass?") + "\n" +
 		m.textInput.View() + "\n" +
 		"\n" +
@@ -170,20 +175,23 @@ type setSuggestionsMsg struct {
 var _ tea.Model = userSelectionModel{}
 var _ tea.Msg = setSuggestionsMsg{}
 
-func UserSelection(asyncConfig util.AsyncAppConfig) string {
+// Prompts for reviewers, suggesting suggested first, then the groups of the "reviewer-groups"
+// config, then the collaborators of the repository.
+func UserSelection(asyncConfig util.AsyncAppConfig, suggested []string) string {
 	input := textinput.New()
 	input.Focus()
 	input.Width = 100
 	input.Placeholder = "None"
 	input.ShowSuggestions = true
 	history := util.ReadHistory(asyncConfig.App, REVIEWERS_HISTORY_FILE)
-	suggestions := util.ReadHistory(asyncConfig.App, all_collaborators_file)
+	suggestions := getReviewerSuggestions(suggested, util.ReadHistory(asyncConfig.App, all_collaborators_file))
 	input.SetSuggestions(suggestions)
 	initialModel := userSelectionModel{
 		history:       history,
 		historyIndex:  -1,
 		textInput:     input,
 		confirmed:     false,
+		suggested:     suggested,
 		suggestions:   suggestions,
 		breakingChars: []rune{',', ' '},
 	}
@@ -209,6 +217,22 @@ func normalizeReviewers(selected string) string {
 	return selected
 }
 
+// Returns suggested, then "@group" for each reviewer group, then collaborators, without duplicates.
+func getReviewerSuggestions(suggested []string, collaborators []string) []string {
+	groups := make([]string, 0)
+	for group := range util.GetReviewerGroups() {
+		groups = append(groups, "@"+group)
+	}
+	slices.Sort(groups)
+	suggestions := make([]string, 0, len(suggested)+len(groups)+len(collaborators))
+	for _, suggestion := range slices.Concat(suggested, groups, collaborators) {
+		if suggestion != "" && !slices.Contains(suggestions, suggestion) {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
+
 // Updates suggestions with results from API collaborators call.
 func updateSuggestions(asyncConfig util.AsyncAppConfig, program *tea.Program) {
 	defer asyncConfig.GracefulRecover()
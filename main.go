@@ -5,16 +5,28 @@ usage: sd [top-level-flags] <command> [<args>]
 
 Possible commands are:
 
+	absorb              Add staged changes to the commits that they belong to
 	add-reviewers       Add reviewers to Pull Request on Github once its checks have passed
+	amend-pr            Update the title and description of a PR from its commit
 	branch-name         Outputs branch name of commit
 	checkout            Checks out branch associated with commit indicator
 	code-owners         Outputs code owners for all of the changes in branch
+	config              Get or set config, such as default values for flags
+	dashboard           Interactive dashboard of your commits and their PRs
+	land                Merge a PR once it is ready and then rebase main
 	log                 Displays git log of your changes
+	move                Move a commit up or down the stack
 	new                 Create a new pull request from a commit on main
 	prs                 Lists all Pull Requests you have open.
 	rebase-main         Bring your main branch up to date with remote
 	replace-commit      Replaces a commit on main branch with its associated branch
 	replace-conflicts   For failed rebase: replace changes with its associated branch
+	restack             Rebase stacked PR branches onto their updated base branches
+	split               Split a commit into several commits
+	squash              Squash commits on main into another commit and combine their PRs
+	status              Displays the PR state, checks, and approvals of each commit
+	submit              Create or update PRs for all commits on main
+	undo                Undo the most recent command that changed branches
 	update              Add commits from main to an existing PR
 	wait-for-merge      Waits for a pull request to be merged
 
@@ -30,6 +42,13 @@ flags:
 	         error
 	      Default is info, except on commands that are for output purposes,
 	      (namely branch-name and log), which have a default of error.
+	-output string
+	      Output format of read-only commands:
+	         text   human readable
+	         json   JSON array of records with a stable schema
+	         tsv    tab separated values with a header row
+	      Supported by branch-name, checks, code-owners, config, log, prs, status, and
+	      watch status. (default "text")
 */
 package main
 
@@ -0,0 +1,29 @@
+package templates
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// Key of the git trailer that holds the change id of a commit. Unlike the commit hash and
+// subject, the change id stays the same when the commit is amended or reworded, so the branch
+// of a commit that has one is named after it instead of the subject.
+const ChangeIdTrailer = "Stacked-Diff-Id"
+
+// Matches the change id trailer lines of a commit message.
+var changeIdTrailerRegexp = regexp.MustCompile(`(?m)^` + ChangeIdTrailer + `: .*$\n?`)
+
+// Returns a new random change id, in the same format as a Gerrit Change-Id.
+func NewChangeId() string {
+	id := make([]byte, 20)
+	if _, err := rand.Read(id); err != nil {
+		panic("Could not create change id: " + err.Error())
+	}
+	return "I" + hex.EncodeToString(id)
+}
+
+// Returns message without its change id trailer.
+func RemoveChangeId(message string) string {
+	return changeIdTrailerRegexp.ReplaceAllString(message, "")
+}
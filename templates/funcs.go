@@ -0,0 +1,127 @@
+package templates
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Functions that templates can use in addition to the built-in functions of text/template. Names
+// and argument order follow the sprig library, with the value last so that functions can be used in
+// pipelines, for example {{.CommitSummary | lower | trunc 50}}. The exception is regexReplaceAll,
+// which like sprig takes the value before the replacement.
+var templateFuncs = template.FuncMap{
+	// Strings.
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      titleCase,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+	"trunc":      truncateRunes,
+	"split":      func(sep string, s string) []string { return strings.Split(s, sep) },
+	"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"indent":     indent,
+	// Regular expressions, see https://pkg.go.dev/regexp/syntax.
+	"regexMatch":      regexMatch,
+	"regexFind":       regexFind,
+	"regexReplaceAll": regexReplaceAll,
+	// Dates, formatted with a layout such as "2006-01-02", see https://pkg.go.dev/time#Layout.
+	"now":  time.Now,
+	"date": func(layout string, t time.Time) string { return t.Format(layout) },
+	// Defaults.
+	"default":  defaultValue,
+	"empty":    isEmpty,
+	"coalesce": coalesce,
+}
+
+// Returns s with the first letter of each word in upper case.
+func titleCase(s string) string {
+	previous := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { previous = r }()
+		if unicode.IsSpace(previous) {
+			return unicode.ToUpper(r)
+		}
+		return r
+	}, s)
+}
+
+// Returns the first length characters of s, or the last -length characters if length is negative.
+func truncateRunes(length int, s string) string {
+	runes := []rune(s)
+	if length < 0 && -length < len(runes) {
+		return string(runes[len(runes)+length:])
+	}
+	if length >= 0 && length < len(runes) {
+		return string(runes[:length])
+	}
+	return s
+}
+
+// Returns s with each line indented by spaces spaces.
+func indent(spaces int, s string) string {
+	padding := strings.Repeat(" ", spaces)
+	return padding + strings.ReplaceAll(s, "\n", "\n"+padding)
+}
+
+func regexMatch(regex string, s string) (bool, error) {
+	return regexp.MatchString(regex, s)
+}
+
+// Returns the first match of regex in s, or "" if none.
+func regexFind(regex string, s string) (string, error) {
+	compiled, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return compiled.FindString(s), nil
+}
+
+// Returns s with the matches of regex replaced by replacement, which can refer to groups as ${1}.
+func regexReplaceAll(regex string, s string, replacement string) (string, error) {
+	compiled, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return compiled.ReplaceAllString(s, replacement), nil
+}
+
+// Returns value, or defaultValue if value is empty.
+func defaultValue(defaultValue any, value any) any {
+	if isEmpty(value) {
+		return defaultValue
+	}
+	return value
+}
+
+// Returns the first of values that is not empty, or nil if they are all empty.
+func coalesce(values ...any) any {
+	for _, value := range values {
+		if !isEmpty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// Returns whether value is nil, false, zero, or an empty string, slice, or map.
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return reflected.Len() == 0
+	default:
+		return reflected.IsZero()
+	}
+}
@@ -15,47 +15,83 @@ type GitLog struct {
 	Subject string
 	// Associated branch name. Branch might not exist.
 	Branch string
+	// Value of the Stacked-Diff-Id trailer of the commit, or empty if it has none, see
+	// [NewChangeId].
+	ChangeId string
 }
 
 // Delimter for git log format when a space cannot be used.
 const formatDelimiter = "|stackeddiff-delim|"
 
 // Format sent to "git log" for use by [newGitLogs].
-const newGitLogsFormat = "--pretty=format:%h" + formatDelimiter + "%s" + formatDelimiter + "%f"
+const newGitLogsFormat = "--pretty=format:%h" + formatDelimiter + "%s" + formatDelimiter + "%f" + formatDelimiter + "%H" +
+	formatDelimiter + "%(trailers:key=" + ChangeIdTrailer + ",valueonly,separator=%x2C)"
 
 // Returns all the commits on the current branch. For use by tests.
 func GetAllCommits() []GitLog {
 	gitArgs := []string{"--no-pager", "log", newGitLogsFormat, "--abbrev-commit"}
 	logsRaw := util.ExecuteOrDie(util.ExecuteOptions{}, "git", gitArgs...)
-	return newGitLogs(logsRaw)
+	return newGitLogs(logsRaw, false)
 }
 
 func GetNewCommits(to string) []GitLog {
 	compareFromRemoteBranch := util.GetMainBranchOrDie()
 	gitArgs := []string{"--no-pager", "log", newGitLogsFormat, "--abbrev-commit"}
-	if util.RemoteHasBranch(compareFromRemoteBranch) {
-		gitArgs = append(gitArgs, "origin/"+compareFromRemoteBranch+".."+to)
+	if util.RemoteHasMainBranch() {
+		gitArgs = append(gitArgs, util.GetRemoteMainBranchOrDie()+".."+to)
 	} else {
 		gitArgs = append(gitArgs, to)
 	}
 	logsRaw := util.ExecuteOrDie(util.ExecuteOptions{}, "git", gitArgs...)
-	return newGitLogs(logsRaw)
+	// Only follow rewritten commits on main, see [util.GetStackEntries].
+	followRewrites := to == compareFromRemoteBranch ||
+		(to == "HEAD" && util.GetCurrentBranchName() == compareFromRemoteBranch)
+	return newGitLogs(logsRaw, followRewrites)
 }
 
-func newGitLogs(logsRaw string) []GitLog {
+// Parses output of "git log" that used [newGitLogsFormat]. The branch of each commit is the one
+// recorded in the stack store, or if none then the one from the branch-name template, which uses
+// the change id of the commit if it has one.
+func newGitLogs(logsRaw string, followRewrites bool) []GitLog {
 	logLines := strings.Split(strings.TrimSpace(logsRaw), "\n")
 	var logs []GitLog
+	var fullCommits []string
 	for _, logLine := range logLines {
 		components := strings.Split(logLine, formatDelimiter)
-		if len(components) != 3 {
+		if len(components) != 5 {
 			// No git logs.
 			continue
 		}
-		logs = append(logs, GitLog{Commit: components[0], Subject: components[1], Branch: getBranchForSantizedSubject(components[2])})
+		// Use the first id if the trailer was added more than once.
+		changeId := strings.Split(components[4], ",")[0]
+		logs = append(logs, GitLog{
+			Commit:   components[0],
+			Subject:  components[1],
+			Branch:   getBranchName(components[2], changeId),
+			ChangeId: changeId,
+		})
+		fullCommits = append(fullCommits, components[3])
+	}
+	if len(logs) == 0 {
+		return logs
+	}
+	stackEntries := util.GetStackEntries(fullCommits, followRewrites)
+	for i, fullCommit := range fullCommits {
+		if entry, ok := stackEntries[fullCommit]; ok {
+			logs[i].Branch = entry.Branch
+		}
 	}
 	return logs
 }
 
+// Updates the stack store so that its entries follow the commits on main that were rewritten,
+// for example by a rebase. Call after rewriting commits, as [GetNewCommits] only follows them the
+// first time.
+func FollowRewrittenCommits() {
+	util.InvalidateStackRewrites()
+	GetNewCommits(util.GetMainBranchOrDie())
+}
+
 func RequireCommitOnMain(commit string) {
 	if commit == util.GetMainBranchOrDie() {
 		return
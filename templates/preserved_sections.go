@@ -0,0 +1,64 @@
+package templates
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches the start of a preserved section in a PR description, for example
+// "<!-- sd-preserve:testing -->". The section ends with "<!-- /sd-preserve:testing -->".
+var preservedSectionStart = regexp.MustCompile(`<!-- sd-preserve:([\w-]+) -->`)
+
+// Returns the end marker of the preserved section named name.
+func preservedSectionEnd(name string) string {
+	return "<!-- /sd-preserve:" + name + " -->"
+}
+
+// Returns description with the contents of each preserved section replaced by the contents of the
+// same section in existingDescription, so that any edits made to those sections on the PR are kept.
+// Sections that are not in existingDescription are left as rendered.
+func PreserveSections(description string, existingDescription string) string {
+	existingSections := getPreservedSections(existingDescription)
+	var result strings.Builder
+	remaining := description
+	for {
+		start := preservedSectionStart.FindStringSubmatchIndex(remaining)
+		if start == nil {
+			break
+		}
+		name := remaining[start[2]:start[3]]
+		contentStart := start[1]
+		contentLength := strings.Index(remaining[contentStart:], preservedSectionEnd(name))
+		if contentLength == -1 {
+			// Not terminated, leave as is.
+			break
+		}
+		result.WriteString(remaining[:contentStart])
+		if existing, ok := existingSections[name]; ok {
+			result.WriteString(existing)
+		} else {
+			result.WriteString(remaining[contentStart : contentStart+contentLength])
+		}
+		remaining = remaining[contentStart+contentLength:]
+	}
+	result.WriteString(remaining)
+	return result.String()
+}
+
+// Returns the contents of each preserved section in description, keyed by name.
+func getPreservedSections(description string) map[string]string {
+	sections := make(map[string]string)
+	// Github stores descriptions edited in the browser with Windows line endings.
+	description = strings.ReplaceAll(description, "\r\n", "\n")
+	for _, start := range preservedSectionStart.FindAllStringSubmatchIndex(description, -1) {
+		name := description[start[2]:start[3]]
+		contentLength := strings.Index(description[start[1]:], preservedSectionEnd(name))
+		if contentLength == -1 {
+			continue
+		}
+		if _, ok := sections[name]; !ok {
+			sections[name] = description[start[1] : start[1]+contentLength]
+		}
+	}
+	return sections
+}
@@ -0,0 +1,165 @@
+package templates
+
+import (
+	_ "embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+//go:embed config/stack.template
+var stackTemplateText string
+
+// Markers around the stack section of a PR description, see [ReplaceStackSection].
+const (
+	stackSectionStart = "<!-- sd-stack -->"
+	stackSectionEnd   = "<!-- /sd-stack -->"
+)
+
+// PR of a commit in the local stack of new commits on main.
+type StackPullRequest struct {
+	Commit  string
+	Subject string
+	Branch  string
+	// Number of the PR, 0 if the commit does not have an open PR, such as when it is being created.
+	Number int
+	Url    string
+	// Whether this is the PR of the commit that the template is rendered for.
+	Current bool
+}
+
+// Returns a markdown link to the PR, such as "[#101](https://github.com/owner/repo/pull/101)",
+// or "this PR" if it does not have a number yet.
+func (pullRequest StackPullRequest) Link() string {
+	if pullRequest.Number == 0 {
+		return "this PR"
+	}
+	return fmt.Sprint("[#", pullRequest.Number, "](", pullRequest.Url, ")")
+}
+
+// Stack section rendered for the PR of a commit in the local stack, see [GetStackSections].
+type StackSection struct {
+	PullRequest util.PullRequest
+	// Output of the "stack" template for the commit of the PR.
+	Section string
+}
+
+// Local stack of new commits on HEAD along with their PRs, see [GetStack].
+type Stack struct {
+	// PRs of the commits, from the bottom of the stack to the top.
+	PullRequests []StackPullRequest
+	// Open PRs of the commits by branch.
+	openPullRequests map[string]util.PullRequest
+}
+
+// Returns a [StackPullRequest] for each new commit on HEAD, from the bottom of the stack to the
+// top. The PRs of all the commits are fetched together via [util.Forge.GetPullRequests], so fetch
+// the stack once per command and pass it to [GetPullRequestText] and [GetStackSections].
+func GetStack() Stack {
+	gitLogs := GetNewCommits("HEAD")
+	slices.Reverse(gitLogs)
+	pullRequests := util.GetForge().GetPullRequests(util.MapSlice(gitLogs, func(gitLog GitLog) string {
+		return gitLog.Branch
+	}))
+	stack := Stack{
+		PullRequests:     make([]StackPullRequest, 0, len(gitLogs)),
+		openPullRequests: make(map[string]util.PullRequest),
+	}
+	for _, gitLog := range gitLogs {
+		stack.PullRequests = append(stack.PullRequests, StackPullRequest{Commit: gitLog.Commit, Subject: gitLog.Subject, Branch: gitLog.Branch})
+		if pullRequest, ok := pullRequests[gitLog.Branch]; ok && pullRequest.State == util.PullRequestStateOpen {
+			stack.AddPullRequest(pullRequest)
+		}
+	}
+	return stack
+}
+
+// Adds pullRequest to the commit of its branch, such as after creating it, so that the stack does
+// not need to be fetched again. Does nothing if the branch is not in the stack.
+func (stack *Stack) AddPullRequest(pullRequest util.PullRequest) {
+	index := slices.IndexFunc(stack.PullRequests, func(next StackPullRequest) bool {
+		return next.Branch == pullRequest.HeadBranch
+	})
+	if index == -1 {
+		return
+	}
+	stack.PullRequests[index].Number = pullRequest.Number
+	stack.PullRequests[index].Url = pullRequest.Url
+	stack.openPullRequests[pullRequest.HeadBranch] = pullRequest
+}
+
+// Returns the stack fields of the template data, where isCurrent returns whether a PR of stack is
+// the one that the template is rendered for.
+func getStackTemplateData(stack []StackPullRequest, isCurrent func(StackPullRequest) bool) templateData {
+	linkedStack := make([]StackPullRequest, 0, len(stack))
+	for _, next := range stack {
+		next.Current = isCurrent(next)
+		if next.Current || next.Number != 0 {
+			linkedStack = append(linkedStack, next)
+		}
+	}
+	data := templateData{StackSize: len(stack), Stack: linkedStack}
+	for i, next := range linkedStack {
+		if !next.Current {
+			continue
+		}
+		data.StackPosition = slices.IndexFunc(stack, func(other StackPullRequest) bool {
+			return other.Commit == next.Commit
+		}) + 1
+		if i > 0 {
+			data.PreviousPullRequest = &linkedStack[i-1]
+		}
+		if i < len(linkedStack)-1 {
+			data.NextPullRequest = &linkedStack[i+1]
+		}
+	}
+	return data
+}
+
+// Returns the stack section of the description of each open PR in stack, rendered from the "stack"
+// template, so that the PRs can be updated when the stack changes. Only the stack fields of the
+// template data are set, as the section does not use the others.
+func GetStackSections(stack Stack) []StackSection {
+	sections := make([]StackSection, 0, len(stack.openPullRequests))
+	for _, next := range stack.PullRequests {
+		pullRequest, ok := stack.openPullRequests[next.Branch]
+		if !ok {
+			continue
+		}
+		data := getStackTemplateData(stack.PullRequests, func(other StackPullRequest) bool {
+			return other.Commit == next.Commit
+		})
+		sections = append(sections, StackSection{PullRequest: pullRequest, Section: runTemplate("stack", data)})
+	}
+	return sections
+}
+
+// Returns description with its stack section, between "<!-- sd-stack -->" and
+// "<!-- /sd-stack -->", replaced by the one in stackSection. Returns false if either does not have
+// a stack section.
+func ReplaceStackSection(description string, stackSection string) (string, bool) {
+	start, end, ok := findStackSection(description)
+	if !ok {
+		return description, false
+	}
+	sectionStart, sectionEnd, ok := findStackSection(stackSection)
+	if !ok {
+		return description, false
+	}
+	return description[:start] + stackSection[sectionStart:sectionEnd] + description[end:], true
+}
+
+// Returns the start and end of the stack section of description, including its markers.
+func findStackSection(description string) (int, int, bool) {
+	start := strings.Index(description, stackSectionStart)
+	if start == -1 {
+		return 0, 0, false
+	}
+	length := strings.Index(description[start:], stackSectionEnd)
+	if length == -1 {
+		return 0, 0, false
+	}
+	return start, start + length + len(stackSectionEnd), true
+}
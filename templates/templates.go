@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
@@ -33,6 +36,7 @@ type PullRequestText struct {
 type branchTemplateData struct {
 	UsernameCleaned      string
 	CommitSummaryCleaned string
+	ChangeId             string
 }
 
 type templateData struct {
@@ -43,6 +47,25 @@ type templateData struct {
 	CommitSummaryCleaned       string
 	CommitSummaryWithoutTicket string
 	FeatureFlag                string
+	JiraUrl                    string
+	// Value of the Stacked-Diff-Id trailer of the commit, or empty if it has none.
+	ChangeId string
+	// Files changed by the commit.
+	ChangedFiles []string
+	// Summary of the changes of the commit, as output by "git show --stat".
+	DiffStat string
+	// Code owners of ChangedFiles, such as "@octocat" or "@org/team", sorted.
+	CodeOwners []string
+	// Position of the commit in the local stack, 1 being the bottom, or 0 if it is not in the stack.
+	StackPosition int
+	// Number of commits in the local stack.
+	StackSize int
+	// Commits of the stack that have an open PR, along with the commit itself, from bottom to top.
+	Stack []StackPullRequest
+	// PR below the commit in Stack, or nil if none.
+	PreviousPullRequest *StackPullRequest
+	// PR above the commit in Stack, or nil if none.
+	NextPullRequest *StackPullRequest
 }
 
 // Enum for what commitIndicator represents.
@@ -82,13 +105,15 @@ func GetBranchInfo(commitIndicator string, indicatorType IndicatorType) GitLog {
 	switch indicatorType {
 	case IndicatorTypePr:
 		slog.Debug("Using commitIndicator as a pull request number " + commitIndicator)
-
-		branchName := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "gh", "pr", "view", commitIndicator, "--json", "headRefName", "-q", ".headRefName"))
-		// Fetch the branch in case the lastest commit is only on GitHub.
-		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "fetch", "origin", branchName)
-		// Get the first commit of the branch on Github.
-		prCommit := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "gh", "pr", "view", commitIndicator, "--json", "commits", "-q", "[.commits[].oid] | first"))
-		gitLogs := newGitLogs(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", newGitLogsFormat, "--abbrev-commit", prCommit))
+		if stackInfo, ok := getBranchInfoFromStack(commitIndicator); ok {
+			info = stackInfo
+			slog.Info("Using pull request " + commitIndicator + ", commit " + info.Commit + ", branch " + info.Branch)
+			break
+		}
+		branchName, prCommit := util.GetForge().GetPullRequestFirstCommit(commitIndicator)
+		// Fetch the branch in case the lastest commit is only on the remote.
+		util.ExecuteOrDie(util.ExecuteOptions{}, "git", "fetch", util.GetPushRemote(), branchName)
+		gitLogs := newGitLogs(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", newGitLogsFormat, "--abbrev-commit", prCommit), false)
 		if len(gitLogs) == 0 {
 			panic(fmt.Sprint("Could not find first commit (", prCommit, ") of PR ", commitIndicator))
 		}
@@ -98,7 +123,7 @@ func GetBranchInfo(commitIndicator string, indicatorType IndicatorType) GitLog {
 		slog.Info("Using pull request " + commitIndicator + ", commit " + info.Commit + ", branch " + info.Branch)
 	case IndicatorTypeCommit:
 		slog.Debug("Using commitIndicator as a commit hash " + commitIndicator)
-		gitLogs := newGitLogs(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", newGitLogsFormat, "--abbrev-commit", commitIndicator))
+		gitLogs := newGitLogs(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", newGitLogsFormat, "--abbrev-commit", commitIndicator), false)
 		if len(gitLogs) == 0 {
 			panic(fmt.Sprint("Could not find commit ", commitIndicator))
 		}
@@ -127,6 +152,27 @@ func GetBranchInfo(commitIndicator string, indicatorType IndicatorType) GitLog {
 	return info
 }
 
+// Returns the commit on main of the PR numbered pullRequest, as recorded in the stack store.
+func getBranchInfoFromStack(pullRequest string) (GitLog, bool) {
+	pullRequestNumber, err := strconv.Atoi(pullRequest)
+	if err != nil {
+		return GitLog{}, false
+	}
+	entry, ok := util.GetStackEntryForPullRequest(pullRequestNumber)
+	if !ok {
+		return GitLog{}, false
+	}
+	if _, err := util.Execute(util.ExecuteOptions{}, "git", "merge-base", "--is-ancestor", entry.Commit, util.GetMainBranchOrDie()); err != nil {
+		// Commit is no longer on main, for example the PR was merged.
+		return GitLog{}, false
+	}
+	gitLogs := newGitLogs(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", newGitLogsFormat, "--abbrev-commit", entry.Commit), false)
+	if len(gitLogs) == 0 {
+		return GitLog{}, false
+	}
+	return gitLogs[0], true
+}
+
 func guessIndicatorType(commitIndicator string) IndicatorType {
 	if _, err := strconv.Atoi(commitIndicator); err == nil {
 		if len(commitIndicator) < 3 {
@@ -144,8 +190,8 @@ func guessIndicatorType(commitIndicator string) IndicatorType {
 	return IndicatorTypeCommit
 }
 
-func getBranchForSantizedSubject(sanitizedSubject string) string {
-	name := runTemplate("branch-name.template", branchNameTemplateText, getBranchTemplateData(sanitizedSubject))
+func getBranchName(sanitizedSubject string, changeId string) string {
+	name := runTemplate("branch-name", getBranchTemplateData(sanitizedSubject, changeId))
 	// Branch names that are too long cause problems with Github.
 	name = truncateString(name, 120)
 	return name
@@ -160,70 +206,157 @@ func truncateString(str string, maxBytes int) string {
 	return str
 }
 
-func GetPullRequestText(commitHash string, featureFlag string) PullRequestText {
-	data := getPullRequestTemplateData(commitHash, featureFlag)
-	title := runTemplate("pr-title.template", prTitleTemplateText, data)
-	description := runTemplate("pr-description.template", prDescriptionTemplateText, data)
+// Returns the PR title and description of commitHash, where stack is the local stack that the
+// commit is in, see [GetStack].
+func GetPullRequestText(commitHash string, featureFlag string, stack Stack) PullRequestText {
+	data := getPullRequestTemplateData(commitHash, featureFlag, stack.PullRequests)
+	title := runTemplate("pr-title", data)
+	description := runTemplate("pr-description", data)
 	return PullRequestText{Description: description, Title: title}
 }
 
-func runTemplate(configFilename string, defaultTemplateText string, data any) string {
-	configFile := getConfigFile(configFilename)
-	var parsed *template.Template
-	var err error
-	if configFile != nil {
-		parsed, err = template.ParseFiles(*configFile)
-		if err != nil {
-			panic(fmt.Sprint("Could not parse ", *configFile, err))
-		}
-	} else {
-		parsed, err = template.New("").Parse(defaultTemplateText)
-		if err != nil {
-			panic(fmt.Sprint("Could not parse ", defaultTemplateText, err))
-		}
+// Default text of each template, which can be replaced in the template dirs, keyed by template name.
+var defaultTemplates = map[string]string{
+	"branch-name":    branchNameTemplateText,
+	"pr-title":       prTitleTemplateText,
+	"pr-description": prDescriptionTemplateText,
+	"stack":          stackTemplateText,
+}
+
+// Directory, relative to the root of the repository, with templates that are committed to it.
+const RepoTemplatesDir = ".sd-templates"
+
+// Template sets parsed by [getTemplateSet], keyed by the template dirs that they were read from.
+var templateSets = make(map[string]*template.Template)
+
+var templateSetsMutex sync.Mutex
+
+// Forgets the templates that have been parsed, so that the template dirs are read again by the
+// next command.
+func ResetTemplates() {
+	templateSetsMutex.Lock()
+	defer templateSetsMutex.Unlock()
+	clear(templateSets)
+}
+
+// Returns the output of the template named templateName, such as "pr-title", for data.
+func runTemplate(templateName string, data any) string {
+	// Clone so that the shared set is never executed, which would prevent cloning it again.
+	parsed, err := getTemplateSet().Clone()
+	if err != nil {
+		panic(err)
 	}
 	var output bytes.Buffer
-	if err := parsed.Execute(&output, data); err != nil {
+	if err := parsed.ExecuteTemplate(&output, templateName, data); err != nil {
 		panic(err)
 	}
 	return output.String()
 }
 
-func getPullRequestTemplateData(commitHash string, featureFlag string) templateData {
+// Returns the set of all templates, parsed once per command.
+//
+// Each "<name>.template" file in the template dirs, see [GetTemplateDirs], is parsed as a template
+// named name, replacing the one of [defaultTemplates], so any of them can be used as a partial via
+// {{template "name" .}}. Templates in the user config dir replace those of the same name in the
+// repository.
+func getTemplateSet() *template.Template {
+	dirs := GetTemplateDirs()
+	key := strings.Join(dirs, string(os.PathListSeparator))
+	templateSetsMutex.Lock()
+	defer templateSetsMutex.Unlock()
+	if set, ok := templateSets[key]; ok {
+		return set
+	}
+	set := template.New("").Funcs(templateFuncs)
+	for name, text := range defaultTemplates {
+		if _, err := set.New(name).Parse(text); err != nil {
+			panic(fmt.Sprint("Could not parse ", text, err))
+		}
+	}
+	for _, dir := range dirs {
+		files, err := filepath.Glob(filepath.Join(dir, "*.template"))
+		if err != nil {
+			panic(err)
+		}
+		for _, file := range files {
+			text, err := os.ReadFile(file)
+			if err != nil {
+				panic(fmt.Sprint("Could not read ", file, ": ", err))
+			}
+			if _, err := set.New(strings.TrimSuffix(filepath.Base(file), ".template")).Parse(string(text)); err != nil {
+				panic(fmt.Sprint("Could not parse ", file, ": ", err))
+			}
+		}
+	}
+	templateSets[key] = set
+	return set
+}
+
+// Returns the dirs that templates are read from, in order of lowest to highest precedence: the
+// [RepoTemplatesDir] of the repository, if in one, and then the user config dir.
+func GetTemplateDirs() []string {
+	dirs := make([]string, 0, 2)
+	if repoConfigFile := util.GetRepoConfigFile(); repoConfigFile != "" {
+		dirs = append(dirs, filepath.Join(filepath.Dir(repoConfigFile), RepoTemplatesDir))
+	}
+	return append(dirs, util.GetUserConfigDir())
+}
+
+// Returns the data of the PR templates for commitHash, where stack is the local stack of commits
+// from bottom to top, see [GetStack].
+func getPullRequestTemplateData(commitHash string, featureFlag string, stack []StackPullRequest) templateData {
 	commitSummary := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--no-patch", "--format=%s", commitHash))
-	commitBody := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--no-patch", "--format=%b", commitHash))
+	commitBody := strings.TrimSpace(RemoveChangeId(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--no-patch", "--format=%b", commitHash)))
 	commitSummaryCleaned := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "show", "--no-patch", "--format=%f", commitHash))
 	expression := regexp.MustCompile(`^(\S+-[[:digit:]]+ )?(.*)`)
 	summaryMatches := expression.FindStringSubmatch(commitSummary)
-	return templateData{
-		Username:                   util.GetUsername(),
-		TicketNumber:               strings.TrimSpace(summaryMatches[1]),
-		CommitBody:                 commitBody,
-		CommitSummary:              commitSummary,
-		CommitSummaryWithoutTicket: summaryMatches[2],
-		CommitSummaryCleaned:       commitSummaryCleaned,
-		FeatureFlag:                featureFlag,
+	changedFiles := util.FilterSlice(strings.Split(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--name-only", "-z", "--format=", commitHash), "\x00"), func(filename string) bool {
+		return filename != ""
+	})
+	diffStat := strings.TrimRight(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--stat", "--format=", commitHash), "\n")
+	fullCommit := strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "rev-parse", commitHash))
+	data := getStackTemplateData(stack, func(next StackPullRequest) bool {
+		return strings.HasPrefix(fullCommit, next.Commit)
+	})
+	data.Username = util.GetUsername()
+	data.TicketNumber = strings.TrimSpace(summaryMatches[1])
+	data.CommitBody = commitBody
+	data.CommitSummary = commitSummary
+	data.CommitSummaryWithoutTicket = summaryMatches[2]
+	data.CommitSummaryCleaned = commitSummaryCleaned
+	data.FeatureFlag = featureFlag
+	data.JiraUrl = util.GetConfigString("jira-url")
+	data.ChangeId = strings.TrimSpace(util.ExecuteOrDie(util.ExecuteOptions{}, "git", "--no-pager", "show", "--no-patch",
+		"--format=%(trailers:key="+ChangeIdTrailer+",valueonly,separator=%x2C)", commitHash))
+	data.ChangedFiles = changedFiles
+	data.DiffStat = diffStat
+	data.CodeOwners = getCodeOwners(changedFiles)
+	return data
+}
+
+// Returns the owners of changedFiles in the CODEOWNERS file, sorted, or an empty slice if there is
+// no CODEOWNERS file.
+func getCodeOwners(changedFiles []string) []string {
+	codeOwners, ok := util.ReadCodeOwners()
+	if !ok {
+		return []string{}
+	}
+	owners := make([]string, 0)
+	for _, filename := range changedFiles {
+		for _, group := range codeOwners.GetOwners(filename) {
+			owners = append(owners, group.Owners...)
+		}
 	}
+	slices.Sort(owners)
+	return slices.Compact(owners)
 }
 
-func getBranchTemplateData(sanitizedSummary string) branchTemplateData {
+func getBranchTemplateData(sanitizedSummary string, changeId string) branchTemplateData {
 	// Dots are not allowed in branch names of some Github configurations.
 	username := strings.ReplaceAll(util.GetUsername(), ".", "-")
 	return branchTemplateData{
 		UsernameCleaned:      username,
 		CommitSummaryCleaned: sanitizedSummary,
-	}
-}
-
-func getConfigFile(filenameWithoutPath string) *string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		panic(fmt.Sprint("Could not get home dir", err))
-	}
-	fullPath := home + "/.gh-stacked-diff/" + filenameWithoutPath
-	if _, err := os.Stat(fullPath); err == nil {
-		return &fullPath
-	} else {
-		return nil
+		ChangeId:             changeId,
 	}
 }
@@ -0,0 +1,35 @@
+package testutil
+
+import (
+	"github.com/joshallenit/gh-stacked-diff/v2/util"
+)
+
+// Fakes that the PR of branchName is open, with numChecks passing checks and with approvers
+// having approved the latest commit of the branch.
+func SetOpenPullRequest(branchName string, numChecks int, approvers ...string) {
+	pullRequest := util.PullRequest{
+		HeadBranch: branchName,
+		BaseBranch: util.GetMainBranchOrDie(),
+		State:      util.PullRequestStateOpen,
+		HeadCommit: util.GetBranchLatestCommit(branchName),
+	}
+	for range numChecks {
+		pullRequest.Checks = append(pullRequest.Checks, util.PullRequestCheck{Status: "COMPLETED", Conclusion: "SUCCESS"})
+	}
+	for _, approver := range approvers {
+		pullRequest.Reviews = append(pullRequest.Reviews,
+			util.PullRequestReview{Author: approver, State: "APPROVED", Commit: pullRequest.HeadCommit})
+	}
+	fakeGithubClient.SetPullRequest(pullRequest)
+}
+
+// Fakes that the PR of branchName was merged into main via mergeCommit.
+func SetMergedPullRequest(branchName string, mergeCommit string) {
+	fakeGithubClient.SetPullRequest(util.PullRequest{
+		HeadBranch:  branchName,
+		BaseBranch:  util.GetMainBranchOrDie(),
+		State:       util.PullRequestStateMerged,
+		HeadCommit:  util.GetBranchLatestCommit(branchName),
+		MergeCommit: mergeCommit,
+	})
+}
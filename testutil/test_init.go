@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/joshallenit/gh-stacked-diff/v2/interactive"
+	"github.com/joshallenit/gh-stacked-diff/v2/templates"
 	"github.com/joshallenit/gh-stacked-diff/v2/util"
 )
 
@@ -22,6 +23,8 @@ const InitialCommitSubject = "Initial empty commit"
 
 var TestWorkingDir string
 var thisFile string
+var fakeGithubClient *util.FakeGithubClient
+var fakeNotifier *util.FakeNotifier
 
 func init() {
 	_, file, _, ok := runtime.Caller(0)
@@ -38,7 +41,7 @@ func init() {
 	os.Mkdir(TestWorkingDir, os.ModePerm)
 }
 
-// CD into repository directory and set any global DI variables (slog, sleep, and executor).
+// CD into repository directory and set any global DI variables (slog, sleep, executor, Github client, and notifier).
 func InitTest(t *testing.T, logLevel slog.Level) *util.TestExecutor {
 	handler := util.NewPrettyHandler(os.Stdout, slog.HandlerOptions{Level: logLevel})
 	slog.SetDefault(slog.New(handler))
@@ -46,8 +49,15 @@ func InitTest(t *testing.T, logLevel slog.Level) *util.TestExecutor {
 
 	// Set new TestExecutor in case previous test has faked any of the git responses.
 	testExecutor := setTestExecutor()
+	fakeGithubClient = util.NewFakeGithubClient()
+	util.SetGlobalGithubClient(fakeGithubClient)
+	fakeNotifier = &util.FakeNotifier{}
+	util.SetGlobalNotifier(fakeNotifier)
 
 	cdTestRepo(testFunctionName)
+	util.ResetStackStores()
+	templates.ResetTemplates()
+	util.SetUserConfigDir(filepath.Join(TestWorkingDir, testFunctionName, "user-config"))
 	// Setup author config in case it is not set on machine.
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "config", "user.email", "unit-test@example.com")
 	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "config", "user.name", "Unit Test")
@@ -62,6 +72,24 @@ func InitTest(t *testing.T, logLevel slog.Level) *util.TestExecutor {
 	return testExecutor
 }
 
+// Adds a remote named "fork" that belongs to owner and pushes branches to it instead of origin.
+func AddForkRemote(t *testing.T, owner string) {
+	forkDir := filepath.Join("..", owner, "forked-repo.git")
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "init", "--bare", forkDir)
+	util.ExecuteOrDie(util.ExecuteOptions{}, "git", "remote", "add", "fork", forkDir)
+	t.Setenv(util.GetConfigEnvVariable(util.GetConfigKey("push-remote")), "fork")
+}
+
+// Returns the fake Github client set by [InitTest].
+func GetFakeGithubClient() *util.FakeGithubClient {
+	return fakeGithubClient
+}
+
+// Returns the fake notifier set by [InitTest], which records the notifications of the test.
+func GetFakeNotifier() *util.FakeNotifier {
+	return fakeNotifier
+}
+
 func getTestFunctionName() string {
 	var functionName string
 	for i := 0; i < 10; i++ {
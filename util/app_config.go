@@ -18,6 +18,29 @@ type AppConfig struct {
 	AppExecutable string         // Path of this executable.
 	Exit          func(code int) // Call os.Exit with the given code, or panic during unit tests.
 	UserCacheDir  string         // os.UserCacheDir or a dir specific for each test in unit tests.
+	Output        OutputFormat   // Format of the output of read-only commands, set by "--output".
+}
+
+// Format of the output of read-only commands.
+type OutputFormat string
+
+const (
+	// Human readable output. The default.
+	OutputFormatText OutputFormat = "text"
+	// JSON array of records, for use by scripts and editor integrations.
+	OutputFormatJson OutputFormat = "json"
+	// Tab separated values with a header row.
+	OutputFormatTsv OutputFormat = "tsv"
+)
+
+// Returns whether the output format is of a known type.
+func (format OutputFormat) IsValid() bool {
+	switch format {
+	case OutputFormatText, OutputFormatJson, OutputFormatTsv:
+		return true
+	default:
+		return false
+	}
 }
 
 type AsyncAppConfig struct {
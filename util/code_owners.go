@@ -0,0 +1,189 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hairyhenderson/go-codeowners"
+)
+
+// Locations of the CODEOWNERS file, relative to the root of the repository, in the order that they
+// are searched. Github uses the first of .github/, the root, and docs/, and GitLab also supports
+// .gitlab/.
+var codeOwnersLocations = []string{
+	filepath.Join(".github", "CODEOWNERS"),
+	"CODEOWNERS",
+	filepath.Join("docs", "CODEOWNERS"),
+	filepath.Join(".gitlab", "CODEOWNERS"),
+}
+
+// Header of a GitLab section such as "[Docs]", "^[Docs]" for an optional section, or "[Docs][2]"
+// for a section that requires two approvals, optionally followed by the default owners.
+var codeOwnersSectionRegexp = regexp.MustCompile(`^(\^)?\[([^\]]+)\](?:\[(\d+)\])?(.*)$`)
+
+// Rules of a CODEOWNERS file.
+type CodeOwners struct {
+	// Path of the file, relative to the root of the repository.
+	Filename string
+	sections []codeOwnersSection
+}
+
+// Section of a CODEOWNERS file. Each section is matched separately, so a file can have owners in
+// more than one of them. Github does not support sections, so all of its rules are in a single
+// section without a name, as are the rules before the first section of a GitLab file.
+type codeOwnersSection struct {
+	name          string
+	optional      bool
+	approvals     int
+	defaultOwners []string
+	rules         codeowners.Codeowners
+}
+
+// Owners of a file from one section of a CODEOWNERS file, any of whom can approve changes to it.
+type CodeOwnerGroup struct {
+	// Name of the GitLab section, empty if the file has no sections.
+	Section string
+	// Whether approval is optional, as set by a "^[Section]" in GitLab.
+	Optional bool
+	// Number of approvals required, as set by a "[Section][2]" in GitLab.
+	Approvals int
+	// Owners such as "@octocat", "@org/team", or an email.
+	Owners []string
+}
+
+// Returns the owners separated by commas, prefixed by the section if any, for example
+// "[Docs] @alice,@bob".
+func (group CodeOwnerGroup) String() string {
+	owners := strings.Join(group.Owners, ",")
+	if group.Section == "" {
+		return owners
+	}
+	prefix := "[" + group.Section + "]"
+	if group.Optional {
+		prefix = "^" + prefix
+	}
+	return prefix + " " + owners
+}
+
+// Returns the users in approvers that are owners of group. Teams are not expanded to their
+// members, so a group owned only by teams has no approvers.
+func (group CodeOwnerGroup) GetApprovers(approvers []string) []string {
+	return FilterSlice(approvers, func(approver string) bool {
+		return slices.ContainsFunc(group.Owners, func(owner string) bool {
+			reviewer, ok := GetCodeOwnerReviewer(owner)
+			return ok && strings.EqualFold(reviewer, approver)
+		})
+	})
+}
+
+// Returns whether enough of approvers are owners of group.
+func (group CodeOwnerGroup) IsApproved(approvers []string) bool {
+	return len(group.GetApprovers(approvers)) >= group.Approvals
+}
+
+// Returns the CODEOWNERS file of the repository in the current directory, searching the locations
+// supported by Github and GitLab. Returns false if there is none.
+func ReadCodeOwners() (CodeOwners, bool) {
+	root := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "rev-parse", "--show-toplevel"))
+	for _, location := range codeOwnersLocations {
+		file, err := os.Open(filepath.Join(root, location))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			panic("Could not read " + location + ": " + err.Error())
+		}
+		defer file.Close()
+		codeOwners := ParseCodeOwners(file)
+		codeOwners.Filename = filepath.ToSlash(location)
+		return codeOwners, true
+	}
+	return CodeOwners{}, false
+}
+
+// Parses a CODEOWNERS file in Github format, or in GitLab format with sections.
+func ParseCodeOwners(reader io.Reader) CodeOwners {
+	codeOwners := CodeOwners{sections: []codeOwnersSection{{approvals: 1}}}
+	section := &codeOwners.sections[0]
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if match := codeOwnersSectionRegexp.FindStringSubmatch(line); match != nil {
+			section = getCodeOwnersSection(&codeOwners, match)
+			continue
+		}
+		fields := splitCodeOwnersLine(line)
+		owners := fields[1:]
+		if len(owners) == 0 {
+			owners = section.defaultOwners
+		}
+		rule, _ := codeowners.NewCodeowner(fields[0], owners)
+		section.rules.Patterns = append(section.rules.Patterns, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		panic("Could not read CODEOWNERS: " + err.Error())
+	}
+	return codeOwners
+}
+
+// Returns the section of the header in match, adding it to codeOwners unless a section with the
+// same name was already added, as GitLab combines them.
+func getCodeOwnersSection(codeOwners *CodeOwners, match []string) *codeOwnersSection {
+	name := strings.TrimSpace(match[2])
+	index := slices.IndexFunc(codeOwners.sections, func(next codeOwnersSection) bool {
+		return strings.EqualFold(next.name, name)
+	})
+	if index == -1 {
+		approvals := 1
+		if match[3] != "" {
+			approvals, _ = strconv.Atoi(match[3])
+		}
+		codeOwners.sections = append(codeOwners.sections, codeOwnersSection{
+			name:          name,
+			optional:      match[1] != "",
+			approvals:     approvals,
+			defaultOwners: strings.Fields(match[4]),
+		})
+		index = len(codeOwners.sections) - 1
+	}
+	return &codeOwners.sections[index]
+}
+
+// Returns the pattern and owners of line, keeping spaces escaped with "\" in the pattern.
+func splitCodeOwnersLine(line string) []string {
+	fields := strings.Fields(line)
+	for len(fields) > 1 && strings.HasSuffix(fields[0], `\`) {
+		fields = append([]string{strings.TrimSuffix(fields[0], `\`) + " " + fields[1]}, fields[2:]...)
+	}
+	return fields
+}
+
+// Returns the owners of filename, a path relative to the root of the repository, from each section
+// that has a rule for it. Returns an empty slice if it has no owners.
+func (c CodeOwners) GetOwners(filename string) []CodeOwnerGroup {
+	groups := make([]CodeOwnerGroup, 0)
+	for _, section := range c.sections {
+		owners := section.rules.Owners(filename)
+		if len(owners) == 0 {
+			continue
+		}
+		groups = append(groups, CodeOwnerGroup{
+			Section:   section.name,
+			Optional:  section.optional,
+			Approvals: section.approvals,
+			Owners:    owners,
+		})
+	}
+	return groups
+}
@@ -0,0 +1,39 @@
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCodeOwners_WithGitlabSections_MatchesEachSection(t *testing.T) {
+	assert := assert.New(t)
+	codeOwners := ParseCodeOwners(strings.NewReader(
+		"# Comment\n" +
+			"*.go @alice\n" +
+			"[Docs] @docs-team\n" +
+			"*.md\n" +
+			"^[Optional] @bob\n" +
+			"docs/ @carol\n" +
+			"[Security][2]\n" +
+			"docs/my\\ file.md @dave @erin\n"))
+
+	assert.Equal([]CodeOwnerGroup{
+		{Section: "Docs", Approvals: 1, Owners: []string{"@docs-team"}},
+		{Section: "Optional", Optional: true, Approvals: 1, Owners: []string{"@carol"}},
+		{Section: "Security", Approvals: 2, Owners: []string{"@dave", "@erin"}},
+	}, codeOwners.GetOwners("docs/my file.md"))
+	assert.Equal([]CodeOwnerGroup{{Approvals: 1, Owners: []string{"@alice"}}}, codeOwners.GetOwners("main.go"))
+	assert.Equal([]CodeOwnerGroup{}, codeOwners.GetOwners("main.py"))
+}
+
+func TestCodeOwnerGroup_IsApproved_RequiresApprovalsFromOwners(t *testing.T) {
+	assert := assert.New(t)
+	group := CodeOwnerGroup{Section: "Security", Approvals: 2, Owners: []string{"@dave", "@Erin", "@my-org/security"}}
+
+	assert.Equal([]string{"erin"}, group.GetApprovers([]string{"alice", "erin"}))
+	assert.False(group.IsApproved([]string{"alice", "erin"}))
+	assert.True(group.IsApproved([]string{"dave", "erin"}))
+	assert.Equal("[Security] @dave,@Erin,@my-org/security", group.String())
+}
@@ -0,0 +1,385 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Name of the config file that is committed to a repository.
+const RepoConfigFilename = ".sd.yaml"
+
+// Name of the config file in the user config dir.
+const UserConfigFilename = "config.yaml"
+
+// Prefix of the environment variables that override config values, for example SD_MIN_CHECKS.
+const configEnvPrefix = "SD_"
+
+// Type of a config value, used to validate it.
+type ConfigType string
+
+const (
+	ConfigTypeString   ConfigType = "string"
+	ConfigTypeBool     ConfigType = "bool"
+	ConfigTypeInt      ConfigType = "int"
+	ConfigTypeDuration ConfigType = "duration"
+)
+
+// Where a config value came from, in order of lowest to highest precedence.
+// Command line flags have the highest precedence of all.
+type ConfigSource string
+
+const (
+	ConfigSourceDefault ConfigSource = "default"
+	ConfigSourceRepo    ConfigSource = "repo"
+	ConfigSourceUser    ConfigSource = "user"
+	ConfigSourceEnv     ConfigSource = "env"
+)
+
+// A config setting that can be set in any of the [ConfigSource].
+type ConfigKey struct {
+	Name        string
+	Type        ConfigType
+	Default     string
+	Description string
+}
+
+// All of the config settings.
+var configKeys = []ConfigKey{
+	{Name: "base-remote", Type: ConfigTypeString, Default: "origin",
+		Description: "Remote that PRs are opened against and that main is synced from"},
+	{Name: "change-id", Type: ConfigTypeBool, Default: "false",
+		Description: "Whether \"sd new\" adds a Stacked-Diff-Id trailer that branches are named after"},
+	{Name: "check-retries", Type: ConfigTypeInt, Default: "0",
+		Description: "Number of times \"sd add-reviewers\" re-runs failed checks before giving up"},
+	{Name: "draft", Type: ConfigTypeBool, Default: "true",
+		Description: "Whether to create new PRs as draft"},
+	{Name: "forge", Type: ConfigTypeString, Default: "",
+		Description: "Where PRs are hosted: github or gitlab, empty to detect from base-remote"},
+	{Name: "forge-url", Type: ConfigTypeString, Default: "",
+		Description: "URL of the GitLab server, empty to use the host of base-remote"},
+	{Name: "jira-url", Type: ConfigTypeString, Default: "https://jira.tinyspeck.com/browse/",
+		Description: "URL that the ticket number is appended to in the PR description"},
+	{Name: "merge-method", Type: ConfigTypeString, Default: "squash",
+		Description: "How \"sd land\" merges PRs: squash, rebase, or merge"},
+	{Name: "min-approvals", Type: ConfigTypeInt, Default: "1",
+		Description: "Minimum number of approvals required by \"sd land\""},
+	{Name: "min-checks", Type: ConfigTypeInt, Default: "-1",
+		Description: "Minimum number of checks to wait for, -1 to use the average of merged PRs"},
+	{Name: "notifier", Type: ConfigTypeString, Default: "auto",
+		Description: "How to notify: comma-separated auto, bell, command, desktop, none, say, or webhook"},
+	{Name: "notify-command", Type: ConfigTypeString, Default: "",
+		Description: "Shell command run by the command notifier, with SD_NOTIFICATION_* variables set"},
+	{Name: "notify-webhook", Type: ConfigTypeString, Default: "",
+		Description: "URL that the webhook notifier posts Slack compatible JSON to"},
+	{Name: "out-of-office", Type: ConfigTypeString, Default: "",
+		Description: "Comma-separated list of Github usernames to not add as reviewers"},
+	{Name: "pick-reviewers", Type: ConfigTypeInt, Default: "0",
+		Description: "Number of reviewers to pick from reviewers for each PR, 0 to add all of them"},
+	{Name: "pick-strategy", Type: ConfigTypeString, Default: "round-robin",
+		Description: "How to pick reviewers: round-robin, fewest-requests, or random"},
+	{Name: "poll-frequency", Type: ConfigTypeDuration, Default: "30s",
+		Description: "How often to poll Github for the status of PRs"},
+	{Name: "push-remote", Type: ConfigTypeString, Default: "",
+		Description: "Remote to push branches to, such as a fork, empty to use base-remote"},
+	{Name: "reviewer-groups", Type: ConfigTypeString, Default: "",
+		Description: "Groups to use as \"@name\" in reviewers, for example \"web=alice,bob;ios=carol\""},
+	{Name: "reviewers", Type: ConfigTypeString, Default: "",
+		Description: "Comma-separated list of Github usernames, teams, or @groups to add as reviewers"},
+	{Name: "silent", Type: ConfigTypeBool, Default: "false",
+		Description: "Whether to be silent instead of sending notifications, see notifier"},
+	{Name: "stack", Type: ConfigTypeBool, Default: "false",
+		Description: "Whether to stack new PRs on top of the PR of the commit below"},
+}
+
+// A config value and where it came from.
+type ConfigValue struct {
+	Key    ConfigKey
+	Value  string
+	Source ConfigSource
+	// File or environment variable that the value came from. Empty for defaults.
+	Location string
+}
+
+// Returns a description of where the value came from, for example "user (~/.gh-stacked-diff/config.yaml)".
+func (value ConfigValue) SourceDescription() string {
+	if value.Location == "" {
+		return string(value.Source)
+	}
+	return string(value.Source) + " (" + value.Location + ")"
+}
+
+var userConfigDir string
+
+// Returns the dir that contains the user config file and templates, ~/.gh-stacked-diff.
+func GetUserConfigDir() string {
+	if userConfigDir != "" {
+		return userConfigDir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		panic(fmt.Sprint("Could not get home dir", err))
+	}
+	return filepath.Join(home, ".gh-stacked-diff")
+}
+
+// Override the default user config dir. For use by tests.
+func SetUserConfigDir(dir string) {
+	userConfigDir = dir
+	configFilesMutex.Lock()
+	defer configFilesMutex.Unlock()
+	clear(configFiles)
+}
+
+// Returns all the config settings, sorted by name.
+func GetConfigKeys() []ConfigKey {
+	return slices.Clone(configKeys)
+}
+
+// Returns the config setting named name. Panics if there is no such setting.
+func GetConfigKey(name string) ConfigKey {
+	index := slices.IndexFunc(configKeys, func(key ConfigKey) bool {
+		return key.Name == name
+	})
+	if index == -1 {
+		panic("Unknown config key " + name + ", use \"sd config list\" to see all keys")
+	}
+	return configKeys[index]
+}
+
+// Cached result of [GetRepoConfigFile] keyed by working directory, as it is called for the default
+// value of many flags.
+var repoConfigFiles = make(map[string]string)
+
+// Returns the file of the config that is committed to the repository, or "" if not in a repository.
+func GetRepoConfigFile() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		panic(err)
+	}
+	if configFile, ok := repoConfigFiles[wd]; ok {
+		return configFile
+	}
+	configFile := ""
+	if topLevel, err := Execute(ExecuteOptions{}, "git", "rev-parse", "--show-toplevel"); err == nil {
+		configFile = filepath.Join(strings.TrimSpace(topLevel), RepoConfigFilename)
+	}
+	repoConfigFiles[wd] = configFile
+	return configFile
+}
+
+func GetUserConfigFile() string {
+	return filepath.Join(GetUserConfigDir(), UserConfigFilename)
+}
+
+// Returns the environment variable that overrides key.
+func GetConfigEnvVariable(key ConfigKey) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(key.Name, "-", "_"))
+}
+
+// Returns the value of the config setting named name from the source with highest precedence.
+func GetConfigValue(name string) ConfigValue {
+	return getConfigValue(GetConfigKey(name), readConfigFile(GetRepoConfigFile()), readConfigFile(GetUserConfigFile()))
+}
+
+// Returns the values of all config settings.
+func GetConfigValues() []ConfigValue {
+	repoConfig := readConfigFile(GetRepoConfigFile())
+	userConfig := readConfigFile(GetUserConfigFile())
+	return MapSlice(configKeys, func(key ConfigKey) ConfigValue {
+		return getConfigValue(key, repoConfig, userConfig)
+	})
+}
+
+func getConfigValue(key ConfigKey, repoConfig map[string]string, userConfig map[string]string) ConfigValue {
+	if env, ok := os.LookupEnv(GetConfigEnvVariable(key)); ok {
+		return ConfigValue{Key: key, Value: env, Source: ConfigSourceEnv, Location: GetConfigEnvVariable(key)}
+	}
+	if value, ok := userConfig[key.Name]; ok {
+		return ConfigValue{Key: key, Value: value, Source: ConfigSourceUser, Location: GetUserConfigFile()}
+	}
+	if value, ok := repoConfig[key.Name]; ok {
+		return ConfigValue{Key: key, Value: value, Source: ConfigSourceRepo, Location: GetRepoConfigFile()}
+	}
+	return ConfigValue{Key: key, Value: key.Default, Source: ConfigSourceDefault}
+}
+
+func GetConfigString(name string) string {
+	return GetConfigValue(name).Value
+}
+
+func GetConfigBool(name string) bool {
+	value := GetConfigValue(name)
+	parsed, err := strconv.ParseBool(value.Value)
+	if err != nil {
+		panic(invalidConfigMessage(value, err))
+	}
+	return parsed
+}
+
+func GetConfigInt(name string) int {
+	value := GetConfigValue(name)
+	parsed, err := strconv.Atoi(value.Value)
+	if err != nil {
+		panic(invalidConfigMessage(value, err))
+	}
+	return parsed
+}
+
+func GetConfigDuration(name string) time.Duration {
+	value := GetConfigValue(name)
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		panic(invalidConfigMessage(value, err))
+	}
+	return parsed
+}
+
+func invalidConfigMessage(value ConfigValue, err error) string {
+	return fmt.Sprint("Invalid value for ", value.Key.Name, " from ", value.SourceDescription(), ": ", err)
+}
+
+// Returns an error if value is not valid for the type of key.
+func ValidateConfigValue(key ConfigKey, value string) error {
+	var err error
+	switch key.Type {
+	case ConfigTypeBool:
+		_, err = strconv.ParseBool(value)
+	case ConfigTypeInt:
+		_, err = strconv.Atoi(value)
+	case ConfigTypeDuration:
+		_, err = time.ParseDuration(value)
+	}
+	return err
+}
+
+// Sets the config setting named name in the file of source, which is either [ConfigSourceRepo] or
+// [ConfigSourceUser]. Any other values and comments in the file are kept. Returns the file.
+func SetConfigValue(name string, value string, source ConfigSource) string {
+	key := GetConfigKey(name)
+	if err := ValidateConfigValue(key, value); err != nil {
+		panic(fmt.Sprint("Invalid value for ", name, ": ", err))
+	}
+	var configFile string
+	switch source {
+	case ConfigSourceRepo:
+		configFile = GetRepoConfigFile()
+		if configFile == "" {
+			panic("Not in a git repository")
+		}
+	case ConfigSourceUser:
+		configFile = GetUserConfigFile()
+	default:
+		panic("Cannot set config in " + string(source))
+	}
+	var document yaml.Node
+	data, err := os.ReadFile(configFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		panic("Could not read " + configFile + ": " + err.Error())
+	}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		panic("Could not parse " + configFile + ": " + err.Error())
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	mapping := document.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		panic("Expected " + configFile + " to contain a mapping of keys to values")
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: getConfigYamlTag(key), Value: value}
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			mapping.Content[i+1] = valueNode
+			found = true
+		}
+	}
+	if !found {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}, valueNode)
+	}
+	out, err := yaml.Marshal(&document)
+	if err != nil {
+		panic(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(configFile), os.ModePerm); err != nil {
+		panic("Could not create directory for " + configFile + ": " + err.Error())
+	}
+	if err := os.WriteFile(configFile, out, 0644); err != nil {
+		panic("Could not write " + configFile + ": " + err.Error())
+	}
+	configFilesMutex.Lock()
+	defer configFilesMutex.Unlock()
+	delete(configFiles, configFile)
+	return configFile
+}
+
+func getConfigYamlTag(key ConfigKey) string {
+	switch key.Type {
+	case ConfigTypeBool:
+		return "!!bool"
+	case ConfigTypeInt:
+		return "!!int"
+	default:
+		return "!!str"
+	}
+}
+
+// Values of the config files that have been read, keyed by file, so that each is only read once.
+var configFiles = make(map[string]map[string]string)
+
+var configFilesMutex sync.Mutex
+
+// Returns the values of configFile keyed by name, or an empty map if configFile does not exist.
+func readConfigFile(configFile string) map[string]string {
+	configFilesMutex.Lock()
+	defer configFilesMutex.Unlock()
+	if values, ok := configFiles[configFile]; ok {
+		return values
+	}
+	values := parseConfigFile(configFile)
+	configFiles[configFile] = values
+	return values
+}
+
+func parseConfigFile(configFile string) map[string]string {
+	values := make(map[string]string)
+	if configFile == "" {
+		return values
+	}
+	data, err := os.ReadFile(configFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return values
+	}
+	if err != nil {
+		panic("Could not read " + configFile + ": " + err.Error())
+	}
+	var parsed map[string]any
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		panic("Could not parse " + configFile + ": " + err.Error())
+	}
+	for name, value := range parsed {
+		if !slices.ContainsFunc(configKeys, func(key ConfigKey) bool {
+			return key.Name == name
+		}) {
+			slog.Debug("Ignoring unknown config key " + name + " in " + configFile)
+			continue
+		}
+		if value == nil {
+			values[name] = ""
+		} else {
+			values[name] = fmt.Sprint(value)
+		}
+	}
+	return values
+}
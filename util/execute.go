@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 )
 
@@ -16,6 +17,8 @@ type ExecuteOptions struct {
 	Io StdIo
 	// For example "MY_VAR=some_value"
 	EnvironmentVariables []string
+	// Working directory of the program, empty for the current directory.
+	Dir string
 }
 
 // Provides a simple way to execute shell commands.
@@ -37,6 +40,7 @@ func SetGlobalExecutor(executor Executor) {
 // Implementation of Execute that uses [exec.Command].
 func (defaultExecutor DefaultExecutor) Execute(options ExecuteOptions, programName string, args ...string) (string, error) {
 	cmd := exec.Command(programName, args...)
+	cmd.Dir = options.Dir
 	if options.EnvironmentVariables != nil {
 		cmd.Env = append(os.Environ(), options.EnvironmentVariables...)
 	}
@@ -63,7 +67,13 @@ func (defaultExecutor DefaultExecutor) Execute(options ExecuteOptions, programNa
 }
 
 // Executes a shell program with arguments.
+// gh uses the repository of the base remote, see [GetBaseRemote].
 func Execute(options ExecuteOptions, programName string, args ...string) (string, error) {
+	if programName == "gh" {
+		if environmentVariables := getGhEnvironmentVariables(); environmentVariables != nil {
+			options.EnvironmentVariables = append(slices.Clone(options.EnvironmentVariables), environmentVariables...)
+		}
+	}
 	return globalExecutor.Execute(options, programName, args...)
 }
 
@@ -0,0 +1,102 @@
+package util
+
+import (
+	"slices"
+	"sync"
+)
+
+// Fake [GithubClient] for testing.
+type FakeGithubClient struct {
+	mu           sync.Mutex
+	pullRequests map[string]PullRequest
+	username     string
+	// Number of open review requests of each reviewer.
+	reviewRequestCounts map[string]int
+	// Number of times GetPullRequests was called.
+	GetPullRequestsCalls int
+}
+
+// Ensure that [FakeGithubClient] implements [GithubClient].
+var _ GithubClient = &FakeGithubClient{}
+
+func NewFakeGithubClient() *FakeGithubClient {
+	return &FakeGithubClient{
+		pullRequests:        make(map[string]PullRequest),
+		username:            "unit-test",
+		reviewRequestCounts: make(map[string]int),
+	}
+}
+
+// Adds or replaces the PR for pullRequest.HeadBranch.
+func (c *FakeGithubClient) SetPullRequest(pullRequest PullRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if pullRequest.Reviews == nil {
+		pullRequest.Reviews = []PullRequestReview{}
+	}
+	if pullRequest.Checks == nil {
+		pullRequest.Checks = []PullRequestCheck{}
+	}
+	c.pullRequests[pullRequest.HeadBranch] = pullRequest
+}
+
+// Sets the login returned by [FakeGithubClient.GetLoggedInUsername].
+func (c *FakeGithubClient) SetLoggedInUsername(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username = username
+}
+
+// Sets the count returned by [FakeGithubClient.GetReviewRequestCounts] for reviewer.
+func (c *FakeGithubClient) SetReviewRequestCount(reviewer string, count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reviewRequestCounts[reviewer] = count
+}
+
+func (c *FakeGithubClient) GetPullRequests(branchNames []string) map[string]PullRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.GetPullRequestsCalls++
+	pullRequests := make(map[string]PullRequest)
+	for _, branchName := range branchNames {
+		if pullRequest, ok := c.pullRequests[branchName]; ok {
+			pullRequests[branchName] = pullRequest
+		}
+	}
+	return pullRequests
+}
+
+// Returns the PRs set via [FakeGithubClient.SetPullRequest] that are merged into baseBranch.
+// author is ignored.
+func (c *FakeGithubClient) GetMergedPullRequests(baseBranch string, author string) []PullRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	merged := make([]PullRequest, 0)
+	for _, pullRequest := range c.pullRequests {
+		if pullRequest.State == PullRequestStateMerged && pullRequest.BaseBranch == baseBranch {
+			merged = append(merged, pullRequest)
+		}
+	}
+	slices.SortFunc(merged, func(a PullRequest, b PullRequest) int {
+		return b.Number - a.Number
+	})
+	return merged
+}
+
+func (c *FakeGithubClient) GetLoggedInUsername() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.username
+}
+
+// Returns the counts set via [FakeGithubClient.SetReviewRequestCount], 0 for other reviewers.
+func (c *FakeGithubClient) GetReviewRequestCounts(reviewers []string) map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	counts := make(map[string]int)
+	for _, reviewer := range reviewers {
+		counts[reviewer] = c.reviewRequestCounts[reviewer]
+	}
+	return counts
+}
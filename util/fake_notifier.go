@@ -0,0 +1,28 @@
+package util
+
+import (
+	"slices"
+	"sync"
+)
+
+// Fake [Notifier] for testing that records notifications.
+type FakeNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+// Ensure that [FakeNotifier] implements [Notifier].
+var _ Notifier = &FakeNotifier{}
+
+func (n *FakeNotifier) Notify(notification Notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, notification)
+}
+
+// Returns the notifications received so far.
+func (n *FakeNotifier) Notifications() []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.notifications)
+}
@@ -0,0 +1,108 @@
+package util
+
+import (
+	"strings"
+	"sync"
+)
+
+// Options for [Forge.CreatePullRequest].
+type CreatePullRequestOptions struct {
+	Title string
+	Body  string
+	// Branch that the PR is merged into.
+	BaseBranch string
+	// Branch with the changes of the PR, see [GetPullRequestHead].
+	HeadBranch string
+	Draft      bool
+}
+
+// Options for [Forge.EditPullRequest]. Empty values are left unchanged.
+type EditPullRequestOptions struct {
+	Title      string
+	Body       string
+	BaseBranch string
+}
+
+// Hosting service of the repository, such as Github or GitLab, that PRs are opened on.
+// Allows swapping in a different implementation via Dependency Injection during tests.
+//
+// Methods that take a pullRequest accept either the number of the PR or its head branch.
+type Forge interface {
+	// Returns the most recent PR of each branch in branchNames. Branches without a PR are not
+	// included.
+	GetPullRequests(branchNames []string) map[string]PullRequest
+	// Returns the most recently merged PRs into baseBranch. If author is not empty then only PRs
+	// from that author are returned. Use "@me" for the logged in user.
+	GetMergedPullRequests(baseBranch string, author string) []PullRequest
+	// Returns the open PRs of the logged in user.
+	GetOpenPullRequests() []PullRequest
+	// Returns the head branch of pullRequest and the first of its commits.
+	GetPullRequestFirstCommit(pullRequest string) (string, string)
+	// Returns the login of the authenticated user.
+	GetLoggedInUsername() string
+	// Returns the logins of the users that can review PRs.
+	GetCollaborators() []string
+	// Returns the number of open PRs that each of reviewers has been requested to review.
+	GetReviewRequestCounts(reviewers []string) map[string]int
+	// Creates a PR and returns its URL.
+	CreatePullRequest(options CreatePullRequestOptions) string
+	EditPullRequest(pullRequest string, options EditPullRequestOptions)
+	// Marks a draft PR as ready for review.
+	MarkPullRequestReady(pullRequest string)
+	// Requests reviews from reviewers, which are usernames or team slugs such as "org/team", and
+	// returns the URL of the PR.
+	AddReviewers(pullRequest string, reviewers []string) string
+	// Merges pullRequest with method, which is "squash", "rebase", or "merge". If auto is set
+	// then it is merged once its checks pass instead.
+	MergePullRequest(pullRequest string, method string, auto bool)
+	// Closes pullRequest, leaving comment on it.
+	ClosePullRequest(pullRequest string, comment string)
+	// Opens pullRequest in the web browser.
+	OpenPullRequest(pullRequest string)
+	// Returns the log of the failed jobs of the run with runId, see [PullRequestCheck.RunId].
+	GetFailedCheckLog(runId string) string
+	// Re-runs the failed jobs of the run with runId.
+	RerunFailedChecks(runId string)
+}
+
+var globalForge Forge
+var globalForgeOnce *sync.Once = new(sync.Once)
+
+// Sets the forge that [GetForge] returns.
+func SetGlobalForge(forge Forge) {
+	globalForge = forge
+}
+
+// Returns the forge of the repository, as set by the "forge" config, or else detected from the
+// URL of the base remote.
+func GetForge() Forge {
+	if globalForge == nil {
+		globalForgeOnce.Do(func() {
+			forge := GetConfigString("forge")
+			if forge == "" {
+				forge = detectForge()
+			}
+			switch forge {
+			case "github":
+				globalForge = githubForge{}
+			case "gitlab":
+				globalForge = newGitlabForgeFromRemotes()
+			default:
+				panic("Unsupported forge " + forge + ", expected github or gitlab")
+			}
+		})
+	}
+	return globalForge
+}
+
+// Returns "gitlab" if the base remote is hosted on GitLab, otherwise "github".
+func detectForge() string {
+	url, err := Execute(ExecuteOptions{}, "git", "remote", "get-url", GetBaseRemote())
+	if err == nil {
+		host, _ := parseRemoteUrl(strings.TrimSpace(url))
+		if strings.Contains(host, "gitlab") {
+			return "gitlab"
+		}
+	}
+	return "github"
+}
@@ -1,9 +1,7 @@
 package util
 
 import (
-	"bufio"
 	"slices"
-	"strconv"
 	"strings"
 	"sync"
 )
@@ -15,6 +13,50 @@ type PullRequestChecksStatus struct {
 	Failing   int
 	Passing   int
 	MinChecks int
+	// Result of each check, in the order returned by Github.
+	Checks []CheckResult
+}
+
+// Result of a single check of a PR.
+type CheckResult struct {
+	Name string
+	// State of the check, for example "SUCCESS", "FAILURE", or "IN_PROGRESS".
+	Conclusion string
+	// Page with the details of the check, if any.
+	Url string
+	// See [PullRequestCheck.RunId].
+	RunId string
+}
+
+// Outcome of a check as counted by [PullRequestChecksStatus].
+type checkOutcome int
+
+const (
+	checkOutcomePending checkOutcome = iota
+	checkOutcomePassing
+	checkOutcomeFailing
+)
+
+/*
+ * Logic copied from https://github.com/cli/cli/blob/57fbe4f317ca7d0849eeeedb16c1abc21a81913b/api/queries_pr.go#L258-L274
+ */
+func (c CheckResult) outcome() checkOutcome {
+	switch c.Conclusion {
+	case "SUCCESS", "NEUTRAL", "SKIPPED":
+		return checkOutcomePassing
+	case "ERROR", "FAILURE", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED":
+		return checkOutcomeFailing
+	default: // "EXPECTED", "REQUESTED", "WAITING", "QUEUED", "PENDING", "IN_PROGRESS", "STALE"
+		return checkOutcomePending
+	}
+}
+
+func (c CheckResult) IsFailing() bool {
+	return c.outcome() == checkOutcomeFailing
+}
+
+func (c CheckResult) IsPending() bool {
+	return c.outcome() == checkOutcomePending
 }
 
 func (s PullRequestChecksStatus) PercentageComplete() float32 {
@@ -37,6 +79,11 @@ func (s PullRequestChecksStatus) Total() int {
 	return s.Failing + s.Passing + s.Pending
 }
 
+// Returns the checks that failed.
+func (s PullRequestChecksStatus) FailingChecks() []CheckResult {
+	return FilterSlice(s.Checks, CheckResult.IsFailing)
+}
+
 type PullRequestState int
 
 const (
@@ -45,7 +92,20 @@ const (
 	PullRequestStateClosed
 )
 
+func (state PullRequestState) String() string {
+	switch state {
+	case PullRequestStateOpen:
+		return "open"
+	case PullRequestStateMerged:
+		return "merged"
+	default:
+		return "closed"
+	}
+}
+
 type PullRequestStatus struct {
+	Number    int
+	Url       string
 	Checks    PullRequestChecksStatus
 	Approvers []string
 	State     PullRequestState
@@ -59,10 +119,15 @@ var repoNameWithOwnerOnce *sync.Once = new(sync.Once)
 var loggedInUsername string
 var loggedInUsernameOnce *sync.Once = new(sync.Once)
 
-// Returns "repository-owner/repository-name".
+// Returns "repository-owner/repository-name" of the repository that PRs are opened against. When
+// pushing to a fork this is the repository of the base remote, see [IsForkWorkflow].
 func GetRepoNameWithOwner() string {
 	if repoNameWithOwner == "" {
 		repoNameWithOwnerOnce.Do(func() {
+			if IsForkWorkflow() {
+				repoNameWithOwner = getRemoteNameWithOwner(GetBaseRemote())
+				return
+			}
 			out := ExecuteOrDie(ExecuteOptions{},
 				"gh", "repo", "view", "--json", "nameWithOwner", "--jq", ".nameWithOwner")
 			repoNameWithOwner = strings.TrimSpace(out)
@@ -71,68 +136,19 @@ func GetRepoNameWithOwner() string {
 	return repoNameWithOwner
 }
 
+// Returns the login of the user authenticated with Github.
 func GetLoggedInUsername() string {
 	if loggedInUsername == "" {
 		loggedInUsernameOnce.Do(func() {
-			out := ExecuteOrDie(ExecuteOptions{},
-				"gh", "api", "https://api.github.com/user", "--jq", ".login")
-			loggedInUsername = strings.TrimSpace(out)
+			loggedInUsername = GetForge().GetLoggedInUsername()
 		})
 	}
 	return loggedInUsername
 }
 
-/*
-Returns users that have already approved latest commit.
-
-Example output of gh pr view:
-
-$ gh pr view mybranch --json "reviews"
-
-	{
-	  "reviews": [
-	    {
-	      "id": "PRR_kwDODeVIac6f37Qq",
-	      "author": {
-	        "login": "mybestie"
-	      },
-	      "authorAssociation": "MEMBER",
-	      "body": "",
-	      "submittedAt": "2025-03-13T14:47:31Z",
-	      "includesCreatedEdit": false,
-	      "reactionGroups": [],
-	      "state": "COMMENTED",
-	      "commit": {
-	        "oid": "af01bdf8eb5649956096a608717f7de5eeb97e45"
-	      }
-	    },
-	    {
-	      "id": "PRR_kwDODeVIac6f5jeG",
-	      "author": {
-	        "login": "myfave"
-	      },
-	      "authorAssociation": "MEMBER",
-	      "body": "",
-	      "submittedAt": "2025-03-13T16:32:44Z",
-	      "includesCreatedEdit": false,
-	      "reactionGroups": [],
-	      "state": "APPROVED",
-	      "commit": {
-	        "oid": "af01bdf8eb5649956096a608717f7de5eeb97e45"
-	      }
-	    }
-	  ]
-	}
-*/
+// Returns users that have already approved latest commit.
 func GetAllApprovingUsers(branchName string) []string {
-	// Note: technically it is possible to query for more than one PR at a time but requires knowing a commit hash so not as reliable.
-	// gh pr list --search "429bb20,0ff019b" --state all
-	lastCommit := GetBranchLatestCommit(branchName)
-	jq := ".reviews[] | select(.state == \"APPROVED\" and .commit.oid == \"" + lastCommit + "\") | .author.login"
-	out := ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "view", branchName, "--json", "reviews", "--jq", jq)
-	approvingUsers := strings.Fields(out)
-	slices.Sort(approvingUsers)
-	return slices.Compact(approvingUsers)
+	return GetPullRequestStatus(branchName, 0).Approvers
 }
 
 // Returns full commit hash of branch with name of branchName, or "" if no such branch.
@@ -145,63 +161,44 @@ func GetBranchLatestCommit(branchName string) string {
 	}
 }
 
-/*
- * Logic copied from https://github.com/cli/cli/blob/57fbe4f317ca7d0849eeeedb16c1abc21a81913b/api/queries_pr.go#L258-L274
- */
+// Returns the status of the checks of the PR of branchName.
 func GetChecksStatus(branchName string, minChecks int) PullRequestChecksStatus {
-	if minChecks == -1 {
-		minChecks = getMinChecks()
-	}
-	summary := PullRequestChecksStatus{MinChecks: minChecks}
-	stateString := ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "view", branchName, "--json", "statusCheckRollup", "--jq", ".statusCheckRollup[] | .status, .conclusion, .state")
-	scanner := bufio.NewScanner(strings.NewReader(strings.TrimSpace(stateString)))
-	for scanner.Scan() {
-		status := scanner.Text()
-		scanner.Scan()
-		conclusion := scanner.Text()
-		scanner.Scan()
-		state := scanner.Text()
-		updatePullRequestChecksStatus(&summary, status, conclusion, state)
-	}
-	return summary
+	return GetPullRequestStatus(branchName, minChecks).Checks
 }
 
-func updatePullRequestChecksStatus(checks *PullRequestChecksStatus, status string, conclusion string, state string) {
+func updatePullRequestChecksStatus(checks *PullRequestChecksStatus, check PullRequestCheck) {
+	state := check.State
 	if state == "" {
-		if status == "COMPLETED" {
-			state = conclusion
+		if check.Status == "COMPLETED" {
+			state = check.Conclusion
 		} else {
-			state = status
+			state = check.Status
 		}
 	}
-	switch state {
-	case "SUCCESS", "NEUTRAL", "SKIPPED":
+	result := CheckResult{Name: check.Name, Conclusion: state, Url: check.Url, RunId: check.RunId}
+	switch result.outcome() {
+	case checkOutcomePassing:
 		checks.Passing++
-	case "ERROR", "FAILURE", "CANCELLED", "TIMED_OUT", "ACTION_REQUIRED":
+	case checkOutcomeFailing:
 		checks.Failing++
-	default: // "EXPECTED", "REQUESTED", "WAITING", "QUEUED", "PENDING", "IN_PROGRESS", "STALE"
+	default:
 		checks.Pending++
 	}
+	checks.Checks = append(checks.Checks, result)
 }
 
-func getMinChecks() int {
-	jq := ".[].statusCheckRollup | length"
-	out := ExecuteOrDie(ExecuteOptions{},
-		"gh", "pr", "list", "--state", "merged", "--base", GetMainBranchOrDie(),
-		"--json", "statusCheckRollup", "--jq", jq)
-	allNumChecks := strings.Fields(out)
-	if len(allNumChecks) == 0 {
+// Returns the minimum number of checks to wait for, based on the average number of checks of
+// merged PRs, up to [DEFAULT_MIN_CHECKS].
+func GetMinChecks() int {
+	mergedPullRequests := GetForge().GetMergedPullRequests(GetMainBranchOrDie(), "")
+	if len(mergedPullRequests) == 0 {
 		return 0
 	}
 	totalChecks := 0
-	for _, numChecksString := range allNumChecks {
-		numChecks, err := strconv.Atoi(numChecksString)
-		if err != nil {
-			panic(err)
-		}
-		totalChecks = totalChecks + numChecks
+	for _, pullRequest := range mergedPullRequests {
+		totalChecks = totalChecks + len(pullRequest.Checks)
 	}
-	avg := totalChecks / len(allNumChecks)
+	avg := totalChecks / len(mergedPullRequests)
 	if avg < DEFAULT_MIN_CHECKS {
 		return avg
 	} else {
@@ -209,88 +206,46 @@ func getMinChecks() int {
 	}
 }
 
-/*
-$ gh pr view 73 --json "reviews,statusCheckRollup" --jq "pick(.reviews[].author.login, .reviews[].state, .reviews[].commit.oid, .statusCheckRollup[].status, .statusCheckRollup[].conclusion, .statusCheckRollup[].state)"
-
-	{
-	  "reviews": [
-	    {
-	      "author": {
-	        "login": "jallentest1"
-	      },
-	      "commit": {
-	        "oid": "b7a6a8e29a906fbb009e5747167c5d11e80bc9b3"
-	      },
-	      "state": "CHANGES_REQUESTED"
-	    },
-	    {
-	      "author": {
-	        "login": "jallentest1"
-	      },
-	      "commit": {
-	        "oid": "b7a6a8e29a906fbb009e5747167c5d11e80bc9b3"
-	      },
-	      "state": "APPROVED"
-	    },
-	    {
-	      "author": {
-	        "login": "jallentest1"
-	      },
-	      "commit": {
-	        "oid": "b7a6a8e29a906fbb009e5747167c5d11e80bc9b3"
-	      },
-	      "state": "COMMENTED"
-	    }
-	  ],
-	  "statusCheckRollup": [
-	    {
-	      "conclusion": "SUCCESS",
-	      "state": null,
-	      "status": "COMPLETED"
-	    }
-	  ]
-	}
-*/
+// Returns the status of the PR of branchName. Panics if branchName does not have a PR.
 func GetPullRequestStatus(branchName string, minChecks int) PullRequestStatus {
-	/*
-		Turn each type into a CSV with initial key field.
-		gh pr view 73 --json "state,reviews,statusCheckRollup" --jq '(.reviews[] | select(.state == "APPROVED") | "approver," + .author.login + "," + .state+","+.commit.oid),(.statusCheckRollup[] | "check," + .status + ","+.conclusion+","+.state),("state," + .state)'
-		approved,jallentest1
-		check,COMPLETED,SUCCESS,SUCCESS
-		state,OPEN
-	*/
+	status, ok := GetPullRequestStatuses([]string{branchName}, minChecks)[branchName]
+	if !ok {
+		panic("No PR found for branch " + branchName)
+	}
+	return status
+}
+
+// Returns the status of the PRs of all branchNames, fetched from Github with a single request.
+// Branches without a PR are not included.
+func GetPullRequestStatuses(branchNames []string, minChecks int) map[string]PullRequestStatus {
 	if minChecks == -1 {
-		minChecks = getMinChecks()
+		minChecks = GetMinChecks()
 	}
-	lastCommit := GetBranchLatestCommit(branchName)
-	jq := "(.reviews[] | select(.state == \"APPROVED\" and .commit.oid == \"" + lastCommit + "\") | \"approver,\" + .author.login)," +
-		"(.statusCheckRollup[] | \"check,\" + .status + \",\"+.conclusion+\",\"+.state)," +
-		"(\"state,\" + .state)"
-	out := ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "view", branchName, "--json", "state,reviews,statusCheckRollup", "--jq", jq)
-	lines := strings.Split(strings.TrimSpace(out), "\n")
-	status := PullRequestStatus{Checks: PullRequestChecksStatus{MinChecks: minChecks}, Approvers: []string{}, State: PullRequestStateClosed}
-	for _, line := range lines {
-		fields := strings.Split(line, ",")
-		if len(fields) > 0 {
-			switch fields[0] {
-			case "approver":
-				status.Approvers = append(status.Approvers, fields[1])
-			case "check":
-				updatePullRequestChecksStatus(&status.Checks, fields[1], fields[2], fields[3])
-			case "state":
-				switch fields[1] {
-				case "MERGED":
-					status.State = PullRequestStateMerged
-				case "OPEN":
-					status.State = PullRequestStateOpen
-				default:
-					status.State = PullRequestStateClosed
-				}
-			default:
-				panic("Unexpected key " + fields[0])
-			}
+	statuses := make(map[string]PullRequestStatus)
+	for branchName, pullRequest := range GetForge().GetPullRequests(branchNames) {
+		statuses[branchName] = NewPullRequestStatus(pullRequest, minChecks)
+	}
+	return statuses
+}
+
+// Returns the status of pullRequest, for when the PR was already fetched from the [Forge].
+func NewPullRequestStatus(pullRequest PullRequest, minChecks int) PullRequestStatus {
+	lastCommit := GetBranchLatestCommit(pullRequest.HeadBranch)
+	status := PullRequestStatus{
+		Number:    pullRequest.Number,
+		Url:       pullRequest.Url,
+		Checks:    PullRequestChecksStatus{MinChecks: minChecks, Checks: []CheckResult{}},
+		Approvers: []string{},
+		State:     pullRequest.State,
+	}
+	for _, review := range pullRequest.Reviews {
+		if review.State == "APPROVED" && review.Commit == lastCommit {
+			status.Approvers = append(status.Approvers, review.Author)
 		}
 	}
+	for _, check := range pullRequest.Checks {
+		updatePullRequestChecksStatus(&status.Checks, check)
+	}
 	slices.Sort(status.Approvers)
 	status.Approvers = slices.Compact(status.Approvers)
 	return status
@@ -2,6 +2,7 @@ package util
 
 import (
 	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -65,12 +66,12 @@ func getMainBranchFromGitLog() (string, error) {
 	if mainBranchNameFromGitLog != "" {
 		return mainBranchNameFromGitLog, nil
 	}
-	remoteMainBranch, err := Execute(ExecuteOptions{}, "git", "rev-parse", "--abbrev-ref", "origin/HEAD")
+	remoteMainBranch, err := Execute(ExecuteOptions{}, "git", "rev-parse", "--abbrev-ref", GetBaseRemote()+"/HEAD")
 	if err != nil {
 		return remoteMainBranch, err
 	}
 	remoteMainBranch = strings.TrimSpace(remoteMainBranch)
-	mainBranchNameFromGitLog = remoteMainBranch[strings.Index(remoteMainBranch, "/")+1:]
+	mainBranchNameFromGitLog = strings.TrimPrefix(remoteMainBranch, GetBaseRemote()+"/")
 	return mainBranchNameFromGitLog, nil
 }
 
@@ -85,7 +86,7 @@ func setRemoteHead() {
 	}
 	if currentBranch == defaultBranch || currentBranch == "main" {
 		slog.Warn("Setting remote head to " + currentBranch + " because it is not set.")
-		out, err := Execute(ExecuteOptions{}, "git", "remote", "set-head", "origin", currentBranch)
+		out, err := Execute(ExecuteOptions{}, "git", "remote", "set-head", GetBaseRemote(), currentBranch)
 		if err != nil {
 			panic("Remote repository not setup.\n" + out)
 		}
@@ -102,17 +103,23 @@ func GetUsername() string {
 	return userEmail
 }
 
-// Returns most recent commit of the given branch that is on origin/main.
+// Returns most recent commit of the given branch that is on the main branch of the base remote.
 func FirstOriginMainCommit(branchName string) string {
 	if !GetLocalHasBranchOrDie(branchName) {
 		panic("Branch does not exist " + branchName)
 	}
-	return strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "merge-base", "origin/"+GetMainBranchOrDie(), branchName))
+	return strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "merge-base", GetRemoteMainBranchOrDie(), branchName))
 }
 
-// Returns whether branchName is on remote.
+// Returns whether branchName is on the remote that branches are pushed to.
 func RemoteHasBranch(branchName string) bool {
-	remoteBranch := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "branch", "-r", "--list", "origin/"+branchName))
+	remoteBranch := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "branch", "-r", "--list", GetPushRemote()+"/"+branchName))
+	return remoteBranch != ""
+}
+
+// Returns whether the main branch is on the base remote.
+func RemoteHasMainBranch() bool {
+	remoteBranch := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "branch", "-r", "--list", GetRemoteMainBranchOrDie()))
 	return remoteBranch != ""
 }
 
@@ -144,10 +151,22 @@ func GetCurrentBranchName() string {
 	return strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "rev-parse", "--abbrev-ref", "HEAD"))
 }
 
+// Returns whether there is a rebase in progress, such as one that stopped on a merge conflict.
+func IsRebaseInProgress() bool {
+	for _, rebaseDir := range []string{"rebase-merge", "rebase-apply"} {
+		path := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "rev-parse", "--git-path", rebaseDir))
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func Stash(forName string) bool {
 	stashResult := strings.Split(strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "stash", "save", "-u", "before "+forName)), "\n")
 	if len(stashResult) > 0 && strings.HasPrefix(stashResult[len(stashResult)-1], "Saved working") {
 		slog.Info(stashResult[len(stashResult)-1])
+		recordJournalStash(strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "rev-parse", "stash@{0}")))
 		return true
 	}
 	return false
@@ -156,6 +175,7 @@ func Stash(forName string) bool {
 func PopStash(popStash bool) {
 	if popStash {
 		ExecuteOrDie(ExecuteOptions{}, "git", "stash", "pop")
+		recordJournalStashPopped()
 		slog.Info("Popped stash back")
 	}
 }
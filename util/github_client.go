@@ -0,0 +1,437 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long to wait for a response from the Github API.
+const githubRequestTimeout = 30 * time.Second
+
+// Pull request as returned by [Forge].
+type PullRequest struct {
+	Number     int
+	HeadBranch string
+	BaseBranch string
+	State      PullRequestState
+	Title      string
+	Body       string
+	Url        string
+	IsDraft    bool
+	// Latest commit of the head branch on Github.
+	HeadCommit string
+	// Empty if the PR is not merged.
+	MergeCommit string
+	Reviews     []PullRequestReview
+	Checks      []PullRequestCheck
+}
+
+type PullRequestReview struct {
+	Author string
+	// For example "APPROVED", "CHANGES_REQUESTED", or "COMMENTED".
+	State string
+	// Commit that was reviewed.
+	Commit string
+}
+
+// A check run or a commit status of a PR. Check runs have a Status and Conclusion, commit statuses
+// have a State.
+type PullRequestCheck struct {
+	Name       string
+	Status     string
+	Conclusion string
+	State      string
+	// Page with the details of the check, if any.
+	Url string
+	// Id of the run of the check, used to view its logs and re-run it, see
+	// [Forge.GetFailedCheckLog]. Empty if the check was not run by Github Actions or GitLab CI.
+	RunId string
+}
+
+// Typed access to the Github API.
+// Allows swapping in a [FakeGithubClient] via Dependency Injection during tests.
+type GithubClient interface {
+	// Returns the most recent PR of each branch in branchNames, fetched with a single request.
+	// Branches without a PR are not included.
+	GetPullRequests(branchNames []string) map[string]PullRequest
+	// Returns the most recently merged PRs into baseBranch. If author is not empty then only PRs
+	// from that author are returned. Use "@me" for the logged in user.
+	GetMergedPullRequests(baseBranch string, author string) []PullRequest
+	// Returns the login of the authenticated user.
+	GetLoggedInUsername() string
+	// Returns the number of open PRs of the repository that each of reviewers has been requested
+	// to review, fetched with a single request.
+	GetReviewRequestCounts(reviewers []string) map[string]int
+}
+
+var globalGithubClient GithubClient
+var globalGithubClientOnce *sync.Once = new(sync.Once)
+
+// Sets the client that [GetGithubClient] returns.
+func SetGlobalGithubClient(client GithubClient) {
+	globalGithubClient = client
+}
+
+// Returns the client used to access Github, creating one that uses the auth token of Github CLI
+// if none was set.
+func GetGithubClient() GithubClient {
+	if globalGithubClient == nil {
+		globalGithubClientOnce.Do(func() {
+			host := os.Getenv("GH_HOST")
+			var apiUrl, graphqlUrl string
+			if host == "" || host == "github.com" {
+				apiUrl = "https://api.github.com"
+				graphqlUrl = "https://api.github.com/graphql"
+			} else {
+				// Github Enterprise Server.
+				apiUrl = "https://" + host + "/api/v3"
+				graphqlUrl = "https://" + host + "/api/graphql"
+			}
+			globalGithubClient = NewGithubApiClient(apiUrl, graphqlUrl, getGithubToken(), GetRepoNameWithOwner())
+		})
+	}
+	return globalGithubClient
+}
+
+// Returns the token from the environment, or from Github CLI if not set, so that the user
+// only has to login once via "gh auth login".
+func getGithubToken() string {
+	for _, name := range []string{"GH_TOKEN", "GITHUB_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "gh", "auth", "token"))
+}
+
+// Implementation of [GithubClient] that uses the Github GraphQL and REST APIs.
+type githubApiClient struct {
+	httpClient        *http.Client
+	apiUrl            string
+	graphqlUrl        string
+	token             string
+	repoNameWithOwner string
+}
+
+// Ensure that [githubApiClient] implements [GithubClient].
+var _ GithubClient = &githubApiClient{}
+
+// Returns a [GithubClient] for the repository repoNameWithOwner, ("owner/name").
+func NewGithubApiClient(apiUrl string, graphqlUrl string, token string, repoNameWithOwner string) GithubClient {
+	return &githubApiClient{
+		httpClient:        &http.Client{Timeout: githubRequestTimeout},
+		apiUrl:            strings.TrimSuffix(apiUrl, "/"),
+		graphqlUrl:        graphqlUrl,
+		token:             token,
+		repoNameWithOwner: repoNameWithOwner,
+	}
+}
+
+const pullRequestGraphqlFragment = `
+fragment pullRequestFields on PullRequest {
+  number
+  headRefName
+  baseRefName
+  state
+  title
+  body
+  url
+  isDraft
+  headRefOid
+  mergeCommit { oid }
+  reviews(last: 100) { nodes { state author { login } commit { oid } } }
+  commits(last: 1) {
+    nodes {
+      commit {
+        statusCheckRollup {
+          contexts(first: 100) {
+            nodes {
+              __typename
+              ... on CheckRun { name status conclusion detailsUrl checkSuite { workflowRun { databaseId } } }
+              ... on StatusContext { context state targetUrl }
+            }
+          }
+        }
+      }
+    }
+  }
+}`
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+type graphqlPullRequest struct {
+	Number      int    `json:"number"`
+	HeadRefName string `json:"headRefName"`
+	BaseRefName string `json:"baseRefName"`
+	State       string `json:"state"`
+	Title       string `json:"title"`
+	Body        string `json:"body"`
+	Url         string `json:"url"`
+	IsDraft     bool   `json:"isDraft"`
+	HeadRefOid  string `json:"headRefOid"`
+	MergeCommit *struct {
+		Oid string `json:"oid"`
+	} `json:"mergeCommit"`
+	Reviews struct {
+		Nodes []struct {
+			State  string `json:"state"`
+			Author *struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			Commit *struct {
+				Oid string `json:"oid"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"reviews"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup *struct {
+					Contexts struct {
+						Nodes []struct {
+							Typename   string `json:"__typename"`
+							Name       string `json:"name"`
+							Status     string `json:"status"`
+							Conclusion string `json:"conclusion"`
+							DetailsUrl string `json:"detailsUrl"`
+							CheckSuite *struct {
+								WorkflowRun *struct {
+									DatabaseId int64 `json:"databaseId"`
+								} `json:"workflowRun"`
+							} `json:"checkSuite"`
+							Context   string `json:"context"`
+							State     string `json:"state"`
+							TargetUrl string `json:"targetUrl"`
+						} `json:"nodes"`
+					} `json:"contexts"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+}
+
+func (c *githubApiClient) GetPullRequests(branchNames []string) map[string]PullRequest {
+	pullRequests := make(map[string]PullRequest)
+	if len(branchNames) == 0 {
+		return pullRequests
+	}
+	owner, name := c.splitRepoName()
+	variables := map[string]any{"owner": owner, "name": name}
+	var variableDefinitions strings.Builder
+	var aliases strings.Builder
+	for i, branchName := range branchNames {
+		variables[fmt.Sprint("branch", i)] = branchName
+		variableDefinitions.WriteString(fmt.Sprint(", $branch", i, ": String!"))
+		aliases.WriteString(fmt.Sprint(
+			"    pr", i, ": pullRequests(headRefName: $branch", i, ", first: 1, "+
+				"orderBy: {field: CREATED_AT, direction: DESC}) { nodes { ...pullRequestFields } }\n"))
+	}
+	query := "query($owner: String!, $name: String!" + variableDefinitions.String() + ") {\n" +
+		"  repository(owner: $owner, name: $name) {\n" +
+		aliases.String() +
+		"  }\n" +
+		"}\n" +
+		pullRequestGraphqlFragment
+	var data struct {
+		Repository map[string]struct {
+			Nodes []graphqlPullRequest `json:"nodes"`
+		} `json:"repository"`
+	}
+	c.graphql(query, variables, &data)
+	for _, result := range data.Repository {
+		if len(result.Nodes) > 0 {
+			pullRequest := result.Nodes[0].toPullRequest()
+			pullRequests[pullRequest.HeadBranch] = pullRequest
+		}
+	}
+	return pullRequests
+}
+
+func (c *githubApiClient) GetMergedPullRequests(baseBranch string, author string) []PullRequest {
+	search := "repo:" + c.repoNameWithOwner + " is:pr is:merged base:" + baseBranch + " sort:created-desc"
+	if author != "" {
+		search += " author:" + author
+	}
+	query := "query($search: String!) {\n" +
+		"  search(query: $search, type: ISSUE, first: 30) { nodes { ...pullRequestFields } }\n" +
+		"}\n" +
+		pullRequestGraphqlFragment
+	var data struct {
+		Search struct {
+			Nodes []graphqlPullRequest `json:"nodes"`
+		} `json:"search"`
+	}
+	c.graphql(query, map[string]any{"search": search}, &data)
+	return MapSlice(data.Search.Nodes, func(node graphqlPullRequest) PullRequest {
+		return node.toPullRequest()
+	})
+}
+
+func (c *githubApiClient) GetLoggedInUsername() string {
+	var user struct {
+		Login string `json:"login"`
+	}
+	c.rest(http.MethodGet, "/user", &user)
+	return user.Login
+}
+
+func (c *githubApiClient) GetReviewRequestCounts(reviewers []string) map[string]int {
+	counts := make(map[string]int)
+	if len(reviewers) == 0 {
+		return counts
+	}
+	variables := map[string]any{}
+	var variableDefinitions []string
+	var aliases strings.Builder
+	for i, reviewer := range reviewers {
+		variables[fmt.Sprint("search", i)] = "repo:" + c.repoNameWithOwner + " is:pr is:open review-requested:" + reviewer
+		variableDefinitions = append(variableDefinitions, fmt.Sprint("$search", i, ": String!"))
+		aliases.WriteString(fmt.Sprint("  reviewer", i, ": search(query: $search", i, ", type: ISSUE, first: 0) { issueCount }\n"))
+	}
+	query := "query(" + strings.Join(variableDefinitions, ", ") + ") {\n" +
+		aliases.String() +
+		"}\n"
+	var data map[string]struct {
+		IssueCount int `json:"issueCount"`
+	}
+	c.graphql(query, variables, &data)
+	for i, reviewer := range reviewers {
+		counts[reviewer] = data[fmt.Sprint("reviewer", i)].IssueCount
+	}
+	return counts
+}
+
+func (c *githubApiClient) splitRepoName() (string, string) {
+	owner, name, found := strings.Cut(c.repoNameWithOwner, "/")
+	if !found {
+		panic("Invalid repository name, expected owner/name: " + c.repoNameWithOwner)
+	}
+	return owner, name
+}
+
+// Executes a GraphQL query and unmarshalls the "data" field of the response into data.
+func (c *githubApiClient) graphql(query string, variables map[string]any, data any) {
+	requestBody, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	if err != nil {
+		panic(err)
+	}
+	responseBody := c.send(http.MethodPost, c.graphqlUrl, bytes.NewReader(requestBody))
+	var response struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphqlError  `json:"errors"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		panic(fmt.Sprint("Could not parse Github GraphQL response: ", err, "\n", string(responseBody)))
+	}
+	if len(response.Errors) > 0 {
+		messages := MapSlice(response.Errors, func(next graphqlError) string {
+			return next.Message
+		})
+		panic("Github GraphQL query failed: " + strings.Join(messages, ", "))
+	}
+	if err := json.Unmarshal(response.Data, data); err != nil {
+		panic(fmt.Sprint("Could not parse Github GraphQL data: ", err, "\n", string(response.Data)))
+	}
+}
+
+// Executes a REST request for path and unmarshalls the response into result.
+func (c *githubApiClient) rest(method string, path string, result any) {
+	responseBody := c.send(method, c.apiUrl+path, nil)
+	if err := json.Unmarshal(responseBody, result); err != nil {
+		panic(fmt.Sprint("Could not parse Github response for ", path, ": ", err, "\n", string(responseBody)))
+	}
+}
+
+func (c *githubApiClient) send(method string, url string, body io.Reader) []byte {
+	request, err := http.NewRequest(method, url, body)
+	if err != nil {
+		panic(err)
+	}
+	request.Header.Set("Authorization", "bearer "+c.token)
+	request.Header.Set("Accept", "application/vnd.github+json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	slog.Debug(fmt.Sprint("Sending ", method, " ", url))
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		panic(fmt.Sprint("Could not reach Github: ", err))
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(fmt.Sprint("Could not read Github response: ", err))
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		panic(fmt.Sprint("Github request ", method, " ", url, " failed with ", response.Status, ": ", string(responseBody)))
+	}
+	return responseBody
+}
+
+func (node graphqlPullRequest) toPullRequest() PullRequest {
+	pullRequest := PullRequest{
+		Number:     node.Number,
+		HeadBranch: node.HeadRefName,
+		BaseBranch: node.BaseRefName,
+		State:      toPullRequestState(node.State),
+		Title:      node.Title,
+		Body:       node.Body,
+		Url:        node.Url,
+		IsDraft:    node.IsDraft,
+		HeadCommit: node.HeadRefOid,
+		Reviews:    []PullRequestReview{},
+		Checks:     []PullRequestCheck{},
+	}
+	if node.MergeCommit != nil {
+		pullRequest.MergeCommit = node.MergeCommit.Oid
+	}
+	for _, review := range node.Reviews.Nodes {
+		next := PullRequestReview{State: review.State}
+		if review.Author != nil {
+			next.Author = review.Author.Login
+		}
+		if review.Commit != nil {
+			next.Commit = review.Commit.Oid
+		}
+		pullRequest.Reviews = append(pullRequest.Reviews, next)
+	}
+	for _, commit := range node.Commits.Nodes {
+		if commit.Commit.StatusCheckRollup == nil {
+			continue
+		}
+		for _, context := range commit.Commit.StatusCheckRollup.Contexts.Nodes {
+			if context.Typename == "StatusContext" {
+				pullRequest.Checks = append(pullRequest.Checks,
+					PullRequestCheck{Name: context.Context, State: context.State, Url: context.TargetUrl})
+			} else {
+				check := PullRequestCheck{Name: context.Name, Status: context.Status, Conclusion: context.Conclusion, Url: context.DetailsUrl}
+				if context.CheckSuite != nil && context.CheckSuite.WorkflowRun != nil {
+					check.RunId = fmt.Sprint(context.CheckSuite.WorkflowRun.DatabaseId)
+				}
+				pullRequest.Checks = append(pullRequest.Checks, check)
+			}
+		}
+	}
+	return pullRequest
+}
+
+func toPullRequestState(state string) PullRequestState {
+	switch state {
+	case "MERGED":
+		return PullRequestStateMerged
+	case "OPEN":
+		return PullRequestStateOpen
+	default:
+		return PullRequestStateClosed
+	}
+}
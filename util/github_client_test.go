@@ -0,0 +1,130 @@
+package util
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type graphqlRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+// Returns a server that responds to GraphQL requests with graphqlResponse and records them.
+func newFakeGithubServer(t *testing.T, graphqlResponse string, requests *[]graphqlRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "bearer test-token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/graphql":
+			var request graphqlRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				t.Error(err)
+			}
+			*requests = append(*requests, request)
+			_, _ = w.Write([]byte(graphqlResponse))
+		case "/user":
+			_, _ = w.Write([]byte(`{"login": "octocat"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGithubApiClient_GetPullRequests_FetchesAllBranchesInOneQuery(t *testing.T) {
+	assert := assert.New(t)
+	requests := []graphqlRequest{}
+	server := newFakeGithubServer(t, `{"data": {"repository": {
+		"pr0": {"nodes": [{
+			"number": 7, "headRefName": "first", "baseRefName": "main", "state": "OPEN", "headRefOid": "abc",
+			"title": "First", "body": "Description", "url": "https://github.com/owner/repo/pull/7", "mergeCommit": null,
+			"reviews": {"nodes": [{"state": "APPROVED", "author": {"login": "mybestie"}, "commit": {"oid": "abc"}}]},
+			"commits": {"nodes": [{"commit": {"statusCheckRollup": {"contexts": {"nodes": [
+				{"__typename": "CheckRun", "name": "build", "status": "COMPLETED", "conclusion": "SUCCESS",
+				 "detailsUrl": "https://github.com/owner/repo/actions/runs/42/job/1", "checkSuite": {"workflowRun": {"databaseId": 42}}},
+				{"__typename": "StatusContext", "context": "ci/lint, \"quoted\"", "state": "PENDING", "targetUrl": "https://ci.example.com/1"}
+			]}}}}]}
+		}]},
+		"pr1": {"nodes": []}
+	}}}`, &requests)
+	client := NewGithubApiClient(server.URL, server.URL+"/graphql", "test-token", "owner/repo")
+
+	pullRequests := client.GetPullRequests([]string{"first", "second"})
+
+	assert.Equal(1, len(requests))
+	assert.Equal("owner", requests[0].Variables["owner"])
+	assert.Equal("first", requests[0].Variables["branch0"])
+	assert.Equal("second", requests[0].Variables["branch1"])
+	assert.Equal(1, len(pullRequests))
+	assert.Equal(PullRequest{
+		Number:     7,
+		HeadBranch: "first",
+		BaseBranch: "main",
+		State:      PullRequestStateOpen,
+		Title:      "First",
+		Body:       "Description",
+		Url:        "https://github.com/owner/repo/pull/7",
+		HeadCommit: "abc",
+		Reviews:    []PullRequestReview{{Author: "mybestie", State: "APPROVED", Commit: "abc"}},
+		Checks: []PullRequestCheck{
+			{Name: "build", Status: "COMPLETED", Conclusion: "SUCCESS", Url: "https://github.com/owner/repo/actions/runs/42/job/1", RunId: "42"},
+			{Name: "ci/lint, \"quoted\"", State: "PENDING", Url: "https://ci.example.com/1"},
+		},
+	}, pullRequests["first"])
+}
+
+func TestGithubApiClient_GetMergedPullRequests_ReturnsMergeCommit(t *testing.T) {
+	assert := assert.New(t)
+	requests := []graphqlRequest{}
+	server := newFakeGithubServer(t, `{"data": {"search": {"nodes": [
+		{"number": 3, "headRefName": "merged", "baseRefName": "main", "state": "MERGED", "headRefOid": "def",
+		 "mergeCommit": {"oid": "123"}, "reviews": {"nodes": []}, "commits": {"nodes": []}}
+	]}}}`, &requests)
+	client := NewGithubApiClient(server.URL, server.URL+"/graphql", "test-token", "owner/repo")
+
+	pullRequests := client.GetMergedPullRequests("main", "@me")
+
+	assert.Equal("repo:owner/repo is:pr is:merged base:main sort:created-desc author:@me", requests[0].Variables["search"])
+	assert.Equal(1, len(pullRequests))
+	assert.Equal("merged", pullRequests[0].HeadBranch)
+	assert.Equal("123", pullRequests[0].MergeCommit)
+	assert.Equal(PullRequestStateMerged, pullRequests[0].State)
+}
+
+func TestGithubApiClient_GetReviewRequestCounts_SearchesAllReviewersInOneQuery(t *testing.T) {
+	assert := assert.New(t)
+	requests := []graphqlRequest{}
+	server := newFakeGithubServer(t, `{"data": {"reviewer0": {"issueCount": 3}, "reviewer1": {"issueCount": 0}}}`, &requests)
+	client := NewGithubApiClient(server.URL, server.URL+"/graphql", "test-token", "owner/repo")
+
+	counts := client.GetReviewRequestCounts([]string{"alice", "bob"})
+
+	assert.Equal(map[string]int{"alice": 3, "bob": 0}, counts)
+	assert.Equal(1, len(requests))
+	assert.Equal("repo:owner/repo is:pr is:open review-requested:bob", requests[0].Variables["search1"])
+}
+
+func TestGithubApiClient_WhenGraphqlErrors_Panics(t *testing.T) {
+	assert := assert.New(t)
+	requests := []graphqlRequest{}
+	server := newFakeGithubServer(t, `{"errors": [{"message": "Something went wrong"}]}`, &requests)
+	client := NewGithubApiClient(server.URL, server.URL+"/graphql", "test-token", "owner/repo")
+
+	assert.PanicsWithValue("Github GraphQL query failed: Something went wrong", func() {
+		client.GetPullRequests([]string{"first"})
+	})
+}
+
+func TestGithubApiClient_GetLoggedInUsername_UsesRestApi(t *testing.T) {
+	assert := assert.New(t)
+	requests := []graphqlRequest{}
+	server := newFakeGithubServer(t, "", &requests)
+	client := NewGithubApiClient(server.URL, server.URL+"/graphql", "test-token", "owner/repo")
+
+	assert.Equal("octocat", client.GetLoggedInUsername())
+}
@@ -0,0 +1,209 @@
+package util
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// Implementation of [Forge] for Github. Queries use [GithubClient], and changes use Github CLI.
+type githubForge struct{}
+
+// Ensure that [githubForge] implements [Forge].
+var _ Forge = githubForge{}
+
+// PR as output by "gh pr list --json".
+type ghPrListItem struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	HeadRefName string `json:"headRefName"`
+	Url         string `json:"url"`
+	State       string `json:"state"`
+	IsDraft     bool   `json:"isDraft"`
+}
+
+func (f githubForge) GetPullRequests(branchNames []string) map[string]PullRequest {
+	return GetGithubClient().GetPullRequests(branchNames)
+}
+
+func (f githubForge) GetMergedPullRequests(baseBranch string, author string) []PullRequest {
+	return GetGithubClient().GetMergedPullRequests(baseBranch, author)
+}
+
+func (f githubForge) GetOpenPullRequests() []PullRequest {
+	prsJson := ExecuteOrDie(ExecuteOptions{},
+		"gh", "pr", "list", "--author", "@me", "--json", "number,title,headRefName,url,state,isDraft")
+	var pullRequests []ghPrListItem
+	if err := json.Unmarshal([]byte(prsJson), &pullRequests); err != nil {
+		panic("Could not parse PRs: " + err.Error())
+	}
+	return MapSlice(pullRequests, func(pullRequest ghPrListItem) PullRequest {
+		return PullRequest{
+			Number:     pullRequest.Number,
+			Title:      pullRequest.Title,
+			HeadBranch: pullRequest.HeadRefName,
+			Url:        pullRequest.Url,
+			State:      toPullRequestState(pullRequest.State),
+			IsDraft:    pullRequest.IsDraft,
+		}
+	})
+}
+
+func (f githubForge) GetPullRequestFirstCommit(pullRequest string) (string, string) {
+	branchName := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "view", pullRequest, "--json", "headRefName", "-q", ".headRefName"))
+	firstCommit := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "view", pullRequest, "--json", "commits", "-q", "[.commits[].oid] | first"))
+	return branchName, firstCommit
+}
+
+func (f githubForge) GetLoggedInUsername() string {
+	return GetGithubClient().GetLoggedInUsername()
+}
+
+/*
+Example output of gh api collaborators:
+gh api repos/joshallenit/gh-stacked-diff/collaborators
+[
+
+	{
+	  "login": "xxx",
+	  "id": 4293001,
+	  "node_id": "MDQ6VXNlcjQyOTMwMDE=",
+	  "avatar_url": "https://avatars.githubusercontent.com/u/4293001?v=4",
+	  "gravatar_id": "",
+	  "url": "https://api.github.com/users/joshallenit",
+	  "html_url": "https://github.com/joshallenit",
+	  "followers_url": "https://api.github.com/users/joshallenit/followers",
+	  "following_url": "https://api.github.com/users/joshallenit/following{/other_user}",
+	  "gists_url": "https://api.github.com/users/joshallenit/gists{/gist_id}",
+	  "starred_url": "https://api.github.com/users/joshallenit/starred{/owner}{/repo}",
+	  "subscriptions_url": "https://api.github.com/users/joshallenit/subscriptions",
+	  "organizations_url": "https://api.github.com/users/joshallenit/orgs",
+	  "repos_url": "https://api.github.com/users/joshallenit/repos",
+	  "events_url": "https://api.github.com/users/joshallenit/events{/privacy}",
+	  "received_events_url": "https://api.github.com/users/joshallenit/received_events",
+	  "type": "User",
+	  "user_view_type": "public",
+	  "site_admin": false,
+	  "permissions": {
+	    "admin": true,
+	    "maintain": true,
+	    "push": true,
+	    "triage": true,
+	    "pull": true
+	  },
+	  "role_name": "admin"
+	},
+	{
+	  "login": "xxxx",
+	  "id": 79605685,
+	  "node_id": "MDQ6VXNlcjc5NjA1Njg1",
+	  "avatar_url": "https://avatars.githubusercontent.com/u/79605685?v=4",
+	  "gravatar_id": "",
+	  "url": "https://api.github.com/users/slack-jallen",
+	  "html_url": "https://github.com/slack-jallen",
+	  "followers_url": "https://api.github.com/users/slack-jallen/followers",
+	  "following_url": "https://api.github.com/users/slack-jallen/following{/other_user}",
+	  "gists_url": "https://api.github.com/users/slack-jallen/gists{/gist_id}",
+	  "starred_url": "https://api.github.com/users/slack-jallen/starred{/owner}{/repo}",
+	  "subscriptions_url": "https://api.github.com/users/slack-jallen/subscriptions",
+	  "organizations_url": "https://api.github.com/users/slack-jallen/orgs",
+	  "repos_url": "https://api.github.com/users/slack-jallen/repos",
+	  "events_url": "https://api.github.com/users/slack-jallen/events{/privacy}",
+	  "received_events_url": "https://api.github.com/users/slack-jallen/received_events",
+	  "type": "User",
+	  "user_view_type": "public",
+	  "site_admin": false,
+	  "permissions": {
+	    "admin": false,
+	    "maintain": false,
+	    "push": true,
+	    "triage": true,
+	    "pull": true
+	  },
+	  "role_name": "write"
+	}
+
+]
+
+Example output from: gh repo view --json nameWithOwner
+
+	{
+	  "nameWithOwner": "joshallenit/gh-stacked-diff"
+	}
+*/
+func (f githubForge) GetCollaborators() []string {
+	out := ExecuteOrDie(ExecuteOptions{},
+		"gh", "api", "repos/"+GetRepoNameWithOwner()+"/collaborators",
+		"--paginate", "--cache", "6h", "--jq", ".[] | .login")
+	return strings.Fields(out)
+}
+
+func (f githubForge) GetReviewRequestCounts(reviewers []string) map[string]int {
+	return GetGithubClient().GetReviewRequestCounts(reviewers)
+}
+
+func (f githubForge) CreatePullRequest(options CreatePullRequestOptions) string {
+	createPrArgsNoDraft := []string{"pr", "create", "--title", options.Title, "--body", options.Body, "--fill", "--base", options.BaseBranch, "--head", options.HeadBranch}
+	createPrArgs := createPrArgsNoDraft
+	if options.Draft {
+		createPrArgs = append(createPrArgs, "--draft")
+	}
+	createPrOutput, createPrErr := Execute(ExecuteOptions{}, "gh", createPrArgs...)
+	if createPrErr != nil {
+		if options.Draft && strings.Contains(createPrOutput, "Draft pull requests are not supported") {
+			slog.Warn("Draft PRs not supported, trying again without draft.\nUse \"--draft=false\" to avoid this warning.")
+			return ExecuteOrDie(ExecuteOptions{}, "gh", createPrArgsNoDraft...)
+		} else {
+			panic("Could not create PR: " + createPrOutput + ", " + createPrErr.Error())
+		}
+	} else {
+		return createPrOutput
+	}
+}
+
+func (f githubForge) EditPullRequest(pullRequest string, options EditPullRequestOptions) {
+	editArgs := []string{"pr", "edit", pullRequest}
+	if options.Title != "" {
+		editArgs = append(editArgs, "--title", options.Title)
+	}
+	if options.Body != "" {
+		editArgs = append(editArgs, "--body", options.Body)
+	}
+	if options.BaseBranch != "" {
+		editArgs = append(editArgs, "--base", options.BaseBranch)
+	}
+	ExecuteOrDie(ExecuteOptions{}, "gh", editArgs...)
+}
+
+func (f githubForge) MarkPullRequestReady(pullRequest string) {
+	ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "ready", pullRequest)
+}
+
+func (f githubForge) AddReviewers(pullRequest string, reviewers []string) string {
+	return strings.TrimSpace(ExecuteOrDie(ExecuteOptions{},
+		"gh", "pr", "edit", pullRequest, "--add-reviewer", strings.Join(reviewers, ",")))
+}
+
+func (f githubForge) MergePullRequest(pullRequest string, method string, auto bool) {
+	mergeArgs := []string{"pr", "merge", pullRequest, "--" + method}
+	if auto {
+		mergeArgs = append(mergeArgs, "--auto")
+	}
+	ExecuteOrDie(ExecuteOptions{}, "gh", mergeArgs...)
+}
+
+func (f githubForge) ClosePullRequest(pullRequest string, comment string) {
+	ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "close", pullRequest, "--comment", comment)
+}
+
+func (f githubForge) OpenPullRequest(pullRequest string) {
+	ExecuteOrDie(ExecuteOptions{}, "gh", "pr", "view", pullRequest, "--web")
+}
+
+func (f githubForge) GetFailedCheckLog(runId string) string {
+	return ExecuteOrDie(ExecuteOptions{}, "gh", "run", "view", runId, "--log-failed")
+}
+
+func (f githubForge) RerunFailedChecks(runId string) {
+	ExecuteOrDie(ExecuteOptions{}, "gh", "run", "rerun", runId, "--failed")
+}
@@ -0,0 +1,533 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long to wait for a response from the GitLab API.
+const gitlabRequestTimeout = 30 * time.Second
+
+// How long to wait for GitLab to rebase a merge request before merging it.
+const gitlabRebaseTimeout = 2 * time.Minute
+
+// Prefixes of the title of a draft merge request.
+var gitlabDraftPrefixes = []string{"Draft:", "[Draft]", "(Draft)", "Draft -"}
+
+// Implementation of [Forge] for GitLab merge requests, using the GitLab REST API.
+type gitlabForge struct {
+	httpClient *http.Client
+	apiUrl     string
+	token      string
+	// Path of the project that merge requests are opened against, for example "group/name".
+	projectPath string
+	// Path of the project that branches are pushed to, which is different to projectPath when
+	// pushing to a fork.
+	sourceProjectPath string
+}
+
+// Ensure that [gitlabForge] implements [Forge].
+var _ Forge = &gitlabForge{}
+
+type gitlabUser struct {
+	Id       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type gitlabMergeRequest struct {
+	Iid             int          `json:"iid"`
+	Title           string       `json:"title"`
+	Description     string       `json:"description"`
+	State           string       `json:"state"`
+	SourceBranch    string       `json:"source_branch"`
+	TargetBranch    string       `json:"target_branch"`
+	WebUrl          string       `json:"web_url"`
+	Draft           bool         `json:"draft"`
+	Sha             string       `json:"sha"`
+	MergeCommitSha  string       `json:"merge_commit_sha"`
+	SquashCommitSha string       `json:"squash_commit_sha"`
+	Reviewers       []gitlabUser `json:"reviewers"`
+	// Only included when requested with include_rebase_in_progress.
+	RebaseInProgress bool   `json:"rebase_in_progress"`
+	MergeError       string `json:"merge_error"`
+	// Only included when getting a single merge request.
+	HeadPipeline *struct {
+		Id        int `json:"id"`
+		ProjectId int `json:"project_id"`
+	} `json:"head_pipeline"`
+}
+
+type gitlabJob struct {
+	Id           int    `json:"id"`
+	WebUrl       string `json:"web_url"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	AllowFailure bool   `json:"allow_failure"`
+}
+
+// Returns a [Forge] for the GitLab project projectPath, ("group/name"), whose branches are pushed
+// to sourceProjectPath. apiUrl is for example "https://gitlab.example.com/api/v4".
+func NewGitlabForge(apiUrl string, token string, projectPath string, sourceProjectPath string) Forge {
+	return &gitlabForge{
+		httpClient:        &http.Client{Timeout: gitlabRequestTimeout},
+		apiUrl:            strings.TrimSuffix(apiUrl, "/"),
+		token:             token,
+		projectPath:       projectPath,
+		sourceProjectPath: sourceProjectPath,
+	}
+}
+
+// Returns a [Forge] for the projects of the base and push remotes.
+func newGitlabForgeFromRemotes() Forge {
+	host, projectPath := parseRemoteUrl(strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "remote", "get-url", GetBaseRemote())))
+	_, sourceProjectPath := parseRemoteUrl(strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "remote", "get-url", GetPushRemote())))
+	serverUrl := GetConfigString("forge-url")
+	if serverUrl == "" {
+		serverUrl = "https://" + host
+	}
+	// HTTPS remotes of a server on a subpath, such as "https://example.com/gitlab", include it.
+	projectPath = trimServerPath(serverUrl, projectPath)
+	sourceProjectPath = trimServerPath(serverUrl, sourceProjectPath)
+	return NewGitlabForge(strings.TrimSuffix(serverUrl, "/")+"/api/v4", getGitlabToken(host), projectPath, sourceProjectPath)
+}
+
+// Returns projectPath without the path of serverUrl, if it starts with it.
+func trimServerPath(serverUrl string, projectPath string) string {
+	parsed, err := url.Parse(serverUrl)
+	if err != nil {
+		panic("Invalid forge-url " + serverUrl + ": " + err.Error())
+	}
+	serverPath := strings.Trim(parsed.Path, "/")
+	if serverPath == "" {
+		return projectPath
+	}
+	return strings.TrimPrefix(projectPath, serverPath+"/")
+}
+
+// Returns the token from the environment, or from GitLab CLI if not set.
+func getGitlabToken(host string) string {
+	for _, name := range []string{"GITLAB_TOKEN", "GL_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	token, err := Execute(ExecuteOptions{}, "glab", "config", "get", "token", "--host", host)
+	if err != nil || strings.TrimSpace(token) == "" {
+		panic("No GitLab token, set GITLAB_TOKEN or login via \"glab auth login\"")
+	}
+	return strings.TrimSpace(token)
+}
+
+func (f *gitlabForge) GetPullRequests(branchNames []string) map[string]PullRequest {
+	pullRequests := make(map[string]PullRequest)
+	for _, branchName := range branchNames {
+		if mergeRequest, ok := f.findMergeRequest(branchName); ok {
+			pullRequests[branchName] = f.getPullRequest(mergeRequest.Iid)
+		}
+	}
+	return pullRequests
+}
+
+// Checks are not included, as they would require a request per merge request.
+func (f *gitlabForge) GetMergedPullRequests(baseBranch string, author string) []PullRequest {
+	query := url.Values{
+		"state":         {"merged"},
+		"target_branch": {baseBranch},
+		"order_by":      {"created_at"},
+		"sort":          {"desc"},
+		"per_page":      {"30"},
+	}
+	if author == "@me" {
+		query.Set("scope", "created_by_me")
+	} else if author != "" {
+		query.Set("author_username", author)
+	}
+	var mergeRequests []gitlabMergeRequest
+	f.request(http.MethodGet, f.projectUrl(f.projectPath)+"/merge_requests?"+query.Encode(), nil, &mergeRequests)
+	return MapSlice(mergeRequests, gitlabMergeRequest.toPullRequest)
+}
+
+func (f *gitlabForge) GetOpenPullRequests() []PullRequest {
+	query := url.Values{"state": {"opened"}, "scope": {"created_by_me"}, "per_page": {"100"}}
+	var mergeRequests []gitlabMergeRequest
+	f.request(http.MethodGet, f.projectUrl(f.projectPath)+"/merge_requests?"+query.Encode(), nil, &mergeRequests)
+	return MapSlice(mergeRequests, gitlabMergeRequest.toPullRequest)
+}
+
+func (f *gitlabForge) GetPullRequestFirstCommit(pullRequest string) (string, string) {
+	mergeRequest := f.getMergeRequest(f.getIid(pullRequest))
+	var commits []struct {
+		Id string `json:"id"`
+	}
+	// Commits are returned newest first.
+	f.request(http.MethodGet, f.mergeRequestUrl(mergeRequest.Iid)+"/commits?per_page=100", nil, &commits)
+	if len(commits) == 0 {
+		panic(fmt.Sprint("Merge request ", mergeRequest.Iid, " has no commits"))
+	}
+	return mergeRequest.SourceBranch, commits[len(commits)-1].Id
+}
+
+func (f *gitlabForge) GetLoggedInUsername() string {
+	var user gitlabUser
+	f.request(http.MethodGet, "/user", nil, &user)
+	return user.Username
+}
+
+func (f *gitlabForge) GetCollaborators() []string {
+	var members []gitlabUser
+	f.request(http.MethodGet, f.projectUrl(f.projectPath)+"/members/all?per_page=100", nil, &members)
+	return MapSlice(members, func(member gitlabUser) string {
+		return member.Username
+	})
+}
+
+// Counts at most 100 merge requests per reviewer.
+func (f *gitlabForge) GetReviewRequestCounts(reviewers []string) map[string]int {
+	counts := make(map[string]int)
+	for _, reviewer := range reviewers {
+		query := url.Values{"state": {"opened"}, "reviewer_username": {reviewer}, "per_page": {"100"}}
+		var mergeRequests []gitlabMergeRequest
+		f.request(http.MethodGet, f.projectUrl(f.projectPath)+"/merge_requests?"+query.Encode(), nil, &mergeRequests)
+		counts[reviewer] = len(mergeRequests)
+	}
+	return counts
+}
+
+func (f *gitlabForge) CreatePullRequest(options CreatePullRequestOptions) string {
+	// Remove the "owner:" of a branch on a fork, see [GetPullRequestHead].
+	sourceBranch := options.HeadBranch[strings.Index(options.HeadBranch, ":")+1:]
+	title := options.Title
+	if options.Draft {
+		title = "Draft: " + title
+	}
+	body := map[string]any{
+		"source_branch": sourceBranch,
+		"target_branch": options.BaseBranch,
+		"title":         title,
+		"description":   options.Body,
+	}
+	if f.sourceProjectPath != f.projectPath {
+		var project struct {
+			Id int `json:"id"`
+		}
+		f.request(http.MethodGet, f.projectUrl(f.projectPath), nil, &project)
+		body["target_project_id"] = project.Id
+	}
+	var mergeRequest gitlabMergeRequest
+	f.request(http.MethodPost, f.projectUrl(f.sourceProjectPath)+"/merge_requests", body, &mergeRequest)
+	return mergeRequest.WebUrl
+}
+
+func (f *gitlabForge) EditPullRequest(pullRequest string, options EditPullRequestOptions) {
+	mergeRequest := f.getMergeRequest(f.getIid(pullRequest))
+	body := map[string]any{}
+	if options.Title != "" {
+		body["title"] = options.Title
+		if mergeRequest.Draft {
+			// The draft status is part of the title.
+			body["title"] = "Draft: " + options.Title
+		}
+	}
+	if options.Body != "" {
+		body["description"] = options.Body
+	}
+	if options.BaseBranch != "" {
+		body["target_branch"] = options.BaseBranch
+	}
+	f.request(http.MethodPut, f.mergeRequestUrl(mergeRequest.Iid), body, nil)
+}
+
+func (f *gitlabForge) MarkPullRequestReady(pullRequest string) {
+	mergeRequest := f.getMergeRequest(f.getIid(pullRequest))
+	if !mergeRequest.Draft {
+		return
+	}
+	f.request(http.MethodPut, f.mergeRequestUrl(mergeRequest.Iid),
+		map[string]any{"title": removeDraftPrefix(mergeRequest.Title)}, nil)
+}
+
+func (f *gitlabForge) AddReviewers(pullRequest string, reviewers []string) string {
+	mergeRequest := f.getMergeRequest(f.getIid(pullRequest))
+	reviewerIds := MapSlice(mergeRequest.Reviewers, func(reviewer gitlabUser) int {
+		return reviewer.Id
+	})
+	for _, reviewer := range reviewers {
+		if IsTeamReviewer(reviewer) {
+			slog.Warn("Skipping " + reviewer + " as GitLab does not support teams as reviewers")
+			continue
+		}
+		var users []gitlabUser
+		f.request(http.MethodGet, "/users?"+url.Values{"username": {reviewer}}.Encode(), nil, &users)
+		if len(users) == 0 {
+			panic("No GitLab user " + reviewer)
+		}
+		if !slices.Contains(reviewerIds, users[0].Id) {
+			reviewerIds = append(reviewerIds, users[0].Id)
+		}
+	}
+	f.request(http.MethodPut, f.mergeRequestUrl(mergeRequest.Iid), map[string]any{"reviewer_ids": reviewerIds}, nil)
+	return mergeRequest.WebUrl
+}
+
+// Merges with the merge method of the project, squashing if method is "squash".
+// Merges pullRequest with method, which is one of "squash", "rebase", or "merge". GitLab has no
+// rebase merge, so for "rebase" the source branch is rebased onto the target branch first, and
+// then merged the way that the project is configured to, which is without a merge commit if it
+// uses fast-forward merges.
+func (f *gitlabForge) MergePullRequest(pullRequest string, method string, auto bool) {
+	iid := f.getIid(pullRequest)
+	if method == "rebase" {
+		f.rebaseMergeRequest(iid)
+	}
+	body := map[string]any{"squash": method == "squash"}
+	if auto {
+		body["merge_when_pipeline_succeeds"] = true
+	}
+	f.request(http.MethodPut, f.mergeRequestUrl(iid)+"/merge", body, nil)
+}
+
+// Rebases the source branch of the merge request iid onto its target branch and waits for the
+// rebase to finish.
+func (f *gitlabForge) rebaseMergeRequest(iid int) {
+	f.request(http.MethodPut, f.mergeRequestUrl(iid)+"/rebase", nil, nil)
+	for waited := time.Duration(0); ; waited += time.Second {
+		var mergeRequest gitlabMergeRequest
+		f.request(http.MethodGet, f.mergeRequestUrl(iid)+"?include_rebase_in_progress=true", nil, &mergeRequest)
+		if mergeRequest.MergeError != "" {
+			panic(fmt.Sprint("Could not rebase merge request ", iid, ": ", mergeRequest.MergeError))
+		}
+		if !mergeRequest.RebaseInProgress {
+			return
+		}
+		if waited >= gitlabRebaseTimeout {
+			panic(fmt.Sprint("Merge request ", iid, " was not rebased within ", gitlabRebaseTimeout))
+		}
+		Sleep(time.Second)
+	}
+}
+
+func (f *gitlabForge) ClosePullRequest(pullRequest string, comment string) {
+	iid := f.getIid(pullRequest)
+	f.request(http.MethodPost, f.mergeRequestUrl(iid)+"/notes", map[string]any{"body": comment}, nil)
+	f.request(http.MethodPut, f.mergeRequestUrl(iid), map[string]any{"state_event": "close"}, nil)
+}
+
+func (f *gitlabForge) OpenPullRequest(pullRequest string) {
+	webUrl := f.getMergeRequest(f.getIid(pullRequest)).WebUrl
+	var openArgs []string
+	switch runtime.GOOS {
+	case "darwin":
+		openArgs = []string{"open", webUrl}
+	case "windows":
+		openArgs = []string{"rundll32", "url.dll,FileProtocolHandler", webUrl}
+	default:
+		openArgs = []string{"xdg-open", webUrl}
+	}
+	if _, err := Execute(ExecuteOptions{}, openArgs[0], openArgs[1:]...); err != nil {
+		slog.Info("Merge request: " + webUrl)
+	}
+}
+
+// Returns the trace of the job of runId, which includes the output of its failed step.
+func (f *gitlabForge) GetFailedCheckLog(runId string) string {
+	return string(f.requestRaw(http.MethodGet, f.jobUrl(runId)+"/trace", nil))
+}
+
+func (f *gitlabForge) RerunFailedChecks(runId string) {
+	f.request(http.MethodPost, f.jobUrl(runId)+"/retry", nil, nil)
+}
+
+// Returns the path of the job of runId, as set by [gitlabForge.getPullRequest].
+func (f *gitlabForge) jobUrl(runId string) string {
+	projectId, jobId, found := strings.Cut(runId, "/")
+	if !found {
+		panic("Invalid GitLab run id, expected projectId/jobId: " + runId)
+	}
+	return "/projects/" + projectId + "/jobs/" + jobId
+}
+
+// Returns the most recent merge request of branchName.
+func (f *gitlabForge) findMergeRequest(branchName string) (gitlabMergeRequest, bool) {
+	query := url.Values{
+		"source_branch": {branchName},
+		"order_by":      {"created_at"},
+		"sort":          {"desc"},
+		"per_page":      {"1"},
+	}
+	var mergeRequests []gitlabMergeRequest
+	f.request(http.MethodGet, f.projectUrl(f.projectPath)+"/merge_requests?"+query.Encode(), nil, &mergeRequests)
+	if len(mergeRequests) == 0 {
+		return gitlabMergeRequest{}, false
+	}
+	return mergeRequests[0], true
+}
+
+// Returns the internal id of pullRequest, which is either its number or its source branch.
+func (f *gitlabForge) getIid(pullRequest string) int {
+	if iid, err := strconv.Atoi(pullRequest); err == nil {
+		return iid
+	}
+	mergeRequest, ok := f.findMergeRequest(pullRequest)
+	if !ok {
+		panic("No merge request found for branch " + pullRequest)
+	}
+	return mergeRequest.Iid
+}
+
+func (f *gitlabForge) getMergeRequest(iid int) gitlabMergeRequest {
+	var mergeRequest gitlabMergeRequest
+	f.request(http.MethodGet, f.mergeRequestUrl(iid), nil, &mergeRequest)
+	return mergeRequest
+}
+
+// Returns the merge request with its approvals and the jobs of its latest pipeline.
+func (f *gitlabForge) getPullRequest(iid int) PullRequest {
+	mergeRequest := f.getMergeRequest(iid)
+	pullRequest := mergeRequest.toPullRequest()
+	var approvals struct {
+		ApprovedBy []struct {
+			User gitlabUser `json:"user"`
+		} `json:"approved_by"`
+	}
+	f.request(http.MethodGet, f.mergeRequestUrl(iid)+"/approvals", nil, &approvals)
+	for _, approval := range approvals.ApprovedBy {
+		// Approvals are reset when new commits are pushed, so they are for the latest commit.
+		pullRequest.Reviews = append(pullRequest.Reviews,
+			PullRequestReview{Author: approval.User.Username, State: "APPROVED", Commit: mergeRequest.Sha})
+	}
+	if mergeRequest.HeadPipeline != nil {
+		var jobs []gitlabJob
+		f.request(http.MethodGet, fmt.Sprint("/projects/", mergeRequest.HeadPipeline.ProjectId,
+			"/pipelines/", mergeRequest.HeadPipeline.Id, "/jobs?per_page=100"), nil, &jobs)
+		for _, job := range jobs {
+			pullRequest.Checks = append(pullRequest.Checks, PullRequestCheck{
+				Name:  job.Name,
+				State: job.toCheckState(),
+				Url:   job.WebUrl,
+				// Jobs are in the project of the pipeline, which differs for forks.
+				RunId: fmt.Sprint(mergeRequest.HeadPipeline.ProjectId, "/", job.Id),
+			})
+		}
+	}
+	return pullRequest
+}
+
+func (f *gitlabForge) projectUrl(projectPath string) string {
+	return "/projects/" + url.PathEscape(projectPath)
+}
+
+func (f *gitlabForge) mergeRequestUrl(iid int) string {
+	return fmt.Sprint(f.projectUrl(f.projectPath), "/merge_requests/", iid)
+}
+
+// Sends a request for path with body as JSON, and unmarshalls the response into result if it is
+// not nil.
+func (f *gitlabForge) request(method string, path string, body any, result any) {
+	responseBody := f.requestRaw(method, path, body)
+	if result != nil {
+		if err := json.Unmarshal(responseBody, result); err != nil {
+			panic(fmt.Sprint("Could not parse GitLab response for ", path, ": ", err, "\n", string(responseBody)))
+		}
+	}
+}
+
+// Sends a request for path with body as JSON, and returns the body of the response.
+func (f *gitlabForge) requestRaw(method string, path string, body any) []byte {
+	var requestBody io.Reader
+	if body != nil {
+		bodyJson, err := json.Marshal(body)
+		if err != nil {
+			panic(err)
+		}
+		requestBody = bytes.NewReader(bodyJson)
+	}
+	request, err := http.NewRequest(method, f.apiUrl+path, requestBody)
+	if err != nil {
+		panic(err)
+	}
+	request.Header.Set("PRIVATE-TOKEN", f.token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	slog.Debug(fmt.Sprint("Sending ", method, " ", request.URL))
+	response, err := f.httpClient.Do(request)
+	if err != nil {
+		panic(fmt.Sprint("Could not reach GitLab: ", err))
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		panic(fmt.Sprint("Could not read GitLab response: ", err))
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		panic(fmt.Sprint("GitLab request ", method, " ", path, " failed with ", response.Status, ": ", string(responseBody)))
+	}
+	return responseBody
+}
+
+func (mergeRequest gitlabMergeRequest) toPullRequest() PullRequest {
+	pullRequest := PullRequest{
+		Number:     mergeRequest.Iid,
+		HeadBranch: mergeRequest.SourceBranch,
+		BaseBranch: mergeRequest.TargetBranch,
+		Title:      removeDraftPrefix(mergeRequest.Title),
+		Body:       mergeRequest.Description,
+		Url:        mergeRequest.WebUrl,
+		IsDraft:    mergeRequest.Draft,
+		HeadCommit: mergeRequest.Sha,
+		Reviews:    []PullRequestReview{},
+		Checks:     []PullRequestCheck{},
+	}
+	switch mergeRequest.State {
+	case "opened":
+		pullRequest.State = PullRequestStateOpen
+	case "merged":
+		pullRequest.State = PullRequestStateMerged
+		pullRequest.MergeCommit = mergeRequest.SquashCommitSha
+		if pullRequest.MergeCommit == "" {
+			pullRequest.MergeCommit = mergeRequest.MergeCommitSha
+		}
+	default:
+		pullRequest.State = PullRequestStateClosed
+	}
+	return pullRequest
+}
+
+// Returns the state of job as used by Github commit statuses, see [PullRequestCheck].
+func (job gitlabJob) toCheckState() string {
+	switch job.Status {
+	case "success":
+		return "SUCCESS"
+	case "failed":
+		if job.AllowFailure {
+			return "NEUTRAL"
+		}
+		return "FAILURE"
+	case "canceled":
+		return "CANCELLED"
+	case "skipped", "manual":
+		return "SKIPPED"
+	default:
+		return "PENDING"
+	}
+}
+
+func removeDraftPrefix(title string) string {
+	for _, prefix := range gitlabDraftPrefixes {
+		if strings.HasPrefix(title, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(title, prefix))
+		}
+	}
+	return title
+}
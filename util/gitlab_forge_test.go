@@ -0,0 +1,181 @@
+package util
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type gitlabRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Body   map[string]any
+}
+
+// Returns a server that responds to requests with the responses keyed by "METHOD path", and
+// records them.
+func newFakeGitlabServer(t *testing.T, responses map[string]string, requests *[]gitlabRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-token", r.Header.Get("PRIVATE-TOKEN"))
+		request := gitlabRequest{Method: r.Method, Path: r.URL.EscapedPath(), Query: r.URL.RawQuery}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &request.Body); err != nil {
+				t.Error(err)
+			}
+		}
+		*requests = append(*requests, request)
+		response, ok := responses[r.Method+" "+request.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGitlabForge_GetPullRequests_ReturnsApprovalsAndJobs(t *testing.T) {
+	assert := assert.New(t)
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Frepo/merge_requests": `[{"iid": 7}]`,
+		"GET /api/v4/projects/group%2Frepo/merge_requests/7": `{"iid": 7, "title": "Draft: First",
+			"description": "Description", "state": "opened", "source_branch": "first", "target_branch": "main",
+			"web_url": "https://gitlab.example.com/group/repo/-/merge_requests/7", "draft": true, "sha": "abc",
+			"merge_commit_sha": null, "head_pipeline": {"id": 12, "project_id": 3}}`,
+		"GET /api/v4/projects/group%2Frepo/merge_requests/7/approvals": `{"approved_by": [{"user": {"username": "mybestie"}}]}`,
+		"GET /api/v4/projects/3/pipelines/12/jobs": `[
+			{"id": 21, "name": "build", "status": "success", "web_url": "https://gitlab.example.com/group/repo/-/jobs/21"},
+			{"id": 22, "name": "lint", "status": "failed", "allow_failure": true},
+			{"id": 23, "name": "test", "status": "running"}]`,
+	}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "group/repo")
+
+	pullRequests := forge.GetPullRequests([]string{"first"})
+
+	assert.Equal("source_branch=first", requests[0].Query[len(requests[0].Query)-len("source_branch=first"):])
+	assert.Equal(PullRequest{
+		Number:     7,
+		HeadBranch: "first",
+		BaseBranch: "main",
+		State:      PullRequestStateOpen,
+		Title:      "First",
+		Body:       "Description",
+		Url:        "https://gitlab.example.com/group/repo/-/merge_requests/7",
+		IsDraft:    true,
+		HeadCommit: "abc",
+		Reviews:    []PullRequestReview{{Author: "mybestie", State: "APPROVED", Commit: "abc"}},
+		Checks: []PullRequestCheck{
+			{Name: "build", State: "SUCCESS", Url: "https://gitlab.example.com/group/repo/-/jobs/21", RunId: "3/21"},
+			{Name: "lint", State: "NEUTRAL", RunId: "3/22"},
+			{Name: "test", State: "PENDING", RunId: "3/23"},
+		},
+	}, pullRequests["first"])
+}
+
+func TestGitlabForge_CreatePullRequest_WhenFork_TargetsBaseProject(t *testing.T) {
+	assert := assert.New(t)
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Frepo":              `{"id": 3}`,
+		"POST /api/v4/projects/me%2Frepo/merge_requests": `{"iid": 8, "web_url": "https://gitlab.example.com/group/repo/-/merge_requests/8"}`,
+	}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "me/repo")
+
+	url := forge.CreatePullRequest(CreatePullRequestOptions{
+		Title: "First", Body: "Description", BaseBranch: "main", HeadBranch: "me:first", Draft: true,
+	})
+
+	assert.Equal("https://gitlab.example.com/group/repo/-/merge_requests/8", url)
+	assert.Equal(map[string]any{
+		"source_branch":     "first",
+		"target_branch":     "main",
+		"title":             "Draft: First",
+		"description":       "Description",
+		"target_project_id": float64(3),
+	}, requests[1].Body)
+}
+
+func TestGitlabForge_AddReviewers_KeepsExistingReviewers(t *testing.T) {
+	assert := assert.New(t)
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Frepo/merge_requests/7": `{"iid": 7, "web_url": "https://gitlab.example.com/group/repo/-/merge_requests/7",
+			"reviewers": [{"id": 1, "username": "existing"}]}`,
+		"GET /api/v4/users": `[{"id": 2, "username": "mybestie"}]`,
+		"PUT /api/v4/projects/group%2Frepo/merge_requests/7": `{}`,
+	}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "group/repo")
+
+	url := forge.AddReviewers("7", []string{"mybestie"})
+
+	assert.Equal("https://gitlab.example.com/group/repo/-/merge_requests/7", url)
+	assert.Equal("username=mybestie", requests[1].Query)
+	assert.Equal(map[string]any{"reviewer_ids": []any{float64(1), float64(2)}}, requests[2].Body)
+}
+
+func TestGitlabForge_MergePullRequest_WithBranch_MergesWhenPipelineSucceeds(t *testing.T) {
+	assert := assert.New(t)
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{
+		"GET /api/v4/projects/group%2Frepo/merge_requests":         `[{"iid": 7}]`,
+		"PUT /api/v4/projects/group%2Frepo/merge_requests/7/merge": `{}`,
+	}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "group/repo")
+
+	forge.MergePullRequest("first", "squash", true)
+
+	assert.Equal(2, len(requests))
+	assert.Equal(map[string]any{"squash": true, "merge_when_pipeline_succeeds": true}, requests[1].Body)
+}
+
+func TestGitlabForge_MergePullRequest_WithRebase_RebasesBeforeMerging(t *testing.T) {
+	assert := assert.New(t)
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{
+		"PUT /api/v4/projects/group%2Frepo/merge_requests/7/rebase": `{"rebase_in_progress": true}`,
+		"GET /api/v4/projects/group%2Frepo/merge_requests/7":        `{"iid": 7, "rebase_in_progress": false}`,
+		"PUT /api/v4/projects/group%2Frepo/merge_requests/7/merge":  `{}`,
+	}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "group/repo")
+
+	forge.MergePullRequest("7", "rebase", false)
+
+	assert.Equal([]string{"PUT /api/v4/projects/group%2Frepo/merge_requests/7/rebase",
+		"GET /api/v4/projects/group%2Frepo/merge_requests/7",
+		"PUT /api/v4/projects/group%2Frepo/merge_requests/7/merge"},
+		MapSlice(requests, func(request gitlabRequest) string {
+			return request.Method + " " + request.Path
+		}))
+	assert.Equal(map[string]any{"squash": false}, requests[2].Body)
+}
+
+func TestGitlabForge_GetFailedCheckLog_ReturnsJobTrace(t *testing.T) {
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{
+		"GET /api/v4/projects/3/jobs/22/trace": "lint failed\n",
+	}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "group/repo")
+
+	assert.Equal(t, "lint failed\n", forge.GetFailedCheckLog("3/22"))
+}
+
+func TestGitlabForge_WhenRequestFails_Panics(t *testing.T) {
+	requests := []gitlabRequest{}
+	server := newFakeGitlabServer(t, map[string]string{}, &requests)
+	forge := NewGitlabForge(server.URL+"/api/v4", "test-token", "group/repo", "group/repo")
+
+	assert.Panics(t, func() {
+		forge.GetLoggedInUsername()
+	})
+}
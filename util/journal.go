@@ -0,0 +1,176 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Maximum number of entries kept in the journal.
+const MAX_JOURNAL_ENTRIES = 30
+
+const journalFilename = "journal.json"
+
+// Record of how a command changed the branches of the repository, so that it can be undone.
+type JournalEntry struct {
+	Time time.Time `json:"time"`
+	// Command line arguments of the command.
+	Command string `json:"command"`
+	// Branch that was checked out before and after the command.
+	HeadBefore string `json:"headBefore"`
+	HeadAfter  string `json:"headAfter"`
+	// Commit of each local branch, and remote branch, that the command changed, keyed by ref name.
+	// Refs that did not exist are not included.
+	Before map[string]string `json:"before"`
+	After  map[string]string `json:"after"`
+	// Stashes that the command created and did not pop.
+	Stashes []string `json:"stashes,omitempty"`
+	// Whether "sd undo" was used to undo the entry.
+	Undone bool `json:"undone,omitempty"`
+}
+
+// Returns the refs that changed, in sorted order.
+func (entry JournalEntry) ChangedRefs() []string {
+	refs := slices.Collect(maps.Keys(entry.Before))
+	for ref := range entry.After {
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	slices.Sort(refs)
+	return refs
+}
+
+// Journal entry for the command that is currently executing.
+var activeJournalEntry *JournalEntry
+
+// Starts recording a journal entry for command by saving the current state of the branches.
+// Call [FinishJournalEntry] once the command completes.
+func StartJournalEntry(command string) {
+	activeJournalEntry = &JournalEntry{
+		Time:       time.Now(),
+		Command:    command,
+		HeadBefore: GetCurrentBranchName(),
+		Before:     GetJournalRefs(),
+		Stashes:    []string{},
+	}
+}
+
+// Saves the journal entry started by [StartJournalEntry], if the command changed anything.
+// Any entries that were undone are discarded, as they can no longer be redone.
+func FinishJournalEntry(appConfig AppConfig) {
+	entry := activeJournalEntry
+	activeJournalEntry = nil
+	if entry == nil {
+		panic("FinishJournalEntry called without StartJournalEntry")
+	}
+	if IsRebaseInProgress() {
+		// Undoing would restore the branches on top of the stopped rebase.
+		slog.Info("Not adding to journal because a rebase is in progress")
+		return
+	}
+	after := GetJournalRefs()
+	removeUnchangedRefs(entry.Before, after)
+	entry.After = after
+	entry.HeadAfter = GetCurrentBranchName()
+	if len(entry.Before) == 0 && len(entry.After) == 0 && len(entry.Stashes) == 0 {
+		slog.Debug("Command did not change any branches, not adding to journal")
+		return
+	}
+	entries := slices.DeleteFunc(ReadJournal(appConfig), func(next JournalEntry) bool {
+		return next.Undone
+	})
+	entries = append(entries, *entry)
+	WriteJournal(appConfig, entries)
+}
+
+// Removes the refs that have the same commit in before and after.
+func removeUnchangedRefs(before map[string]string, after map[string]string) {
+	for ref, commit := range before {
+		if after[ref] == commit {
+			delete(before, ref)
+			delete(after, ref)
+		}
+	}
+}
+
+// Records that a stash was created, for use by [Stash].
+func recordJournalStash(stashCommit string) {
+	if activeJournalEntry != nil {
+		activeJournalEntry.Stashes = append(activeJournalEntry.Stashes, stashCommit)
+	}
+}
+
+// Records that the most recent stash was popped, for use by [PopStash].
+func recordJournalStashPopped() {
+	if activeJournalEntry != nil && len(activeJournalEntry.Stashes) > 0 {
+		activeJournalEntry.Stashes = activeJournalEntry.Stashes[:len(activeJournalEntry.Stashes)-1]
+	}
+}
+
+// Returns the commit of each local branch and each branch on origin, except for the main branch
+// on origin which is only changed by fetching.
+func GetJournalRefs() map[string]string {
+	out := ExecuteOrDie(ExecuteOptions{}, "git", "for-each-ref", "--format=%(refname) %(objectname)", "refs/heads", "refs/remotes/"+GetPushRemote())
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if fields[0] == "refs/remotes/"+GetPushRemote()+"/HEAD" || fields[0] == "refs/remotes/"+GetPushRemote()+"/"+GetMainBranchOrDie() {
+			continue
+		}
+		refs[fields[0]] = fields[1]
+	}
+	return refs
+}
+
+/*
+journal entries are returned as:
+[0] least recent
+[last element] most recent
+*/
+func ReadJournal(appConfig AppConfig) []JournalEntry {
+	entries := []JournalEntry{}
+	data, err := os.ReadFile(getJournalFile(appConfig))
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries
+	}
+	if err != nil {
+		panic("Could not read journal: " + err.Error())
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		panic(fmt.Sprint("Could not parse journal ", getJournalFile(appConfig), ": ", err))
+	}
+	return entries
+}
+
+func WriteJournal(appConfig AppConfig, entries []JournalEntry) {
+	if len(entries) > MAX_JOURNAL_ENTRIES {
+		entries = entries[len(entries)-MAX_JOURNAL_ENTRIES:]
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	if writeErr := os.WriteFile(getJournalFile(appConfig), data, 0644); writeErr != nil {
+		panic("Could not write file: " + writeErr.Error())
+	}
+}
+
+func getJournalFile(appConfig AppConfig) string {
+	appCacheDir := filepath.Join(appConfig.UserCacheDir, "gh-stacked-diff", GetRepoName())
+	if err := os.MkdirAll(appCacheDir, os.ModePerm); err != nil {
+		panic("Could not create directory for journal: " + err.Error())
+	}
+	return filepath.Join(appCacheDir, journalFilename)
+}
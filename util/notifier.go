@@ -0,0 +1,207 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event of a long-running command that the user is notified of, such as checks failing.
+type Notification struct {
+	// Short summary, for example "Checks failed".
+	Title string
+	// Details, for example which checks failed.
+	Message string
+	// Page to open for more details, if any.
+	Url string
+}
+
+// Notifies the user of events of long-running commands.
+// Allows swapping in a [FakeNotifier] via Dependency Injection during tests.
+//
+// Notifiers only log failures, so that a command does not fail because it could not notify.
+type Notifier interface {
+	Notify(notification Notification)
+}
+
+var globalNotifier Notifier
+var globalNotifierOnce *sync.Once = new(sync.Once)
+
+// Sets the notifier that [GetNotifier] returns.
+func SetGlobalNotifier(notifier Notifier) {
+	globalNotifier = notifier
+}
+
+// Returns the notifier selected by the "notifier" config, which writes to appConfig.Io.
+func GetNotifier(appConfig AppConfig) Notifier {
+	if globalNotifier == nil {
+		globalNotifierOnce.Do(func() {
+			globalNotifier = NewNotifier(GetConfigString("notifier"), appConfig.Io)
+		})
+	}
+	return globalNotifier
+}
+
+// Returns a notifier for names, a comma-separated list of:
+//
+//	auto      say on Mac, desktop if notify-send is installed, otherwise bell
+//	bell      rings the terminal bell
+//	command   runs the "notify-command" config with a shell
+//	desktop   shows a desktop notification via notify-send
+//	none      does nothing
+//	say       uses voice output (Mac only)
+//	webhook   posts Slack compatible JSON to the "notify-webhook" config
+//
+// The bell is written to stdIo.Err.
+func NewNotifier(names string, stdIo StdIo) Notifier {
+	notifiers := multiNotifier{}
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name == "auto" {
+			name = getAutoNotifierName()
+		}
+		switch name {
+		case "", "none":
+		case "bell":
+			notifiers = append(notifiers, bellNotifier{err: stdIo.Err})
+		case "command":
+			notifiers = append(notifiers, commandNotifier{command: GetConfigString("notify-command")})
+		case "desktop":
+			notifiers = append(notifiers, desktopNotifier{})
+		case "say":
+			notifiers = append(notifiers, sayNotifier{})
+		case "webhook":
+			notifiers = append(notifiers, NewWebhookNotifier(GetConfigString("notify-webhook")))
+		default:
+			panic("Unsupported notifier " + name + ", expected auto, bell, command, desktop, none, say, or webhook")
+		}
+	}
+	return notifiers
+}
+
+func getAutoNotifierName() string {
+	if runtime.GOOS == "darwin" {
+		return "say"
+	}
+	if _, err := exec.LookPath("notify-send"); err == nil {
+		return "desktop"
+	}
+	return "bell"
+}
+
+// Notifies all of its notifiers.
+type multiNotifier []Notifier
+
+func (n multiNotifier) Notify(notification Notification) {
+	for _, notifier := range n {
+		notifier.Notify(notification)
+	}
+}
+
+type bellNotifier struct {
+	// Stderr, so that the bell does not end up in output that is redirected to a file.
+	err io.Writer
+}
+
+func (n bellNotifier) Notify(notification Notification) {
+	Fprint(n.err, "\a")
+}
+
+type sayNotifier struct{}
+
+func (n sayNotifier) Notify(notification Notification) {
+	// Spell out PR, otherwise it is pronounced as a word.
+	text := strings.ReplaceAll(notification.Title, "PR", "P R")
+	if out, err := Execute(ExecuteOptions{}, "say", text); err != nil {
+		slog.Warn(fmt.Sprint("Could not notify with say: ", err, " ", out))
+	}
+}
+
+type desktopNotifier struct{}
+
+func (n desktopNotifier) Notify(notification Notification) {
+	body := notification.Message
+	if notification.Url != "" {
+		body = strings.TrimSpace(body + "\n" + notification.Url)
+	}
+	if out, err := Execute(ExecuteOptions{}, "notify-send", "--app-name=sd", notification.Title, body); err != nil {
+		slog.Warn(fmt.Sprint("Could not notify with notify-send: ", err, " ", out))
+	}
+}
+
+// Runs a command with a shell, with the notification in the environment variables
+// SD_NOTIFICATION_TITLE, SD_NOTIFICATION_MESSAGE, and SD_NOTIFICATION_URL.
+type commandNotifier struct {
+	command string
+}
+
+func (n commandNotifier) Notify(notification Notification) {
+	if n.command == "" {
+		slog.Warn("Cannot notify with a command because the notify-command config is not set")
+		return
+	}
+	options := ExecuteOptions{EnvironmentVariables: []string{
+		"SD_NOTIFICATION_TITLE=" + notification.Title,
+		"SD_NOTIFICATION_MESSAGE=" + notification.Message,
+		"SD_NOTIFICATION_URL=" + notification.Url,
+	}}
+	var out string
+	var err error
+	if runtime.GOOS == "windows" {
+		out, err = Execute(options, "cmd", "/C", n.command)
+	} else {
+		out, err = Execute(options, "sh", "-c", n.command)
+	}
+	if err != nil {
+		slog.Warn(fmt.Sprint("Notify command failed: ", err, " ", out))
+	}
+}
+
+// How long to wait for the webhook of [webhookNotifier] to respond.
+const webhookTimeout = 10 * time.Second
+
+// Posts notifications as Slack compatible JSON, which many other services also accept.
+type webhookNotifier struct {
+	httpClient *http.Client
+	url        string
+}
+
+// Returns a [Notifier] that posts notifications to url as Slack compatible JSON.
+func NewWebhookNotifier(url string) Notifier {
+	return webhookNotifier{httpClient: &http.Client{Timeout: webhookTimeout}, url: url}
+}
+
+func (n webhookNotifier) Notify(notification Notification) {
+	if n.url == "" {
+		slog.Warn("Cannot notify with a webhook because the notify-webhook config is not set")
+		return
+	}
+	text := "*" + notification.Title + "*"
+	if notification.Message != "" {
+		text += "\n" + notification.Message
+	}
+	if notification.Url != "" {
+		text += "\n" + notification.Url
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		panic(err)
+	}
+	response, err := n.httpClient.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		slog.Warn(fmt.Sprint("Could not notify with webhook: ", err))
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		slog.Warn("Could not notify with webhook, it responded with " + response.Status)
+	}
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookNotifier_PostsSlackCompatibleJson(t *testing.T) {
+	assert := assert.New(t)
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("application/json", r.Header.Get("Content-Type"))
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	NewWebhookNotifier(server.URL).Notify(Notification{
+		Title: "Checks failed", Message: "First commit: lint", Url: "https://github.com/owner/repo/pull/7",
+	})
+
+	assert.Equal(map[string]string{"text": "*Checks failed*\nFirst commit: lint\nhttps://github.com/owner/repo/pull/7"}, body)
+}
+
+func TestCommandNotifier_SetsNotificationEnvironmentVariables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Uses sh")
+	}
+	outFile := filepath.Join(t.TempDir(), "notification")
+	notifier := commandNotifier{command: "printf '%s|%s|%s' \"$SD_NOTIFICATION_TITLE\" \"$SD_NOTIFICATION_MESSAGE\" \"$SD_NOTIFICATION_URL\" > " + outFile}
+
+	notifier.Notify(Notification{Title: "PR has been merged", Message: "First commit", Url: "https://example.com"})
+
+	out, err := os.ReadFile(outFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "PR has been merged|First commit|https://example.com", string(out))
+}
+
+func TestNewNotifier_WithMultipleNames_ReturnsAll(t *testing.T) {
+	stdIo := StdIo{Err: &bytes.Buffer{}}
+	assert.Equal(t, multiNotifier{bellNotifier{err: stdIo.Err}, sayNotifier{}}, NewNotifier("bell, say", stdIo))
+	assert.Equal(t, multiNotifier{}, NewNotifier("none", stdIo))
+}
+
+func TestBellNotifier_WritesBellToErr(t *testing.T) {
+	err := &bytes.Buffer{}
+
+	NewNotifier("bell", StdIo{Err: err}).Notify(Notification{Title: "PR has been merged"})
+
+	assert.Equal(t, "\a", err.String())
+}
+
+func TestNewNotifier_WithUnknownName_Panics(t *testing.T) {
+	assert.Panics(t, func() {
+		NewNotifier("carrier-pigeon", StdIo{})
+	})
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Returns the remote that PRs are opened against and that main is synced from.
+func GetBaseRemote() string {
+	return GetConfigString("base-remote")
+}
+
+// Returns the remote that branches are pushed to. This is a fork of the base remote when
+// contributing to a repository that you cannot push to.
+func GetPushRemote() string {
+	if pushRemote := GetConfigString("push-remote"); pushRemote != "" {
+		return pushRemote
+	}
+	return GetBaseRemote()
+}
+
+// Returns whether branches are pushed to a different remote than the one PRs are opened against.
+func IsForkWorkflow() bool {
+	return GetPushRemote() != GetBaseRemote()
+}
+
+// Returns the main branch on the base remote, for example "origin/main".
+func GetRemoteMainBranchOrDie() string {
+	return GetBaseRemote() + "/" + GetMainBranchOrDie()
+}
+
+// Returns the head of the PR of branchName as expected by "gh pr create --head", which is
+// "owner:branchName" if the branch is pushed to a fork.
+func GetPullRequestHead(branchName string) string {
+	if !IsForkWorkflow() {
+		return branchName
+	}
+	owner, _, _ := strings.Cut(getRemoteNameWithOwner(GetPushRemote()), "/")
+	return owner + ":" + branchName
+}
+
+// Returns the environment variables to use for gh so that it uses the repository of the base
+// remote, or nil if gh can choose the repository itself.
+func getGhEnvironmentVariables() []string {
+	if !IsForkWorkflow() {
+		return nil
+	}
+	return []string{"GH_REPO=" + GetRepoNameWithOwner()}
+}
+
+// Returns "repository-owner/repository-name" from the URL of remote.
+func getRemoteNameWithOwner(remote string) string {
+	url := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "remote", "get-url", remote))
+	return parseRemoteNameWithOwner(url)
+}
+
+// Returns "repository-owner/repository-name" from a remote URL such as
+// "git@github.com:owner/name.git" or "https://github.com/owner/name". The owner is the full path
+// before the name, such as "group/subgroup" for GitLab. For a local path, which has no host, only
+// the last two parts of the path are used.
+func parseRemoteNameWithOwner(url string) string {
+	host, path := parseRemoteUrl(url)
+	parts := strings.FieldsFunc(path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '\\'
+	})
+	if len(parts) < 2 {
+		panic("Cannot determine the owner and name of the repository from the remote URL " + url)
+	}
+	if host == "" {
+		parts = parts[len(parts)-2:]
+	}
+	return strings.Join(parts, "/")
+}
+
+// Returns the host and the path, without ".git", of a remote URL such as
+// "git@gitlab.example.com:group/name.git" or "https://gitlab.example.com/group/name". The host
+// is empty for a local path.
+func parseRemoteUrl(remoteUrl string) (string, string) {
+	remoteUrl = strings.TrimSuffix(strings.TrimSuffix(remoteUrl, "/"), ".git")
+	if strings.Contains(remoteUrl, "://") {
+		parsed, err := url.Parse(remoteUrl)
+		if err != nil {
+			panic("Invalid remote URL " + remoteUrl + ": " + err.Error())
+		}
+		return parsed.Hostname(), strings.TrimPrefix(parsed.Path, "/")
+	}
+	userAndHost, path, found := strings.Cut(remoteUrl, ":")
+	// A single letter before ":" is a Windows drive.
+	if !found || strings.Contains(userAndHost, "/") || len(userAndHost) == 1 {
+		return "", remoteUrl
+	}
+	_, host, hasUser := strings.Cut(userAndHost, "@")
+	if !hasUser {
+		host = userAndHost
+	}
+	return host, path
+}
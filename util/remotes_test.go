@@ -0,0 +1,34 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRemoteUrl_ReturnsHostAndPath(t *testing.T) {
+	assert := assert.New(t)
+	for url, expected := range map[string][2]string{
+		"git@gitlab.example.com:group/sub/repo.git":        {"gitlab.example.com", "group/sub/repo"},
+		"https://gitlab.example.com/group/repo":            {"gitlab.example.com", "group/repo"},
+		"ssh://git@gitlab.example.com:2222/group/repo.git": {"gitlab.example.com", "group/repo"},
+		"/tmp/owner/repo.git":                              {"", "/tmp/owner/repo"},
+	} {
+		host, path := parseRemoteUrl(url)
+		assert.Equal(expected, [2]string{host, path}, url)
+	}
+}
+
+func TestParseRemoteNameWithOwner_ReturnsPathAfterHost(t *testing.T) {
+	assert.Equal(t, "owner/repo", parseRemoteNameWithOwner("git@github.com:owner/repo.git"))
+	assert.Equal(t, "owner/repo", parseRemoteNameWithOwner("https://github.com/owner/repo"))
+	assert.Equal(t, "group/sub/repo", parseRemoteNameWithOwner("git@gitlab.example.com:group/sub/repo.git"))
+	assert.Equal(t, "group/sub/repo", parseRemoteNameWithOwner("https://gitlab.example.com/group/sub/repo"))
+	assert.Equal(t, "owner/repo", parseRemoteNameWithOwner("/tmp/owner/repo.git"))
+}
+
+func TestTrimServerPath_WhenServerOnSubpath_RemovesSubpath(t *testing.T) {
+	assert.Equal(t, "group/repo", trimServerPath("https://example.com/gitlab/", "gitlab/group/repo"))
+	assert.Equal(t, "group/repo", trimServerPath("https://example.com/gitlab", "group/repo"))
+	assert.Equal(t, "gitlab/repo", trimServerPath("https://gitlab.example.com", "gitlab/repo"))
+}
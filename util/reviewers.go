@@ -0,0 +1,175 @@
+package util
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Returns the reviewers of the comma-separated reviewers, with any "@group" of the
+// "reviewer-groups" config replaced by its members, the "@" removed from usernames and from team
+// slugs such as "@org/team", and without duplicates.
+func ExpandReviewers(reviewers string) []string {
+	groups := GetReviewerGroups()
+	expanded := make([]string, 0)
+	for _, reviewer := range strings.Split(reviewers, ",") {
+		reviewer = strings.TrimSpace(reviewer)
+		if members, ok := groups[strings.TrimPrefix(reviewer, "@")]; ok && strings.HasPrefix(reviewer, "@") {
+			expanded = append(expanded, members...)
+		} else if reviewer != "" {
+			expanded = append(expanded, strings.TrimPrefix(reviewer, "@"))
+		}
+	}
+	unique := make([]string, 0, len(expanded))
+	for _, reviewer := range expanded {
+		if !slices.Contains(unique, reviewer) {
+			unique = append(unique, reviewer)
+		}
+	}
+	return unique
+}
+
+// Returns the members of each group of the "reviewer-groups" config, keyed by group name. The
+// config is formatted as "group1=user1,user2;group2=user3,org/team".
+func GetReviewerGroups() map[string][]string {
+	groups := make(map[string][]string)
+	config := GetConfigValue("reviewer-groups")
+	for _, group := range strings.Split(config.Value, ";") {
+		if strings.TrimSpace(group) == "" {
+			continue
+		}
+		name, members, ok := strings.Cut(group, "=")
+		if !ok {
+			panic("Invalid reviewer group \"" + group + "\" in reviewer-groups from " + config.SourceDescription() +
+				", expected name=user1,user2")
+		}
+		groups[strings.TrimPrefix(strings.TrimSpace(name), "@")] = FilterSlice(
+			MapSlice(strings.Split(members, ","), func(member string) string {
+				return strings.TrimPrefix(strings.TrimSpace(member), "@")
+			}),
+			func(member string) bool {
+				return member != ""
+			})
+	}
+	return groups
+}
+
+// Returns whether reviewer is a team slug, such as "org/team", rather than a username.
+func IsTeamReviewer(reviewer string) bool {
+	return strings.Contains(reviewer, "/")
+}
+
+// Returns the reviewer for a code owner from a CODEOWNERS file, such as "octocat" for "@octocat"
+// or "org/team" for "@org/team". Returns false for owners that cannot review, namely emails.
+func GetCodeOwnerReviewer(owner string) (string, bool) {
+	reviewer := strings.TrimPrefix(owner, "@")
+	if reviewer == "" || strings.Contains(reviewer, "@") {
+		return "", false
+	}
+	return reviewer, true
+}
+
+// Values of [PickReviewersOptions.Strategy].
+const (
+	// Picks the reviewers that were least recently picked.
+	PickStrategyRoundRobin = "round-robin"
+	// Picks the reviewers with the fewest open review requests, then the least recently picked.
+	PickStrategyFewestRequests = "fewest-requests"
+	PickStrategyRandom         = "random"
+)
+
+// Name of the file, in the history dir of the repository, with when each reviewer was last picked.
+const reviewerAssignmentsFile = "reviewer-assignments.json"
+
+// Options for [PickReviewers].
+type PickReviewersOptions struct {
+	// Number of reviewers to pick, 0 to pick all of them.
+	Count int
+	// One of the PickStrategy constants.
+	Strategy string
+}
+
+// When a reviewer was last picked by [PickReviewers], and how many times in total.
+type ReviewerAssignment struct {
+	LastPicked time.Time `json:"lastPicked"`
+	Picks      int       `json:"picks"`
+}
+
+// Serializes picks, as reviewers are added to multiple PRs concurrently.
+var pickReviewersMutex sync.Mutex
+
+// Returns whether strategy is one of the PickStrategy constants.
+func IsValidPickStrategy(strategy string) bool {
+	return slices.Contains([]string{PickStrategyRoundRobin, PickStrategyFewestRequests, PickStrategyRandom}, strategy)
+}
+
+// Returns the options set by the "pick-reviewers" and "pick-strategy" config.
+func GetPickReviewersOptions() PickReviewersOptions {
+	return PickReviewersOptions{Count: GetConfigInt("pick-reviewers"), Strategy: GetConfigString("pick-strategy")}
+}
+
+// Returns reviewers without those in the "out-of-office" config, which is expanded the same way
+// as reviewers by [ExpandReviewers].
+func RemoveOutOfOfficeReviewers(reviewers []string) []string {
+	outOfOffice := ExpandReviewers(GetConfigString("out-of-office"))
+	return FilterSlice(reviewers, func(reviewer string) bool {
+		if slices.Contains(outOfOffice, reviewer) {
+			slog.Info("Not adding " + reviewer + " as a reviewer as they are out of office")
+			return false
+		}
+		return true
+	})
+}
+
+// Returns options.Count reviewers picked from pool using options.Strategy, and records them in the
+// history dir so that later picks are spread fairly across runs. Teams are always returned, and are
+// not counted, as Github assigns their members. The logged in user is never picked.
+func PickReviewers(appConfig AppConfig, pool []string, options PickReviewersOptions) []string {
+	if options.Count <= 0 {
+		return pool
+	}
+	if !IsValidPickStrategy(options.Strategy) {
+		panic("Invalid pick-strategy \"" + options.Strategy + "\", expected round-robin, fewest-requests, or random")
+	}
+	teams := FilterSlice(pool, IsTeamReviewer)
+	loggedInUser := GetForge().GetLoggedInUsername()
+	candidates := FilterSlice(pool, func(reviewer string) bool {
+		return !IsTeamReviewer(reviewer) && reviewer != loggedInUser
+	})
+
+	pickReviewersMutex.Lock()
+	defer pickReviewersMutex.Unlock()
+	assignments := readReviewerAssignments(appConfig)
+	// Sort by least recently picked, keeping the order of pool for ties.
+	slices.SortStableFunc(candidates, func(a string, b string) int {
+		return assignments[a].LastPicked.Compare(assignments[b].LastPicked)
+	})
+	switch options.Strategy {
+	case PickStrategyFewestRequests:
+		counts := GetForge().GetReviewRequestCounts(candidates)
+		slices.SortStableFunc(candidates, func(a string, b string) int {
+			return counts[a] - counts[b]
+		})
+	case PickStrategyRandom:
+		rand.Shuffle(len(candidates), func(i int, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+	}
+	picked := candidates[:min(options.Count, len(candidates))]
+	now := time.Now()
+	for _, reviewer := range picked {
+		assignments[reviewer] = ReviewerAssignment{LastPicked: now, Picks: assignments[reviewer].Picks + 1}
+	}
+	writeJsonFile(getHistoryFile(appConfig, reviewerAssignmentsFile), assignments)
+	return append(teams, picked...)
+}
+
+// Returns the assignments recorded by [PickReviewers], keyed by reviewer.
+func readReviewerAssignments(appConfig AppConfig) map[string]ReviewerAssignment {
+	assignments := make(map[string]ReviewerAssignment)
+	readJsonFile(getHistoryFile(appConfig, reviewerAssignmentsFile), &assignments)
+	return assignments
+}
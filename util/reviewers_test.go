@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandReviewers_ExpandsGroupsAndKeepsTeams(t *testing.T) {
+	t.Setenv("SD_REVIEWER_GROUPS", "mobile-core=alice,bob,@carol;web=dave")
+
+	assert.Equal(t, []string{"alice", "bob", "carol", "my-org/ios", "erin"},
+		ExpandReviewers("@mobile-core,@my-org/ios, bob,erin"))
+}
+
+func TestRemoveOutOfOfficeReviewers_IgnoresSpacesAndAtSigns(t *testing.T) {
+	t.Setenv("SD_OUT_OF_OFFICE", "alice, @bob,")
+
+	assert.Equal(t, []string{"carol"}, RemoveOutOfOfficeReviewers([]string{"alice", "bob", "carol"}))
+}
+
+func TestGetReviewerGroups_WhenInvalid_Panics(t *testing.T) {
+	t.Setenv("SD_REVIEWER_GROUPS", "mobile-core")
+
+	assert.Panics(t, func() {
+		GetReviewerGroups()
+	})
+}
+
+func TestGetCodeOwnerReviewer_SkipsEmails(t *testing.T) {
+	assert := assert.New(t)
+
+	reviewer, ok := GetCodeOwnerReviewer("@my-org/ios")
+	assert.True(ok)
+	assert.Equal("my-org/ios", reviewer)
+	_, ok = GetCodeOwnerReviewer("someone@example.com")
+	assert.False(ok)
+}
@@ -0,0 +1,283 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Association of a commit on main with its branch and PR, as recorded by [RecordStackEntry].
+type StackEntry struct {
+	// Full commit hash.
+	Commit string `json:"commit"`
+	// Stable patch-id of the commit, used to follow the commit when it is rewritten by a rebase.
+	PatchId string `json:"patchId"`
+	Branch  string `json:"branch"`
+	// Zero if the PR number is not known.
+	PullRequest int `json:"pullRequest,omitempty"`
+}
+
+type stackStore struct {
+	Entries []StackEntry `json:"entries"`
+	// Whether entries have been matched to rewritten commits by patch-id since the store was read
+	// or since [InvalidateStackRewrites], see [GetStackEntries].
+	followedRewrites bool
+}
+
+// Stack stores that have been read, keyed by file, so that each is only read once per command.
+// Only the command that is running writes to the store, as the watch process checks each
+// repository with a separate process.
+var stackStores = make(map[string]stackStore)
+
+// Dirs returned by [getGitStackedDiffDir], keyed by the dir of the repository.
+var gitStackedDiffDirs = make(map[string]string)
+
+var stackStoresMutex sync.Mutex
+
+// Forgets the stack stores that have been read, so that they are read again by the next command.
+func ResetStackStores() {
+	stackStoresMutex.Lock()
+	defer stackStoresMutex.Unlock()
+	clear(stackStores)
+	clear(gitStackedDiffDirs)
+}
+
+// Marks that commits may have been rewritten, for example by a rebase, so that the next
+// [GetStackEntries] that follows rewrites matches entries by patch-id again.
+func InvalidateStackRewrites() {
+	store := readStackStore()
+	store.followedRewrites = false
+	cacheStackStore(store)
+}
+
+// Records that commit is associated with branchName and pullRequest, replacing any previous entry
+// for the commit or the branch. A pullRequest of zero keeps the PR number of the previous entry.
+func RecordStackEntry(commit string, branchName string, pullRequest int) {
+	fullCommit := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{}, "git", "rev-parse", commit))
+	store := readStackStore()
+	if pullRequest == 0 {
+		// Keep the PR number of the branch if it was already known.
+		if index := slices.IndexFunc(store.Entries, func(entry StackEntry) bool {
+			return entry.Branch == branchName
+		}); index != -1 {
+			pullRequest = store.Entries[index].PullRequest
+		}
+	}
+	store.Entries = slices.DeleteFunc(store.Entries, func(entry StackEntry) bool {
+		return entry.Commit == fullCommit || entry.Branch == branchName
+	})
+	patchIds := getPatchIds([]string{fullCommit})
+	store.Entries = append(store.Entries, StackEntry{
+		Commit:      fullCommit,
+		PatchId:     patchIds[fullCommit],
+		Branch:      branchName,
+		PullRequest: pullRequest,
+	})
+	writeStackStore(store)
+}
+
+// Removes the entries of branchNames, for example because their PRs were merged.
+func RemoveStackEntries(branchNames []string) {
+	store := readStackStore()
+	store.Entries = slices.DeleteFunc(store.Entries, func(entry StackEntry) bool {
+		return slices.Contains(branchNames, entry.Branch)
+	})
+	writeStackStore(store)
+}
+
+// Returns the entry of the PR with the given number.
+func GetStackEntryForPullRequest(pullRequest int) (StackEntry, bool) {
+	store := readStackStore()
+	index := slices.IndexFunc(store.Entries, func(entry StackEntry) bool {
+		return entry.PullRequest == pullRequest
+	})
+	if index == -1 {
+		return StackEntry{}, false
+	}
+	return store.Entries[index], true
+}
+
+// Returns the entry of branchName.
+func GetStackEntryForBranch(branchName string) (StackEntry, bool) {
+	store := readStackStore()
+	index := slices.IndexFunc(store.Entries, func(entry StackEntry) bool {
+		return entry.Branch == branchName
+	})
+	if index == -1 {
+		return StackEntry{}, false
+	}
+	return store.Entries[index], true
+}
+
+// Returns the entries for commits, which are full commit hashes, keyed by commit.
+//
+// If followRewrites is true then any entries whose commit is not in commits are matched by
+// patch-id, so that they follow their commit when it is rewritten by a rebase. Only set
+// followRewrites for the commits of main, otherwise entries would follow the cherry-picks on
+// the PR branches. Rewrites are only followed once per command, and again after
+// [InvalidateStackRewrites].
+func GetStackEntries(commits []string, followRewrites bool) map[string]StackEntry {
+	store := readStackStore()
+	entries := make(map[string]StackEntry)
+	unmatchedEntries := make([]int, 0)
+	for i, entry := range store.Entries {
+		if slices.Contains(commits, entry.Commit) {
+			entries[entry.Commit] = entry
+		} else {
+			unmatchedEntries = append(unmatchedEntries, i)
+		}
+	}
+	if !followRewrites || store.followedRewrites {
+		return entries
+	}
+	store.followedRewrites = true
+	if len(unmatchedEntries) == 0 || len(entries) == len(commits) {
+		cacheStackStore(store)
+		return entries
+	}
+	unmatchedCommits := FilterSlice(commits, func(commit string) bool {
+		_, ok := entries[commit]
+		return !ok
+	})
+	patchIds := getPatchIds(unmatchedCommits)
+	changed := false
+	for _, commit := range unmatchedCommits {
+		patchId := patchIds[commit]
+		if patchId == "" {
+			continue
+		}
+		for _, entryIndex := range unmatchedEntries {
+			if store.Entries[entryIndex].PatchId == patchId {
+				store.Entries[entryIndex].Commit = commit
+				entries[commit] = store.Entries[entryIndex]
+				changed = true
+				break
+			}
+		}
+	}
+	if changed {
+		writeStackStore(store)
+	} else {
+		cacheStackStore(store)
+	}
+	return entries
+}
+
+// Returns the stable patch-id of each commit, keyed by commit.
+func getPatchIds(commits []string) map[string]string {
+	patchIds := make(map[string]string)
+	if len(commits) == 0 {
+		return patchIds
+	}
+	showArgs := append([]string{"--no-pager", "show", "--no-color", "--pretty=format:commit %H"}, commits...)
+	patches := ExecuteOrDie(ExecuteOptions{}, "git", showArgs...)
+	out := ExecuteOrDie(ExecuteOptions{Io: StdIo{In: strings.NewReader(patches)}}, "git", "patch-id", "--stable")
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			patchIds[fields[1]] = fields[0]
+		}
+	}
+	return patchIds
+}
+
+// Returns a copy of the stack store, reading it if it has not been read yet by this process.
+func readStackStore() stackStore {
+	storeFile := getStackStoreFile()
+	stackStoresMutex.Lock()
+	defer stackStoresMutex.Unlock()
+	if store, ok := stackStores[storeFile]; ok {
+		store.Entries = slices.Clone(store.Entries)
+		return store
+	}
+	store := stackStore{Entries: []StackEntry{}}
+	data, err := os.ReadFile(storeFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		panic("Could not read stack store: " + err.Error())
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &store); err != nil {
+			panic("Could not parse stack store " + storeFile + ": " + err.Error())
+		}
+	}
+	stackStores[storeFile] = store
+	store.Entries = slices.Clone(store.Entries)
+	return store
+}
+
+func cacheStackStore(store stackStore) {
+	storeFile := getStackStoreFile()
+	stackStoresMutex.Lock()
+	defer stackStoresMutex.Unlock()
+	stackStores[storeFile] = store
+}
+
+// Writes store after removing the entries whose branch no longer exists, such as those of merged PRs
+// that rebase-main deleted, or whose commit no longer exists.
+func writeStackStore(store stackStore) {
+	store.Entries = pruneStackEntries(store.Entries)
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	storeFile := getStackStoreFile()
+	if err := os.MkdirAll(filepath.Dir(storeFile), os.ModePerm); err != nil {
+		panic("Could not create directory for stack store: " + err.Error())
+	}
+	if err := os.WriteFile(storeFile, data, 0644); err != nil {
+		panic("Could not write stack store: " + err.Error())
+	}
+	cacheStackStore(store)
+}
+
+// Returns the entries whose branch and commit still exist.
+func pruneStackEntries(entries []StackEntry) []StackEntry {
+	if len(entries) == 0 {
+		return entries
+	}
+	branches := strings.Fields(ExecuteOrDie(ExecuteOptions{}, "git", "for-each-ref", "--format=%(refname:short)", "refs/heads/"))
+	commits := strings.Join(MapSlice(entries, func(entry StackEntry) string {
+		return entry.Commit
+	}), "\n") + "\n"
+	// Outputs "<commit> missing" for each commit that does not exist.
+	objects := ExecuteOrDie(ExecuteOptions{Io: StdIo{In: strings.NewReader(commits)}}, "git", "cat-file", "--batch-check")
+	return FilterSlice(entries, func(entry StackEntry) bool {
+		return slices.Contains(branches, entry.Branch) && !strings.Contains(objects, entry.Commit+" missing")
+	})
+}
+
+// Returns the file that the stack is stored in.
+func getStackStoreFile() string {
+	return filepath.Join(getGitStackedDiffDir(""), "stack.json")
+}
+
+// Returns the directory in the git dir of the repository at repoDir, or of the current directory
+// if repoDir is empty, that stacked diff state is stored in. It is in the git dir so that it is
+// shared by all worktrees and is not committed.
+func getGitStackedDiffDir(repoDir string) string {
+	key := repoDir
+	if key == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			panic(err)
+		}
+		key = wd
+	}
+	stackStoresMutex.Lock()
+	dir, ok := gitStackedDiffDirs[key]
+	stackStoresMutex.Unlock()
+	if ok {
+		return dir
+	}
+	gitDir := strings.TrimSpace(ExecuteOrDie(ExecuteOptions{Dir: repoDir}, "git", "rev-parse", "--path-format=absolute", "--git-common-dir"))
+	dir = filepath.Join(gitDir, "stacked-diff")
+	stackStoresMutex.Lock()
+	defer stackStoresMutex.Unlock()
+	gitStackedDiffDirs[key] = dir
+	return dir
+}
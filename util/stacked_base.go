@@ -0,0 +1,34 @@
+package util
+
+import (
+	"strings"
+)
+
+// Returns the base branch that branchName is stacked on, along with the commit of the base
+// branch that branchName was last rebased on. Returns empty strings if branchName is not stacked.
+func GetStackedBase(branchName string) (string, string) {
+	return getBranchConfig(branchName, "stackedDiffBase"), getBranchConfig(branchName, "stackedDiffBaseCommit")
+}
+
+// Records that branchName is stacked on top of baseBranch, at baseCommit.
+func SetStackedBase(branchName string, baseBranch string, baseCommit string) {
+	ExecuteOrDie(ExecuteOptions{}, "git", "config", "branch."+branchName+".stackedDiffBase", baseBranch)
+	ExecuteOrDie(ExecuteOptions{}, "git", "config", "branch."+branchName+".stackedDiffBaseCommit", baseCommit)
+}
+
+// Records that branchName is no longer stacked, and is instead based on main.
+func ClearStackedBase(branchName string) {
+	// nolint:errcheck
+	Execute(ExecuteOptions{}, "git", "config", "--unset", "branch."+branchName+".stackedDiffBase")
+	// nolint:errcheck
+	Execute(ExecuteOptions{}, "git", "config", "--unset", "branch."+branchName+".stackedDiffBaseCommit")
+}
+
+func getBranchConfig(branchName string, key string) string {
+	out, err := Execute(ExecuteOptions{}, "git", "config", "branch."+branchName+"."+key)
+	if err != nil {
+		// git config returns an error if the key is not set.
+		return ""
+	}
+	return strings.TrimSpace(out)
+}
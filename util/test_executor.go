@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"sync"
 )
 
 type fakeResponse struct {
@@ -25,6 +26,8 @@ type ExecutedResponse struct {
 type TestExecutor struct {
 	fakeResponses []fakeResponse
 	Responses     []ExecutedResponse
+	// Guards fakeResponses and Responses, as commands execute programs from goroutines.
+	mutex sync.Mutex
 }
 
 // Can be used use as last value of [TestExecutor.fakeResponses] [ExecuteResponse.Args]
@@ -35,7 +38,10 @@ var _ Executor = &TestExecutor{}
 
 // Checks [TestExecutor.fakeResponses] for any match before calling [DefaultExecutor.Execute].
 func (t *TestExecutor) Execute(options ExecuteOptions, programName string, args ...string) (string, error) {
-	for _, response := range slices.Backward(t.fakeResponses) {
+	t.mutex.Lock()
+	fakeResponses := slices.Clone(t.fakeResponses)
+	t.mutex.Unlock()
+	for _, response := range slices.Backward(fakeResponses) {
 		if response.isMatch(programName, args...) {
 			executedResponse := ExecutedResponse{
 				Out:         response.out,
@@ -44,16 +50,22 @@ func (t *TestExecutor) Execute(options ExecuteOptions, programName string, args
 				Args:        args,
 				Faked:       true,
 			}
-			t.Responses = append(t.Responses, executedResponse)
+			t.addResponse(executedResponse)
 			slog.Debug(fmt.Sprint("Faked ", executedResponse))
 			return response.out, response.err
 		}
 	}
 	out, err := (&DefaultExecutor{}).Execute(options, programName, args...)
-	t.Responses = append(t.Responses, ExecutedResponse{Out: out, Err: err, ProgramName: programName, Args: args})
+	t.addResponse(ExecutedResponse{Out: out, Err: err, ProgramName: programName, Args: args})
 	return out, err
 }
 
+func (t *TestExecutor) addResponse(response ExecutedResponse) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.Responses = append(t.Responses, response)
+}
+
 // Adds a response to [TestExecutor.fakeResponses].
 // If [fakeArgs] ends with [MatchAnyRemainingArgs], then the last argument is treated as a wildcard
 // for any remaining args.
@@ -77,10 +89,14 @@ func (t *TestExecutor) SetResponse(out string, err error, fakeProgramName string
 		}
 		return slices.Compare(matchFakeArgs, matchActualArgs) == 0
 	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.fakeResponses = append(t.fakeResponses, fakeResponse{out: out, err: err, isMatch: isMatch})
 }
 
 // Adds a response to [TestExecutor.fakeResponses].
 func (t *TestExecutor) SetResponseFunc(out string, err error, isMatch func(programName string, args ...string) bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
 	t.fakeResponses = append(t.fakeResponses, fakeResponse{out: out, err: err, isMatch: isMatch})
 }
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Options of a repository watched by "sd watch".
+type WatchedRepo struct {
+	// Root directory of the repository.
+	Dir string `json:"dir"`
+	// Comma-separated reviewers to add once checks pass, empty to not add reviewers.
+	Reviewers string `json:"reviewers,omitempty"`
+	// Whether to run "sd rebase-main" after a PR is merged.
+	RebaseMain bool `json:"rebaseMain,omitempty"`
+}
+
+// State of the PRs of a repository as last seen by "sd watch", persisted so that events are not
+// repeated when it is restarted.
+type WatchRepoState struct {
+	LastPoll     time.Time            `json:"lastPoll"`
+	PullRequests []WatchedPullRequest `json:"pullRequests"`
+}
+
+// State of a PR as last seen by "sd watch".
+type WatchedPullRequest struct {
+	Branch  string `json:"branch"`
+	Subject string `json:"subject"`
+	Number  int    `json:"number"`
+	Url     string `json:"url"`
+	// One of "open", "merged", or "closed".
+	State string `json:"state"`
+	// One of "passing", "failing", or "pending".
+	Checks string `json:"checks"`
+	// Number of reviews, used to notify of new ones.
+	Reviews int `json:"reviews"`
+	// Whether "sd watch" has added reviewers to the PR.
+	ReviewersAdded bool `json:"reviewersAdded"`
+}
+
+// Returns the state of the repository at repoDir, or of the current directory if repoDir is empty,
+// as last seen by "sd watch".
+func ReadWatchRepoState(repoDir string) WatchRepoState {
+	state := WatchRepoState{PullRequests: []WatchedPullRequest{}}
+	readJsonFile(getWatchRepoStateFile(repoDir), &state)
+	return state
+}
+
+// Persists the state of the current repository, see [ReadWatchRepoState].
+func WriteWatchRepoState(state WatchRepoState) {
+	writeJsonFile(getWatchRepoStateFile(""), state)
+}
+
+// Returns the repositories that "sd watch" watches.
+func ReadWatchedRepos(appConfig AppConfig) []WatchedRepo {
+	repos := []WatchedRepo{}
+	readJsonFile(getWatchedReposFile(appConfig), &repos)
+	return repos
+}
+
+// Persists the repositories that "sd watch" watches, see [ReadWatchedRepos].
+func WriteWatchedRepos(appConfig AppConfig, repos []WatchedRepo) {
+	writeJsonFile(getWatchedReposFile(appConfig), repos)
+}
+
+// Returns the directory with the files of "sd watch" that are not specific to a repository, such
+// as its socket and log.
+func GetWatchDir(appConfig AppConfig) string {
+	watchDir := filepath.Join(appConfig.UserCacheDir, "gh-stacked-diff", "watch")
+	if err := os.MkdirAll(watchDir, os.ModePerm); err != nil {
+		panic("Could not create watch directory: " + err.Error())
+	}
+	return watchDir
+}
+
+func getWatchedReposFile(appConfig AppConfig) string {
+	return filepath.Join(GetWatchDir(appConfig), "repos.json")
+}
+
+// The state is in the git dir, like the stack store, so that it stays with the repository.
+func getWatchRepoStateFile(repoDir string) string {
+	return filepath.Join(getGitStackedDiffDir(repoDir), "watch.json")
+}
+
+// Reads filename into value. Leaves value unchanged if the file does not exist.
+func readJsonFile(filename string, value any) {
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		panic("Could not read " + filename + ": " + err.Error())
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		panic("Could not parse " + filename + ": " + err.Error())
+	}
+}
+
+func writeJsonFile(filename string, value any) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		panic("Could not create directory for " + filename + ": " + err.Error())
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		panic("Could not write " + filename + ": " + err.Error())
+	}
+}